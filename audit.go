@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/audit"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// who can show up as the actor in the audit log
+const (
+	auditActorUser      = "user"
+	auditActorWebhook   = "webhook"
+	auditActorAnonymous = "anonymous"
+)
+
+// actions recorded in the audit log, actions taken on a report are recorded as moderation.<action>
+const (
+	auditAdminReset          = "admin.reset"
+	auditRoleChanged         = "user.role_changed"
+	auditUserSuspended       = "user.suspended"
+	auditSuspensionLifted    = "user.suspension_lifted"
+	auditUserBanned          = "user.banned"
+	auditUserUnbanned        = "user.unbanned"
+	auditReportResolved      = "report.resolved"
+	auditSubscriptionUpdated = "subscription.updated"
+)
+
+// targets entries in the audit log point at
+const (
+	auditTargetUser   = "user"
+	auditTargetChirp  = "chirp"
+	auditTargetReport = "report"
+)
+
+type auditRecord struct {
+	ActorType  string
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	// only the top level fields that changed between these are kept, either can be nil
+	Before any
+	After  any
+}
+
+// helper that appends an entry to the audit log, it must be given the queries of the transaction making the
+// change so the entry is only kept if the change is. The lock keeps entries chained in the order they're written
+func recordAudit(ctx context.Context, db *database.Queries, record auditRecord) error {
+	before, after, err := audit.Diff(record.Before, record.After)
+	if err != nil {
+		return err
+	}
+
+	err = db.LockAuditLog(ctx)
+	if err != nil {
+		return err
+	}
+
+	prevHash, err := db.GetLastAuditHash(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		prevHash = audit.GenesisHash
+	} else if err != nil {
+		return err
+	}
+
+	info := requestInfoFromContext(ctx)
+	entry := audit.Entry{
+		// Postgres keeps microseconds, the hash has to be made from what it will hand back
+		CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+		ActorType:  record.ActorType,
+		ActorID:    record.ActorID,
+		Action:     record.Action,
+		TargetType: record.TargetType,
+		TargetID:   record.TargetID,
+		Before:     before,
+		After:      after,
+		IP:         info.IP,
+		RequestID:  info.ID,
+	}
+	hash, err := audit.Hash(prevHash, entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.CreateAuditEntry(ctx, database.CreateAuditEntryParams{
+		CreatedAt:  entry.CreatedAt,
+		ActorType:  entry.ActorType,
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Before:     entry.Before,
+		After:      entry.After,
+		Ip:         entry.IP,
+		RequestID:  entry.RequestID,
+		PrevHash:   prevHash,
+		Hash:       hash,
+	})
+	return err
+}
+
+// helper that works out who to record as the actor for a request, for endpoints that don't insist on a login
+func (cfg *apiConfig) auditActor(req *http.Request) (string, string) {
+	userID, err := cfg.optionalUserID(req)
+	if err != nil || userID == uuid.Nil {
+		return auditActorAnonymous, ""
+	}
+
+	return auditActorUser, userID.String()
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Khazz0r/chirpy/internal/entitlements"
+	"github.com/google/uuid"
+)
+
+// helper that looks up which plan a user is on so handlers can ask what they're allowed to do
+func (cfg *apiConfig) planForUser(ctx context.Context, userID uuid.UUID) (entitlements.Plan, error) {
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return entitlements.Plan{}, err
+	}
+
+	return cfg.plans.ForUser(user.IsChirpyRed.Bool), nil
+}
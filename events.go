@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/outbox"
+	"github.com/Khazz0r/chirpy/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+// event types that integrators can subscribe to
+const (
+	EventUserCreated         = "user.created"
+	EventUserDeleted         = "user.deleted"
+	EventUserFollowed        = "user.followed"
+	EventChirpCreated        = "chirp.created"
+	EventChirpUpdated        = "chirp.updated"
+	EventChirpDeleted        = "chirp.deleted"
+	EventChirpRestored       = "chirp.restored"
+	EventChirpExpired        = "chirp.expired"
+	EventSubscriptionUpdated = "subscription.updated"
+)
+
+// aggregates events belong to, events for the same aggregate are always published in the order they happened
+const (
+	aggregateUser         = "user"
+	aggregateChirp        = "chirp"
+	aggregateSubscription = "subscription"
+)
+
+// topics carried by the real-time stream hub
+const (
+	topicChirps = "chirps"
+)
+
+// topic a user's notifications are published on
+func notificationsTopic(userID uuid.UUID) string {
+	return "notifications:" + userID.String()
+}
+
+var eventTypes = []string{
+	EventUserCreated,
+	EventUserDeleted,
+	EventUserFollowed,
+	EventChirpCreated,
+	EventChirpUpdated,
+	EventChirpDeleted,
+	EventChirpRestored,
+	EventChirpExpired,
+	EventSubscriptionUpdated,
+}
+
+type Event struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+func isEventType(eventType string) bool {
+	return slices.Contains(eventTypes, eventType)
+}
+
+// helper that writes an event to the outbox, it must be given the queries of the transaction making the
+// change so the event is only published if that change commits
+func recordEvent(ctx context.Context, db *database.Queries, aggregateType string, aggregateID uuid.UUID, eventType string, data interface{}) error {
+	event := Event{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return db.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		EventID:       event.ID,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       payload,
+	})
+}
+
+// bus subscriber that queues an event for every outbound webhook subscribed to it
+func (cfg *apiConfig) enqueueWebhookDeliveries(ctx context.Context, msg outbox.Message) error {
+	eventID, err := uuid.Parse(msg.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = cfg.db.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventID:   eventID,
+		EventType: msg.Type,
+		Payload:   msg.Payload,
+	})
+
+	return err
+}
+
+// helper that hands a message to the stream hub of every server through Postgres NOTIFY, like recordEvent it
+// should be given the transaction's queries since the notification is only sent once that commits
+func publishToStream(ctx context.Context, db *database.Queries, topic, id string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(pubsub.Message{
+		Topic: topic,
+		ID:    id,
+		Data:  encoded,
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.NotifyStream(ctx, string(payload))
+}
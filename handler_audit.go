@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/audit"
+	"github.com/Khazz0r/chirpy/internal/database"
+)
+
+const (
+	auditPageSize   = 100
+	auditExportSize = 500
+)
+
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ActorType  string          `json:"actor_type"`
+	ActorID    string          `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// handler that pages through the audit log newest first, it can be narrowed down with actor_id, action,
+// target_type, target_id, since and until
+func (cfg *apiConfig) handlerGetAuditLog(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Entries    []AuditEntry `json:"entries"`
+		NextCursor string       `json:"next_cursor,omitempty"`
+	}
+
+	_, ok := cfg.authorizeRole(w, req, roleAdmin)
+	if !ok {
+		return
+	}
+
+	filter, err := parseAuditFilter(req, auditPageSize)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	entries, err := cfg.db.GetAuditLog(req.Context(), filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving audit log from database", err)
+		return
+	}
+
+	structuredEntries := []AuditEntry{}
+	for _, entry := range entries {
+		structuredEntries = append(structuredEntries, structureAuditEntry(entry))
+	}
+
+	// entries are numbered in the order they were written so the last ID is all a cursor needs
+	nextCursor := ""
+	if len(entries) == int(filter.PageSize) {
+		nextCursor = strconv.FormatInt(entries[len(entries)-1].ID, 10)
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Entries:    structuredEntries,
+		NextCursor: nextCursor,
+	})
+}
+
+// handler that downloads every audit log entry matching the same filters as listing them, as JSON lines or
+// with ?format=csv as a spreadsheet
+func (cfg *apiConfig) handlerExportAuditLog(w http.ResponseWriter, req *http.Request) {
+	_, ok := cfg.authorizeRole(w, req, roleAdmin)
+	if !ok {
+		return
+	}
+
+	filter, err := parseAuditFilter(req, auditExportSize)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	switch format {
+	case "", "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit-log.csv"`)
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid format, must be jsonl or csv", nil)
+		return
+	}
+
+	// the first page is fetched before anything is written so a database error can still be reported
+	entries, err := cfg.db.GetAuditLog(req.Context(), filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving audit log from database", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	csvWriter := csv.NewWriter(w)
+	if format == "csv" {
+		csvWriter.Write([]string{"id", "created_at", "actor_type", "actor_id", "action", "target_type", "target_id", "before", "after", "ip", "request_id", "prev_hash", "hash"})
+	}
+
+	for len(entries) > 0 {
+		for _, entry := range entries {
+			if format == "csv" {
+				csvWriter.Write([]string{
+					strconv.FormatInt(entry.ID, 10),
+					entry.CreatedAt.Format(time.RFC3339Nano),
+					entry.ActorType,
+					entry.ActorID,
+					entry.Action,
+					entry.TargetType,
+					entry.TargetID,
+					string(entry.Before),
+					string(entry.After),
+					entry.Ip,
+					entry.RequestID,
+					entry.PrevHash,
+					entry.Hash,
+				})
+			} else {
+				encoder.Encode(structureAuditEntry(entry))
+			}
+		}
+		csvWriter.Flush()
+
+		if len(entries) < int(filter.PageSize) {
+			return
+		}
+		filter.BeforeID = entries[len(entries)-1].ID
+		entries, err = cfg.db.GetAuditLog(req.Context(), filter)
+		if err != nil {
+			// the response has already started, so all that can be done is cut it short
+			return
+		}
+	}
+}
+
+// handler that walks the whole audit log recomputing its hash chain, anything edited or removed shows up as
+// the first entry that no longer fits
+func (cfg *apiConfig) handlerVerifyAuditLog(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Valid    bool   `json:"valid"`
+		Verified int    `json:"verified"`
+		BrokenAt *int64 `json:"broken_at"`
+		Error    string `json:"error,omitempty"`
+	}
+
+	_, ok := cfg.authorizeRole(w, req, roleAdmin)
+	if !ok {
+		return
+	}
+
+	prevHash := audit.GenesisHash
+	lastID := int64(0)
+	verified := 0
+	for {
+		entries, err := cfg.db.GetAuditLogAfter(req.Context(), database.GetAuditLogAfterParams{
+			ID:    lastID,
+			Limit: auditExportSize,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving audit log from database", err)
+			return
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		records := []audit.Record{}
+		for _, entry := range entries {
+			records = append(records, audit.Record{
+				Entry: audit.Entry{
+					CreatedAt:  entry.CreatedAt,
+					ActorType:  entry.ActorType,
+					ActorID:    entry.ActorID,
+					Action:     entry.Action,
+					TargetType: entry.TargetType,
+					TargetID:   entry.TargetID,
+					Before:     entry.Before,
+					After:      entry.After,
+					IP:         entry.Ip,
+					RequestID:  entry.RequestID,
+				},
+				PrevHash: entry.PrevHash,
+				Hash:     entry.Hash,
+			})
+		}
+
+		var bad int
+		prevHash, bad, err = audit.Verify(prevHash, records)
+		if err != nil {
+			respondWithJSON(w, http.StatusOK, response{
+				Valid:    false,
+				Verified: verified + bad,
+				BrokenAt: &entries[bad].ID,
+				Error:    err.Error(),
+			})
+			return
+		}
+
+		verified += len(entries)
+		lastID = entries[len(entries)-1].ID
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Valid:    true,
+		Verified: verified,
+	})
+}
+
+// helper that reads the audit log filters out of the query string
+func parseAuditFilter(req *http.Request, pageSize int32) (database.GetAuditLogParams, error) {
+	query := req.URL.Query()
+	filter := database.GetAuditLogParams{
+		ActorID:    query.Get("actor_id"),
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+		Since:      time.Time{},
+		Until:      time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC),
+		BeforeID:   math.MaxInt64,
+		PageSize:   pageSize,
+	}
+
+	var err error
+	if since := query.Get("since"); since != "" {
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return database.GetAuditLogParams{}, errors.New("since must be an RFC 3339 time")
+		}
+		filter.Since = filter.Since.UTC()
+	}
+	if until := query.Get("until"); until != "" {
+		filter.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return database.GetAuditLogParams{}, errors.New("until must be an RFC 3339 time")
+		}
+		filter.Until = filter.Until.UTC()
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		filter.BeforeID, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			return database.GetAuditLogParams{}, errors.New("Invalid cursor")
+		}
+	}
+
+	return filter, nil
+}
+
+func structureAuditEntry(entry database.AuditLog) AuditEntry {
+	return AuditEntry{
+		ID:         entry.ID,
+		CreatedAt:  entry.CreatedAt,
+		ActorType:  entry.ActorType,
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Before:     entry.Before,
+		After:      entry.After,
+		IP:         entry.Ip,
+		RequestID:  entry.RequestID,
+		PrevHash:   entry.PrevHash,
+		Hash:       entry.Hash,
+	}
+}
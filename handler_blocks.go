@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/keywords"
+	"github.com/google/uuid"
+)
+
+type MutedKeyword struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Keyword   string     `json:"keyword"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// handler that has the logged in user block another user, any follows between the two are removed
+func (cfg *apiConfig) handlerBlockUser(w http.ResponseWriter, req *http.Request) {
+	cfg.relateToUser(w, req, func(ctx context.Context, db *database.Queries, userID, otherUserID uuid.UUID) error {
+		// a follow being made at the same time either lands before the block and is removed below, or waits
+		// and sees the block
+		err := db.LockUserPair(ctx, database.LockUserPairParams{
+			UserID:      userID,
+			OtherUserID: otherUserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = db.BlockUser(ctx, database.BlockUserParams{
+			BlockerID: userID,
+			BlockedID: otherUserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return db.RemoveFollowsBetween(ctx, database.RemoveFollowsBetweenParams{
+			UserID:      userID,
+			OtherUserID: otherUserID,
+		})
+	})
+}
+
+// handler that has the logged in user unblock another user, follows removed by the block aren't restored
+func (cfg *apiConfig) handlerUnblockUser(w http.ResponseWriter, req *http.Request) {
+	cfg.relateToUser(w, req, func(ctx context.Context, db *database.Queries, userID, otherUserID uuid.UUID) error {
+		return db.UnblockUser(ctx, database.UnblockUserParams{
+			BlockerID: userID,
+			BlockedID: otherUserID,
+		})
+	})
+}
+
+// handler that hides another user from the logged in user's timelines and notifications without them knowing
+func (cfg *apiConfig) handlerMuteUser(w http.ResponseWriter, req *http.Request) {
+	cfg.relateToUser(w, req, func(ctx context.Context, db *database.Queries, userID, otherUserID uuid.UUID) error {
+		return db.MuteUser(ctx, database.MuteUserParams{
+			MuterID: userID,
+			MutedID: otherUserID,
+		})
+	})
+}
+
+// handler that has the logged in user unmute another user
+func (cfg *apiConfig) handlerUnmuteUser(w http.ResponseWriter, req *http.Request) {
+	cfg.relateToUser(w, req, func(ctx context.Context, db *database.Queries, userID, otherUserID uuid.UUID) error {
+		return db.UnmuteUser(ctx, database.UnmuteUserParams{
+			MuterID: userID,
+			MutedID: otherUserID,
+		})
+	})
+}
+
+// handler that lists the IDs of users the logged in user has blocked
+func (cfg *apiConfig) handlerGetBlocks(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view blocked users", err)
+		return
+	}
+
+	blockedIDs, err := cfg.db.GetBlockedIDs(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving blocked users from database", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, append([]uuid.UUID{}, blockedIDs...))
+}
+
+// handler that lists the IDs of users the logged in user has muted
+func (cfg *apiConfig) handlerGetMutes(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view muted users", err)
+		return
+	}
+
+	mutedIDs, err := cfg.db.GetMutedIDs(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving muted users from database", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, append([]uuid.UUID{}, mutedIDs...))
+}
+
+// handler that mutes a word, phrase or hashtag for the logged in user, muting one again replaces its expiry
+func (cfg *apiConfig) handlerCreateMutedKeyword(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Keyword   string     `json:"keyword"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to mute keywords", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	keyword, err := keywords.Normalize(params.Keyword)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Keyword must be between 1 and 100 characters", err)
+		return
+	}
+
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			respondWithError(w, http.StatusBadRequest, "Expiry must be in the future", nil)
+			return
+		}
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+
+	mutedKeyword, err := cfg.db.UpsertMutedKeyword(req.Context(), database.UpsertMutedKeywordParams{
+		UserID:    userID,
+		Keyword:   keyword,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error muting keyword", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, structureMutedKeyword(mutedKeyword))
+}
+
+// handler that lists the logged in user's muted keywords that haven't expired
+func (cfg *apiConfig) handlerGetMutedKeywords(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view muted keywords", err)
+		return
+	}
+
+	mutedKeywords, err := cfg.db.GetActiveMutedKeywords(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving muted keywords from database", err)
+		return
+	}
+
+	structuredKeywords := []MutedKeyword{}
+	for _, mutedKeyword := range mutedKeywords {
+		structuredKeywords = append(structuredKeywords, structureMutedKeyword(mutedKeyword))
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredKeywords)
+}
+
+// handler that unmutes a keyword before it expires
+func (cfg *apiConfig) handlerDeleteMutedKeyword(w http.ResponseWriter, req *http.Request) {
+	keywordID, err := uuid.Parse(req.PathValue("keywordID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid keyword ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to unmute keywords", err)
+		return
+	}
+
+	err = cfg.db.DeleteMutedKeyword(req.Context(), database.DeleteMutedKeywordParams{
+		ID:     keywordID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unmuting keyword", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// helper shared by blocking and muting, apply runs in a transaction with the logged in user and the user in the path
+func (cfg *apiConfig) relateToUser(w http.ResponseWriter, req *http.Request, apply func(ctx context.Context, db *database.Queries, userID, otherUserID uuid.UUID) error) {
+	otherUserID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to block or mute users", err)
+		return
+	}
+
+	if otherUserID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't block or mute yourself", nil)
+		return
+	}
+
+	_, err = cfg.db.GetUserByID(req.Context(), otherUserID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	err = apply(req.Context(), cfg.db.WithTx(tx), userID, otherUserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user relationship", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user relationship", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// helper for endpoints anyone can use that still hide blocked users from a logged in viewer, returns uuid.Nil
+// when no token was sent
+func (cfg *apiConfig) optionalUserID(req *http.Request) (uuid.UUID, error) {
+	if req.Header.Get("Authorization") == "" {
+		return uuid.Nil, nil
+	}
+
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return auth.ValidateJWT(token, cfg.jwtSecret)
+}
+
+// helper that loads everyone on either side of a block with the user, neither gets to see the other's chirps
+func (cfg *apiConfig) blockedAuthors(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	hidden := map[uuid.UUID]bool{}
+	if userID == uuid.Nil {
+		return hidden, nil
+	}
+
+	blockedIDs, err := cfg.db.GetBlockRelatedIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, blockedID := range blockedIDs {
+		hidden[blockedID] = true
+	}
+
+	return hidden, nil
+}
+
+// helper that adds the user's mutes and muted keywords on top of blocks, only timelines hide muted content
+func (cfg *apiConfig) timelineFilter(ctx context.Context, userID uuid.UUID, followees map[uuid.UUID]bool) (chirpFilter, error) {
+	hidden, err := cfg.blockedAuthors(ctx, userID)
+	if err != nil {
+		return chirpFilter{}, err
+	}
+
+	mutedIDs, err := cfg.db.GetMutedIDs(ctx, userID)
+	if err != nil {
+		return chirpFilter{}, err
+	}
+	for _, mutedID := range mutedIDs {
+		hidden[mutedID] = true
+	}
+
+	mutedKeywords, err := cfg.db.GetActiveMutedKeywords(ctx, userID)
+	if err != nil {
+		return chirpFilter{}, err
+	}
+
+	filter := chirpFilter{followees: followees, hiddenAuthors: hidden}
+	for _, mutedKeyword := range mutedKeywords {
+		filter.mutedKeywords = append(filter.mutedKeywords, mutedKeyword.Keyword)
+	}
+
+	return filter, nil
+}
+
+func structureMutedKeyword(mutedKeyword database.MutedKeyword) MutedKeyword {
+	structuredKeyword := MutedKeyword{
+		ID:        mutedKeyword.ID,
+		CreatedAt: mutedKeyword.CreatedAt,
+		Keyword:   mutedKeyword.Keyword,
+	}
+	if mutedKeyword.ExpiresAt.Valid {
+		structuredKeyword.ExpiresAt = &mutedKeyword.ExpiresAt.Time
+	}
+
+	return structuredKeyword
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"slices"
 	"strings"
@@ -10,25 +13,76 @@ import (
 
 	"github.com/Khazz0r/chirpy/internal/auth"
 	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/entitlements"
+	"github.com/Khazz0r/chirpy/internal/profiles"
+	"github.com/Khazz0r/chirpy/internal/visibility"
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Body       string     `json:"body"`
+	UserID     uuid.UUID  `json:"user_id"`
+	ReplyToID  *uuid.UUID `json:"reply_to_id"`
+	Visibility string     `json:"visibility"`
+	Status     string     `json:"status"`
+	PublishAt  *time.Time `json:"publish_at,omitempty"`
+	ExpiresIn  *int32     `json:"expires_in,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Poll       *Poll      `json:"poll,omitempty"`
+	Media      []string   `json:"media,omitempty"`
+	// the chirp this one quotes, embedded as the viewer gets to see it
+	QuoteOf      *uuid.UUID   `json:"quote_of"`
+	Quoted       *QuotedChirp `json:"quoted,omitempty"`
+	RechirpCount int64        `json:"rechirp_count"`
+	QuoteCount   int64        `json:"quote_count"`
+	// only moderators ever see deleted chirps, or the author when restoring one
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// DeletedChirp is one of the user's own deleted chirps they still have time to restore
+type DeletedChirp struct {
+	Chirp
+	RestorableUntil time.Time `json:"restorable_until"`
 }
 
 type response struct {
 	Chirp
 }
 
-// handler to create a chirp to the database
+const (
+	maxMentionNotifications = 10
+	// how long an author has to change their mind about deleting a chirp
+	chirpRestoreWindow = 7 * 24 * time.Hour
+	// deleted chirps are kept past the restore window so moderators can still look at them
+	deletedChirpRetention = 30 * 24 * time.Hour
+	// how long a self-destructing chirp can be set to last
+	minChirpExpiry = time.Minute
+	maxChirpExpiry = 30 * 24 * time.Hour
+)
+
+// drafts and scheduled chirps are only ever seen by their author until they're published
+const (
+	chirpStatusDraft     = "draft"
+	chirpStatusScheduled = "scheduled"
+	chirpStatusPublished = "published"
+)
+
+// handler to create a chirp to the database, it can also be saved as a draft or scheduled for later, set
+// to delete itself after expires_in seconds, come with a poll and quote another chirp
 func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, req *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body       string          `json:"body"`
+		ReplyToID  *uuid.UUID      `json:"reply_to_id"`
+		Visibility string          `json:"visibility"`
+		Status     string          `json:"status"`
+		PublishAt  *time.Time      `json:"publish_at"`
+		ExpiresIn  *int32          `json:"expires_in"`
+		Poll       *pollParameters `json:"poll"`
+		QuoteOf    *uuid.UUID      `json:"quote_of"`
+		Media      []string        `json:"media"`
 	}
 
 	// obtain token for verifying if user is authorized
@@ -45,6 +99,17 @@ func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	plan, err := cfg.planForUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error looking up user's plan", err)
+		return
+	}
+
+	if !cfg.chirpLimiter.Allow(userID.String(), plan.ChirpsPerMinute, time.Now()) {
+		respondWithError(w, http.StatusTooManyRequests, "Too many chirps, slow down", nil)
+		return
+	}
+
 	decoder := json.NewDecoder(req.Body)
 	params := parameters{}
 	err = decoder.Decode(&params)
@@ -54,35 +119,388 @@ func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, req *http.Reques
 	}
 
 	// ensure chirp body fits all rules before creating it
-	chirpBody, err := validateChirp(params.Body)
+	chirpBody, err := validateChirp(params.Body, plan.MaxChirpLength)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
 
-	chirp, err := cfg.db.CreateChirp(req.Context(), database.CreateChirpParams{
-		Body:   chirpBody,
-		UserID: userID,
+	if params.Visibility == "" {
+		params.Visibility = visibility.Public
+	}
+	if !visibility.IsValid(params.Visibility) {
+		respondWithError(w, http.StatusBadRequest, "Invalid visibility, must be public, unlisted, followers or mentioned", nil)
+		return
+	}
+
+	status, publishAt, err := parseChirpStatus(params.Status, params.PublishAt, time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if status == chirpStatusScheduled && !plan.Can(entitlements.CapabilityScheduledPosts) {
+		respondWithError(w, http.StatusForbidden, "Scheduling chirps is a Chirpy Red feature", nil)
+		return
+	}
+
+	err = validateChirpMedia(params.Media, plan)
+	if errors.Is(err, errMediaNotAllowed) {
+		respondWithError(w, http.StatusForbidden, fmt.Sprintf("Your plan allows at most %d media per chirp", plan.MaxMediaPerChirp), err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	author, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving user from database", err)
+		return
+	}
+	expiresIn, err := parseChirpExpiry(params.ExpiresIn, author.DefaultChirpExpiresIn)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	pollOptions := []string{}
+	if params.Poll != nil {
+		pollOptions, err = validatePoll(*params.Poll)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	replyToID := uuid.NullUUID{}
+	parent := database.Chirp{}
+	if params.ReplyToID != nil {
+		parent, err = cfg.db.GetChirpByID(req.Context(), *params.ReplyToID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "The chirp being replied to does not exist", err)
+			return
+		}
+		canView, err := cfg.canViewChirp(req.Context(), userID, parent)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking chirp visibility", err)
+			return
+		}
+		if !canView {
+			respondWithError(w, http.StatusBadRequest, "The chirp being replied to does not exist", nil)
+			return
+		}
+
+		blocked, err := cfg.db.IsBlockedEitherWay(req.Context(), database.IsBlockedEitherWayParams{
+			UserID:      userID,
+			OtherUserID: parent.UserID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking blocked users", err)
+			return
+		}
+		if blocked {
+			respondWithError(w, http.StatusForbidden, "You can't reply to this chirp", nil)
+			return
+		}
+
+		replyToID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	quoteOfID := uuid.NullUUID{}
+	if params.QuoteOf != nil {
+		quoted, err := cfg.db.GetChirpByID(req.Context(), *params.QuoteOf)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "The chirp being quoted does not exist", err)
+			return
+		}
+		audience, err := chirpAudience(req.Context(), cfg.db, quoted)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking chirp visibility", err)
+			return
+		}
+		canView, err := inAudience(req.Context(), cfg.db, audience, userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking chirp visibility", err)
+			return
+		}
+		if !canView {
+			respondWithError(w, http.StatusBadRequest, "The chirp being quoted does not exist", nil)
+			return
+		}
+
+		blocked, err := cfg.db.IsBlockedEitherWay(req.Context(), database.IsBlockedEitherWayParams{
+			UserID:      userID,
+			OtherUserID: quoted.UserID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking blocked users", err)
+			return
+		}
+		if blocked {
+			respondWithError(w, http.StatusForbidden, "You can't quote this chirp", nil)
+			return
+		}
+
+		// quoting shares a chirp the same way rechirping does
+		if !visibility.Shareable(audience) {
+			respondWithError(w, http.StatusForbidden, "Only public chirps can be quoted", nil)
+			return
+		}
+
+		quoteOfID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	chirp, err := qtx.CreateChirp(req.Context(), database.CreateChirpParams{
+		Body:       chirpBody,
+		UserID:     userID,
+		ReplyToID:  replyToID,
+		Visibility: params.Visibility,
+		Status:     status,
+		PublishAt:  publishAt,
+		ExpiresIn:  expiresIn,
+		ExpiresAt:  chirpExpiresAt(status, expiresIn, time.Now()),
+		QuoteOfID:  quoteOfID,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
 		return
 	}
 
+	if params.Poll != nil {
+		err = createPoll(req.Context(), qtx, chirp.ID, *params.Poll, pollOptions)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error creating poll", err)
+			return
+		}
+	}
+
+	err = addChirpMedia(req.Context(), qtx, chirp.ID, params.Media)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error attaching media", err)
+		return
+	}
+
+	if chirp.Status == chirpStatusPublished {
+		err = publishChirp(req.Context(), qtx, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error publishing chirp", err)
+			return
+		}
+	}
+
+	structuredChirps := []Chirp{structureChirp(chirp)}
+	err = attachChirpDetails(req.Context(), qtx, userID, structuredChirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving chirp details from database", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving chirp", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusCreated, response{
-		Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		},
+		structuredChirps[0],
 	})
 }
 
-// helper function for creating chirps to ensure Chirps are valid
-func validateChirp(body string) (string, error) {
-	const maxChirpLength = 140
+// helper that does everything that happens when a chirp goes out, whether it's posted straight away, a draft
+// being published or a scheduled chirp coming due. Should be given the transaction that published it
+func publishChirp(ctx context.Context, db *database.Queries, chirp database.Chirp) error {
+	author, err := db.GetUserByID(ctx, chirp.UserID)
+	if err != nil {
+		return err
+	}
+	audience := visibility.Audience{
+		AuthorID:   chirp.UserID,
+		Visibility: chirp.Visibility,
+		Protected:  author.IsProtected,
+	}
+
+	// everyone mentioned is remembered so mentioned-only chirps reach them even after a handle changes
+	mentionedUsers := []database.User{}
+	for _, handle := range profiles.Mentions(chirp.Body) {
+		mentioned, err := db.GetUserByHandle(ctx, handle)
+		if errors.Is(err, sql.ErrNoRows) || mentioned.ID == chirp.UserID {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = db.AddChirpMention(ctx, database.AddChirpMentionParams{
+			ChirpID: chirp.ID,
+			UserID:  mentioned.ID,
+		})
+		if err != nil {
+			return err
+		}
+		audience.Mentioned = append(audience.Mentioned, mentioned.ID)
+		mentionedUsers = append(mentionedUsers, mentioned)
+	}
+
+	err = db.StartPoll(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	err = recordChirpEvent(ctx, db, EventChirpCreated, chirp)
+	if err != nil {
+		return err
+	}
+
+	// nobody has voted yet, so the poll goes out without results whoever ends up receiving it
+	structuredChirps := []Chirp{structureChirp(chirp)}
+	err = attachChirpDetails(ctx, db, uuid.Nil, structuredChirps)
+	if err != nil {
+		return err
+	}
+
+	err = publishToStream(ctx, db, topicChirps, chirp.ID.String(), streamedChirp{
+		Chirp:           structuredChirps[0],
+		AuthorProtected: audience.Protected,
+		MentionedIDs:    audience.Mentioned,
+	})
+	if err != nil {
+		return err
+	}
+
+	// a scheduled reply can outlive the chirp it replies to, then there's nobody to tell
+	parent := database.Chirp{}
+	if chirp.ReplyToID.Valid {
+		parent, err = db.GetChirpByID(ctx, chirp.ReplyToID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	// the quoted chirp may be gone by the time a scheduled quote goes out too
+	quoted := database.Chirp{}
+	if chirp.QuoteOfID.Valid {
+		quoted, err = db.GetChirpByID(ctx, chirp.QuoteOfID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	// nobody is told about a chirp they can't see
+	if parent.ID != uuid.Nil {
+		canView, err := inAudience(ctx, db, audience, parent.UserID)
+		if err != nil {
+			return err
+		}
+		if canView {
+			err = notifyUser(ctx, db, parent.UserID, chirp.UserID, notificationReply, uuid.NullUUID{UUID: chirp.ID, Valid: true}, "reply:"+chirp.ID.String())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// replying to and quoting the same person only tells them once
+	if quoted.ID != uuid.Nil && (parent.ID == uuid.Nil || quoted.UserID != parent.UserID) {
+		canView, err := inAudience(ctx, db, audience, quoted.UserID)
+		if err != nil {
+			return err
+		}
+		if canView {
+			err = notifyUser(ctx, db, quoted.UserID, chirp.UserID, notificationQuote, uuid.NullUUID{UUID: chirp.ID, Valid: true}, "quote:"+chirp.ID.String())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// only the first few mentions notify anyone so a chirp can't be used to ping hundreds of people
+	for _, mentioned := range mentionedUsers[:min(len(mentionedUsers), maxMentionNotifications)] {
+		// the authors of the chirps being replied to and quoted already hear about it
+		if (parent.ID != uuid.Nil && mentioned.ID == parent.UserID) || (quoted.ID != uuid.Nil && mentioned.ID == quoted.UserID) {
+			continue
+		}
+		canView, err := inAudience(ctx, db, audience, mentioned.ID)
+		if err != nil {
+			return err
+		}
+		if !canView {
+			continue
+		}
+
+		err = notifyUser(ctx, db, mentioned.ID, chirp.UserID, notificationMention, uuid.NullUUID{UUID: chirp.ID, Valid: true}, "mention:"+chirp.ID.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// helper that checks the status a chirp is being saved with, only scheduled chirps have a publish_at and it
+// has to be in the future
+func parseChirpStatus(status string, publishAt *time.Time, now time.Time) (string, sql.NullTime, error) {
+	switch status {
+	case "":
+		status = chirpStatusPublished
+	case chirpStatusDraft, chirpStatusScheduled, chirpStatusPublished:
+	default:
+		return "", sql.NullTime{}, errors.New("Invalid status, must be draft, scheduled or published")
+	}
+
+	if status != chirpStatusScheduled {
+		if publishAt != nil {
+			return "", sql.NullTime{}, errors.New("publish_at can only be set on scheduled chirps")
+		}
+		return status, sql.NullTime{}, nil
+	}
+	if publishAt == nil {
+		return "", sql.NullTime{}, errors.New("Scheduled chirps need a publish_at time")
+	}
+	if !publishAt.After(now) {
+		return "", sql.NullTime{}, errors.New("publish_at must be in the future")
+	}
+
+	return status, sql.NullTime{Time: publishAt.UTC(), Valid: true}, nil
+}
+
+// helper that checks how long a chirp should last, leaving it out falls back to the user's default and 0
+// keeps the chirp forever
+func parseChirpExpiry(expiresIn *int32, defaultExpiresIn sql.NullInt32) (sql.NullInt32, error) {
+	if expiresIn == nil {
+		return defaultExpiresIn, nil
+	}
+	if *expiresIn == 0 {
+		return sql.NullInt32{}, nil
+	}
+
+	lifetime := time.Duration(*expiresIn) * time.Second
+	if lifetime < minChirpExpiry || lifetime > maxChirpExpiry {
+		return sql.NullInt32{}, errors.New("expires_in must be between 60 seconds and 30 days")
+	}
+
+	return sql.NullInt32{Int32: *expiresIn, Valid: true}, nil
+}
+
+// helper that works out when a chirp being saved expires, the clock only starts once it's published
+func chirpExpiresAt(status string, expiresIn sql.NullInt32, now time.Time) sql.NullTime {
+	if status != chirpStatusPublished || !expiresIn.Valid {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: now.UTC().Add(time.Duration(expiresIn.Int32) * time.Second), Valid: true}
+}
+
+// helper function for creating chirps to ensure Chirps are valid, the max length depends on the author's plan
+func validateChirp(body string, maxChirpLength int) (string, error) {
 	if len(body) > maxChirpLength {
 		return "", errors.New("Chirp is too long")
 	}
@@ -92,6 +510,22 @@ func validateChirp(body string) (string, error) {
 	return cleanedBody, nil
 }
 
+// helper that pulls the hashtags out of a chirp, lowercased and without the leading #
+func chirpHashtags(body string) []string {
+	hashtags := []string{}
+	for _, word := range strings.Fields(body) {
+		if !strings.HasPrefix(word, "#") {
+			continue
+		}
+		tag := strings.ToLower(strings.TrimRight(word[1:], ".,!?:;"))
+		if tag != "" && !slices.Contains(hashtags, tag) {
+			hashtags = append(hashtags, tag)
+		}
+	}
+
+	return hashtags
+}
+
 // helper function for validate chirp to clean up "bad" words
 func badWordReplacer(body string) string {
 	sliceBody := strings.Split(body, " ")
@@ -117,11 +551,37 @@ func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, req *http.Reque
 		userID uuid.UUID
 	)
 
+	// listing chirps doesn't need a token, but a logged in viewer doesn't see anyone on the other side of a block
+	viewerID, err := cfg.optionalUserID(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view chirps", err)
+		return
+	}
+
+	hidden, err := cfg.blockedAuthors(req.Context(), viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving blocked users from database", err)
+		return
+	}
+
+	// chirps from suspended and banned users are hidden from everyone but moderators, who can also ask for
+	// deleted chirps with ?include_deleted=true
+	staff, err := cfg.isStaff(req.Context(), viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving user from database", err)
+		return
+	}
+	includeDeleted := staff && req.URL.Query().Get("include_deleted") == "true"
+
 	authorID := req.URL.Query().Get("author_id")
 	sortType := req.URL.Query().Get("sort")
 
 	if authorID == "" {
-		chirps, err = cfg.db.GetAllChirps(req.Context())
+		chirps, err = cfg.db.GetAllChirps(req.Context(), database.GetAllChirpsParams{
+			IncludeDeleted:    includeDeleted,
+			ViewerID:          viewerID,
+			IncludeRestricted: staff,
+		})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Error retrieving all chirps from database", err)
 			return
@@ -133,7 +593,12 @@ func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, req *http.Reque
 			return
 		}
 
-		chirps, err = cfg.db.GetChirpsByAuthorID(req.Context(), userID)
+		chirps, err = cfg.db.GetChirpsByAuthorID(req.Context(), database.GetChirpsByAuthorIDParams{
+			UserID:            userID,
+			IncludeDeleted:    includeDeleted,
+			ViewerID:          viewerID,
+			IncludeRestricted: staff,
+		})
 		if err != nil {
 			respondWithError(w, http.StatusNotFound, "Unable to find chirpys by that author ID", err)
 			return
@@ -164,13 +629,16 @@ func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, req *http.Reque
 	structuredChirps := []Chirp{}
 
 	for _, chirp := range chirps {
-		structuredChirps = append(structuredChirps, Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
+		if hidden[chirp.UserID] {
+			continue
+		}
+		structuredChirps = append(structuredChirps, structureChirp(chirp))
+	}
+
+	err = attachChirpDetails(req.Context(), cfg.db, viewerID, structuredChirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving chirp details from database", err)
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, structuredChirps)
@@ -184,28 +652,72 @@ func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, req *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID format", err)
 		return
 	}
-	chirp, err := cfg.db.GetChirpByID(req.Context(), chirpID)
+	viewerID, err := cfg.optionalUserID(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view chirp", err)
+		return
+	}
+
+	// moderators can still look at deleted chirps
+	staff, err := cfg.isStaff(req.Context(), viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving user from database", err)
+		return
+	}
+
+	var chirp database.Chirp
+	if staff {
+		chirp, err = cfg.db.GetChirpByIDIncludingDeleted(req.Context(), chirpID)
+	} else {
+		chirp, err = cfg.db.GetChirpByID(req.Context(), chirpID)
+	}
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
 		return
 	}
 
+	// chirps on the other side of a block look the same as ones that don't exist
+	hidden, err := cfg.blockedAuthors(req.Context(), viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving blocked users from database", err)
+		return
+	}
+	if hidden[chirp.UserID] {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+		return
+	}
+
+	// and so do chirps the viewer isn't in the audience for, moderators can see everything
+	if !staff {
+		canView, err := cfg.canViewChirp(req.Context(), viewerID, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking chirp visibility", err)
+			return
+		}
+		if !canView {
+			respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+			return
+		}
+	}
+
 	type response struct {
 		Chirp
 	}
 
+	structuredChirps := []Chirp{structureChirp(chirp)}
+	err = attachChirpDetails(req.Context(), cfg.db, viewerID, structuredChirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving chirp details from database", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, response{
-		Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		},
+		structuredChirps[0],
 	})
 }
 
-// handler that deletes a chirp as long as the user is authorized
+// handler that deletes a chirp as long as the user is authorized, it's hidden straight away but can be
+// restored for a while before it's purged for good
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Request) {
 	chirpIDStr := req.PathValue("chirpID")
 	chirpID, err := uuid.Parse(chirpIDStr)
@@ -235,16 +747,313 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Reques
 	}
 	if userID != chirp.UserID {
 		respondWithError(w, http.StatusForbidden, "Not author of chirp, can't delete", err)
+		return
 	}
 
-	err = cfg.db.DeleteChirp(req.Context(), database.DeleteChirpParams{
-		ID:     chirp.ID,
-		UserID: userID,
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.DeleteChirp(req.Context(), database.DeleteChirpParams{
+		ID:        chirp.ID,
+		UserID:    userID,
+		DeletedBy: uuid.NullUUID{UUID: userID, Valid: true},
 	})
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Could not find chirp by ID provided", err)
 		return
 	}
 
+	err = recordChirpEvent(req.Context(), qtx, EventChirpDeleted, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording chirp event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting chirp", err)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// handler that changes the body of one of the user's published chirps, only while it's inside the edit window
+// of their plan. Mentions and who can see the chirp stay as they were when it was published
+func (cfg *apiConfig) handlerEditChirp(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to edit chirp", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	plan, err := cfg.planForUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error looking up user's plan", err)
+		return
+	}
+	if !plan.Can(entitlements.CapabilityEditChirps) {
+		respondWithError(w, http.StatusForbidden, "Editing chirps is a Chirpy Red feature", nil)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(req.Context(), chirpID)
+	if err != nil || chirp.UserID != userID {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	now := time.Now().UTC()
+	if !plan.CanEditChirp(chirp.CreatedAt, now) {
+		respondWithError(w, http.StatusForbidden, "The edit window for this chirp has passed", nil)
+		return
+	}
+
+	chirpBody, err := validateChirp(params.Body, plan.MaxChirpLength)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// the window is checked again by the update in case it ran out in between
+	qtx := cfg.db.WithTx(tx)
+	edited, err := qtx.EditChirp(req.Context(), database.EditChirpParams{
+		Body:           chirpBody,
+		ID:             chirp.ID,
+		UserID:         userID,
+		PublishedAfter: now.Add(-time.Duration(plan.EditWindow)),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusForbidden, "The edit window for this chirp has passed", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error editing chirp", err)
+		return
+	}
+
+	err = recordChirpEvent(req.Context(), qtx, EventChirpUpdated, edited)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording chirp event", err)
+		return
+	}
+
+	structuredChirps := []Chirp{structureChirp(edited)}
+	err = attachChirpDetails(req.Context(), qtx, userID, structuredChirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving chirp details from database", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error editing chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		structuredChirps[0],
+	})
+}
+
+// handler that brings back one of the user's own deleted chirps, chirps removed by a moderator can't be restored
+func (cfg *apiConfig) handlerRestoreChirp(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to restore chirp", err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByIDIncludingDeleted(req.Context(), chirpID)
+	if err != nil || chirp.UserID != userID {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if !chirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Chirp isn't deleted", nil)
+		return
+	}
+	if chirp.DeletedBy.UUID != userID {
+		respondWithError(w, http.StatusForbidden, "Chirp was removed by a moderator and can't be restored", nil)
+		return
+	}
+	deletedAfter := time.Now().UTC().Add(-chirpRestoreWindow)
+	if !chirp.DeletedAt.Time.After(deletedAfter) {
+		respondWithError(w, http.StatusGone, "Chirp was deleted too long ago to restore", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	restored, err := qtx.RestoreChirp(req.Context(), database.RestoreChirpParams{
+		ID:           chirp.ID,
+		UserID:       userID,
+		DeletedAfter: deletedAfter,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Chirp isn't deleted", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring chirp", err)
+		return
+	}
+
+	structuredChirp := structureChirp(restored)
+
+	err = recordChirpEvent(req.Context(), qtx, EventChirpRestored, restored)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording chirp event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		structuredChirp,
+	})
+}
+
+// handler that lists the user's deleted chirps that can still be restored, most recently deleted first
+func (cfg *apiConfig) handlerGetDeletedChirps(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view deleted chirps", err)
+		return
+	}
+
+	chirps, err := cfg.db.GetRestorableChirps(req.Context(), database.GetRestorableChirpsParams{
+		UserID:       userID,
+		DeletedAfter: time.Now().UTC().Add(-chirpRestoreWindow),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving deleted chirps from database", err)
+		return
+	}
+
+	deletedChirps := []DeletedChirp{}
+	for _, chirp := range chirps {
+		deletedChirps = append(deletedChirps, DeletedChirp{
+			Chirp:           structureChirp(chirp),
+			RestorableUntil: chirp.DeletedAt.Time.Add(chirpRestoreWindow),
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, deletedChirps)
+}
+
+// helper that fills in everything on a page of chirps that depends on other rows or on who's looking, their
+// polls, quoted chirps and share counts
+func attachChirpDetails(ctx context.Context, db *database.Queries, viewerID uuid.UUID, chirps []Chirp) error {
+	err := attachPolls(ctx, db, viewerID, chirps)
+	if err != nil {
+		return err
+	}
+	err = attachMedia(ctx, db, chirps)
+	if err != nil {
+		return err
+	}
+
+	return attachQuotes(ctx, db, viewerID, chirps)
+}
+
+func structureChirp(chirp database.Chirp) Chirp {
+	structuredChirp := Chirp{
+		ID:         chirp.ID,
+		CreatedAt:  chirp.CreatedAt,
+		UpdatedAt:  chirp.UpdatedAt,
+		Body:       chirp.Body,
+		UserID:     chirp.UserID,
+		Visibility: chirp.Visibility,
+		Status:     chirp.Status,
+	}
+	if chirp.ReplyToID.Valid {
+		structuredChirp.ReplyToID = &chirp.ReplyToID.UUID
+	}
+	if chirp.QuoteOfID.Valid {
+		structuredChirp.QuoteOf = &chirp.QuoteOfID.UUID
+	}
+	if chirp.PublishAt.Valid {
+		structuredChirp.PublishAt = &chirp.PublishAt.Time
+	}
+	if chirp.ExpiresIn.Valid {
+		structuredChirp.ExpiresIn = &chirp.ExpiresIn.Int32
+	}
+	if chirp.ExpiresAt.Valid {
+		structuredChirp.ExpiresAt = &chirp.ExpiresAt.Time
+	}
+	if chirp.DeletedAt.Valid {
+		structuredChirp.DeletedAt = &chirp.DeletedAt.Time
+	}
+
+	return structuredChirp
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestParseChirpStatus(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		status        string
+		publishAt     *time.Time
+		wantStatus    string
+		wantPublishAt sql.NullTime
+		wantErr       bool
+	}{
+		{name: "defaults to published", status: "", wantStatus: chirpStatusPublished},
+		{name: "published", status: chirpStatusPublished, wantStatus: chirpStatusPublished},
+		{name: "draft", status: chirpStatusDraft, wantStatus: chirpStatusDraft},
+		{name: "unknown status", status: "archived", wantErr: true},
+		{name: "draft with publish_at", status: chirpStatusDraft, publishAt: &later, wantErr: true},
+		{name: "published with publish_at", status: "", publishAt: &later, wantErr: true},
+		{name: "scheduled without publish_at", status: chirpStatusScheduled, wantErr: true},
+		{name: "scheduled in the past", status: chirpStatusScheduled, publishAt: &earlier, wantErr: true},
+		{name: "scheduled for now", status: chirpStatusScheduled, publishAt: &now, wantErr: true},
+		{
+			name:          "scheduled in the future",
+			status:        chirpStatusScheduled,
+			publishAt:     &later,
+			wantStatus:    chirpStatusScheduled,
+			wantPublishAt: sql.NullTime{Time: later, Valid: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, publishAt, err := parseChirpStatus(tt.status, tt.publishAt, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseChirpStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if status != tt.wantStatus || publishAt != tt.wantPublishAt {
+				t.Errorf("parseChirpStatus() = %q, %v, want %q, %v", status, publishAt, tt.wantStatus, tt.wantPublishAt)
+			}
+		})
+	}
+}
+
+func TestParseChirpExpiry(t *testing.T) {
+	seconds := func(n int32) *int32 {
+		return &n
+	}
+	userDefault := sql.NullInt32{Int32: 3600, Valid: true}
+
+	tests := []struct {
+		name             string
+		expiresIn        *int32
+		defaultExpiresIn sql.NullInt32
+		want             sql.NullInt32
+		wantErr          bool
+	}{
+		{name: "no default", want: sql.NullInt32{}},
+		{name: "falls back to the default", defaultExpiresIn: userDefault, want: userDefault},
+		{name: "zero keeps it forever", expiresIn: seconds(0), defaultExpiresIn: userDefault, want: sql.NullInt32{}},
+		{name: "shortest", expiresIn: seconds(60), want: sql.NullInt32{Int32: 60, Valid: true}},
+		{name: "longest", expiresIn: seconds(30 * 24 * 60 * 60), want: sql.NullInt32{Int32: 30 * 24 * 60 * 60, Valid: true}},
+		{name: "too short", expiresIn: seconds(59), wantErr: true},
+		{name: "too long", expiresIn: seconds(30*24*60*60 + 1), wantErr: true},
+		{name: "negative", expiresIn: seconds(-60), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChirpExpiry(tt.expiresIn, tt.defaultExpiresIn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseChirpExpiry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseChirpExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,600 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxConversationMembers = 10
+	maxMessageLength       = 1000
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 100
+)
+
+type Conversation struct {
+	ID          uuid.UUID            `json:"id"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	IsGroup     bool                 `json:"is_group"`
+	Members     []ConversationMember `json:"members"`
+	UnreadCount int64                `json:"unread_count"`
+}
+
+type ConversationMember struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID   `json:"id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	SenderID       uuid.UUID   `json:"sender_id"`
+	Body           string      `json:"body"`
+	ReadBy         []uuid.UUID `json:"read_by"`
+}
+
+// handler that starts a one-to-one or small group conversation, everyone added has to be a mutual follow of the
+// logged in user or have opened their DMs to anyone
+func (cfg *apiConfig) handlerCreateConversation(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to start a conversation", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	participantIDs := []uuid.UUID{}
+	for _, participantID := range params.ParticipantIDs {
+		if participantID != userID && !slices.Contains(participantIDs, participantID) {
+			participantIDs = append(participantIDs, participantID)
+		}
+	}
+	if len(participantIDs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "A conversation needs at least one other participant", nil)
+		return
+	}
+	if len(participantIDs)+1 > maxConversationMembers {
+		respondWithError(w, http.StatusBadRequest, "Conversations can have at most 10 members", nil)
+		return
+	}
+
+	for _, participantID := range participantIDs {
+		participant, err := cfg.db.GetUserByID(req.Context(), participantID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+			return
+		}
+
+		allowed, err := cfg.canMessage(req, userID, participant)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking if user accepts messages", err)
+			return
+		}
+		if !allowed {
+			respondWithError(w, http.StatusForbidden, "User only accepts direct messages from mutual follows", nil)
+			return
+		}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+
+	// there's only ever one one-to-one conversation between two people, the pair is locked until this commits
+	// so two requests starting the same conversation can't both create it
+	if len(participantIDs) == 1 {
+		err = qtx.LockDirectConversation(req.Context(), database.LockDirectConversationParams{
+			UserID:      userID,
+			OtherUserID: participantIDs[0],
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error starting conversation", err)
+			return
+		}
+
+		existing, err := qtx.GetDirectConversation(req.Context(), database.GetDirectConversationParams{
+			UserID:      userID,
+			OtherUserID: participantIDs[0],
+		})
+		if err == nil {
+			cfg.respondWithConversation(w, req, http.StatusOK, existing)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving conversation from database", err)
+			return
+		}
+	}
+
+	conversation, err := qtx.CreateConversation(req.Context(), database.CreateConversationParams{
+		CreatedBy: userID,
+		IsGroup:   len(participantIDs) > 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+		return
+	}
+
+	for _, memberID := range append([]uuid.UUID{userID}, participantIDs...) {
+		err = qtx.AddConversationMember(req.Context(), database.AddConversationMemberParams{
+			ConversationID: conversation.ID,
+			UserID:         memberID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error adding conversation member", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+		return
+	}
+
+	cfg.respondWithConversation(w, req, http.StatusCreated, conversation)
+}
+
+// handler that lists the logged in user's conversations, most recently active first
+func (cfg *apiConfig) handlerGetConversations(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view conversations", err)
+		return
+	}
+
+	conversations, err := cfg.db.GetConversationsForUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving conversations from database", err)
+		return
+	}
+
+	structuredConversations := []Conversation{}
+	for _, conversation := range conversations {
+		members, err := cfg.db.GetConversationMembers(req.Context(), conversation.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving conversation members from database", err)
+			return
+		}
+
+		structuredConversations = append(structuredConversations, Conversation{
+			ID:          conversation.ID,
+			CreatedAt:   conversation.CreatedAt,
+			UpdatedAt:   conversation.UpdatedAt,
+			IsGroup:     conversation.IsGroup,
+			Members:     structureConversationMembers(members),
+			UnreadCount: conversation.UnreadCount,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredConversations)
+}
+
+// handler that sends a message to a conversation, message bodies go through the same filtering as chirps
+func (cfg *apiConfig) handlerSendMessage(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	conversation, members, userID, ok := cfg.authorizeConversation(w, req)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if strings.TrimSpace(params.Body) == "" {
+		respondWithError(w, http.StatusBadRequest, "Message can't be empty", nil)
+		return
+	}
+
+	// who accepts messages from the sender can change after a conversation starts, through a block, an
+	// unfollow or closing DMs, so everyone else in it is checked again on every message
+	for _, member := range members {
+		if member.UserID == userID {
+			continue
+		}
+		recipient, err := cfg.db.GetUserByID(req.Context(), member.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving conversation members from database", err)
+			return
+		}
+
+		allowed, err := cfg.canMessage(req, userID, recipient)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking if user accepts messages", err)
+			return
+		}
+		if !allowed {
+			respondWithError(w, http.StatusForbidden, "Everyone in this conversation has to accept messages from you", nil)
+			return
+		}
+	}
+
+	messageBody, err := validateChirp(params.Body, maxMessageLength)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Message is too long", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	message, err := qtx.CreateMessage(req.Context(), database.CreateMessageParams{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Body:           messageBody,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+
+	err = qtx.TouchConversation(req.Context(), conversation.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+
+	// whoever sends a message has read everything up to it
+	err = qtx.MarkConversationRead(req.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, structureMessage(message, members))
+}
+
+// handler that pages through a conversation's messages newest first, pages continue from the next_cursor
+// of the previous page
+func (cfg *apiConfig) handlerGetMessages(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Messages   []Message `json:"messages"`
+		NextCursor string    `json:"next_cursor,omitempty"`
+	}
+
+	conversation, members, userID, ok := cfg.authorizeConversation(w, req)
+	if !ok {
+		return
+	}
+
+	pageSize := defaultMessagePageSize
+	if limitStr := req.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxMessagePageSize {
+			respondWithError(w, http.StatusBadRequest, "Limit must be between 1 and 100", err)
+			return
+		}
+		pageSize = parsed
+	}
+
+	cursorTime, cursorID := firstPageCursor()
+	if cursor := req.URL.Query().Get("cursor"); cursor != "" {
+		var err error
+		cursorTime, cursorID, err = decodeCursor(cursor)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+	}
+
+	messages, err := cfg.db.GetMessages(req.Context(), database.GetMessagesParams{
+		UserID:         userID,
+		ConversationID: conversation.ID,
+		CursorTime:     cursorTime,
+		CursorID:       cursorID,
+		PageSize:       int32(pageSize),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving messages from database", err)
+		return
+	}
+
+	structuredMessages := []Message{}
+	for _, message := range messages {
+		structuredMessages = append(structuredMessages, structureMessage(message, members))
+	}
+
+	nextCursor := ""
+	if len(messages) == pageSize {
+		last := messages[len(messages)-1]
+		nextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Messages:   structuredMessages,
+		NextCursor: nextCursor,
+	})
+}
+
+// handler that records the logged in user has read everything in a conversation, this is what read receipts show
+func (cfg *apiConfig) handlerMarkConversationRead(w http.ResponseWriter, req *http.Request) {
+	conversation, _, userID, ok := cfg.authorizeConversation(w, req)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.MarkConversationRead(req.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error marking conversation as read", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that deletes a message for the logged in user only, everyone else still sees it
+func (cfg *apiConfig) handlerDeleteMessage(w http.ResponseWriter, req *http.Request) {
+	conversation, _, userID, ok := cfg.authorizeConversation(w, req)
+	if !ok {
+		return
+	}
+
+	messageID, err := uuid.Parse(req.PathValue("messageID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid message ID format", err)
+		return
+	}
+
+	message, err := cfg.db.GetMessageByID(req.Context(), messageID)
+	if err != nil || message.ConversationID != conversation.ID {
+		respondWithError(w, http.StatusNotFound, "Message not found", err)
+		return
+	}
+
+	err = cfg.db.DeleteMessageForUser(req.Context(), database.DeleteMessageForUserParams{
+		MessageID: message.ID,
+		UserID:    userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting message", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that clears a conversation for the logged in user, it comes back with only the new messages if
+// someone writes again
+func (cfg *apiConfig) handlerDeleteConversation(w http.ResponseWriter, req *http.Request) {
+	conversation, _, userID, ok := cfg.authorizeConversation(w, req)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.ClearConversation(req.Context(), database.ClearConversationParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting conversation", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that lets the logged in user accept direct messages from anyone rather than only mutual follows
+func (cfg *apiConfig) handlerUpdateDMSettings(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Open bool `json:"open"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to update DM settings", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	err = cfg.db.SetUserDMsOpen(req.Context(), database.SetUserDMsOpenParams{
+		ID:      userID,
+		DmsOpen: params.Open,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating DM settings", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, params)
+}
+
+// helper that loads the conversation in the path and makes sure the logged in user is one of its members
+func (cfg *apiConfig) authorizeConversation(w http.ResponseWriter, req *http.Request) (database.Conversation, []database.ConversationMember, uuid.UUID, bool) {
+	conversationID, err := uuid.Parse(req.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid conversation ID format", err)
+		return database.Conversation{}, nil, uuid.Nil, false
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return database.Conversation{}, nil, uuid.Nil, false
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view conversation", err)
+		return database.Conversation{}, nil, uuid.Nil, false
+	}
+
+	conversation, err := cfg.db.GetConversationByID(req.Context(), conversationID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Conversation not found", err)
+		return database.Conversation{}, nil, uuid.Nil, false
+	}
+
+	members, err := cfg.db.GetConversationMembers(req.Context(), conversation.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving conversation members from database", err)
+		return database.Conversation{}, nil, uuid.Nil, false
+	}
+
+	isMember := slices.ContainsFunc(members, func(member database.ConversationMember) bool {
+		return member.UserID == userID
+	})
+	if !isMember {
+		respondWithError(w, http.StatusNotFound, "Conversation not found", nil)
+		return database.Conversation{}, nil, uuid.Nil, false
+	}
+
+	return conversation, members, userID, true
+}
+
+// helper that checks whether sender may start a conversation with recipient, nobody can message across a block
+func (cfg *apiConfig) canMessage(req *http.Request, senderID uuid.UUID, recipient database.User) (bool, error) {
+	blocked, err := cfg.db.IsBlockedEitherWay(req.Context(), database.IsBlockedEitherWayParams{
+		UserID:      senderID,
+		OtherUserID: recipient.ID,
+	})
+	if err != nil || blocked {
+		return false, err
+	}
+
+	if recipient.DmsOpen {
+		return true, nil
+	}
+
+	return cfg.db.IsMutualFollow(req.Context(), database.IsMutualFollowParams{
+		UserID:      senderID,
+		OtherUserID: recipient.ID,
+	})
+}
+
+func (cfg *apiConfig) respondWithConversation(w http.ResponseWriter, req *http.Request, code int, conversation database.Conversation) {
+	members, err := cfg.db.GetConversationMembers(req.Context(), conversation.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving conversation members from database", err)
+		return
+	}
+
+	respondWithJSON(w, code, Conversation{
+		ID:        conversation.ID,
+		CreatedAt: conversation.CreatedAt,
+		UpdatedAt: conversation.UpdatedAt,
+		IsGroup:   conversation.IsGroup,
+		Members:   structureConversationMembers(members),
+	})
+}
+
+func structureConversationMembers(members []database.ConversationMember) []ConversationMember {
+	structuredMembers := []ConversationMember{}
+	for _, member := range members {
+		structuredMember := ConversationMember{
+			UserID:   member.UserID,
+			JoinedAt: member.JoinedAt,
+		}
+		if member.LastReadAt.Valid {
+			structuredMember.LastReadAt = &member.LastReadAt.Time
+		}
+		structuredMembers = append(structuredMembers, structuredMember)
+	}
+
+	return structuredMembers
+}
+
+// read receipts come from how far each other member has read
+func structureMessage(message database.Message, members []database.ConversationMember) Message {
+	structuredMessage := Message{
+		ID:             message.ID,
+		CreatedAt:      message.CreatedAt,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           message.Body,
+		ReadBy:         []uuid.UUID{},
+	}
+	for _, member := range members {
+		if member.UserID != message.SenderID && member.LastReadAt.Valid && !member.LastReadAt.Time.Before(message.CreatedAt) {
+			structuredMessage.ReadBy = append(structuredMessage.ReadBy, member.UserID)
+		}
+	}
+
+	return structuredMessage
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestStructureMessageReadBy(t *testing.T) {
+	sentAt := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	sender := uuid.New()
+	reader := uuid.New()
+	other := uuid.New()
+
+	readAt := func(at time.Time) sql.NullTime {
+		return sql.NullTime{Time: at, Valid: true}
+	}
+
+	tests := []struct {
+		name    string
+		members []database.ConversationMember
+		want    []uuid.UUID
+	}{
+		{"nobody else", []database.ConversationMember{{UserID: sender}}, []uuid.UUID{}},
+		{"never read", []database.ConversationMember{{UserID: sender}, {UserID: reader}}, []uuid.UUID{}},
+		{"read before it was sent", []database.ConversationMember{{UserID: reader, LastReadAt: readAt(sentAt.Add(-time.Second))}}, []uuid.UUID{}},
+		{"read as it was sent", []database.ConversationMember{{UserID: reader, LastReadAt: readAt(sentAt)}}, []uuid.UUID{reader}},
+		{"read after it was sent", []database.ConversationMember{{UserID: reader, LastReadAt: readAt(sentAt.Add(time.Minute))}}, []uuid.UUID{reader}},
+		{"sender doesn't count", []database.ConversationMember{{UserID: sender, LastReadAt: readAt(sentAt.Add(time.Minute))}}, []uuid.UUID{}},
+		{
+			"only members who caught up",
+			[]database.ConversationMember{
+				{UserID: sender, LastReadAt: readAt(sentAt)},
+				{UserID: reader, LastReadAt: readAt(sentAt.Add(time.Minute))},
+				{UserID: other, LastReadAt: readAt(sentAt.Add(-time.Minute))},
+			},
+			[]uuid.UUID{reader},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := database.Message{ID: uuid.New(), CreatedAt: sentAt, SenderID: sender, Body: "hi"}
+			got := structureMessage(message, tt.members)
+			if !slices.Equal(got.ReadBy, tt.want) {
+				t.Errorf("structureMessage().ReadBy = %v, want %v", got.ReadBy, tt.want)
+			}
+			if got.ID != message.ID || got.Body != message.Body {
+				t.Errorf("structureMessage() = %+v, want the message's fields copied over", got)
+			}
+		})
+	}
+}
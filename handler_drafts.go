@@ -0,0 +1,233 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/entitlements"
+	"github.com/Khazz0r/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// handler that lists the logged in user's drafts and scheduled chirps, scheduled ones come in the order they'll
+// go out. Can be narrowed down with ?status=draft or ?status=scheduled
+func (cfg *apiConfig) handlerGetDrafts(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view drafts", err)
+		return
+	}
+
+	status := req.URL.Query().Get("status")
+	if status != "" && status != chirpStatusDraft && status != chirpStatusScheduled {
+		respondWithError(w, http.StatusBadRequest, "Invalid status, must be draft or scheduled", nil)
+		return
+	}
+
+	chirps, err := cfg.db.GetUnpublishedChirps(req.Context(), database.GetUnpublishedChirpsParams{
+		UserID: userID,
+		Status: status,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving drafts from database", err)
+		return
+	}
+
+	structuredChirps := []Chirp{}
+	for _, chirp := range chirps {
+		structuredChirps = append(structuredChirps, structureChirp(chirp))
+	}
+
+	err = attachChirpDetails(req.Context(), cfg.db, userID, structuredChirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving chirp details from database", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredChirps)
+}
+
+// handler that replaces a draft or scheduled chirp, setting its status to published sends it out straight away
+func (cfg *apiConfig) handlerUpdateDraft(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Body       string     `json:"body"`
+		Visibility string     `json:"visibility"`
+		Status     string     `json:"status"`
+		PublishAt  *time.Time `json:"publish_at"`
+		ExpiresIn  *int32     `json:"expires_in"`
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to update drafts", err)
+		return
+	}
+
+	plan, err := cfg.planForUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error looking up user's plan", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	chirpBody, err := validateChirp(params.Body, plan.MaxChirpLength)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	if params.Visibility == "" {
+		params.Visibility = visibility.Public
+	}
+	if !visibility.IsValid(params.Visibility) {
+		respondWithError(w, http.StatusBadRequest, "Invalid visibility, must be public, unlisted, followers or mentioned", nil)
+		return
+	}
+
+	// leaving the status out keeps it a draft, unlike POST /api/chirps where it means publish now
+	if params.Status == "" {
+		params.Status = chirpStatusDraft
+	}
+	status, publishAt, err := parseChirpStatus(params.Status, params.PublishAt, time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if status == chirpStatusScheduled && !plan.Can(entitlements.CapabilityScheduledPosts) {
+		respondWithError(w, http.StatusForbidden, "Scheduling chirps is a Chirpy Red feature", nil)
+		return
+	}
+
+	author, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving user from database", err)
+		return
+	}
+	expiresIn, err := parseChirpExpiry(params.ExpiresIn, author.DefaultChirpExpiresIn)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	chirp, err := qtx.UpdateUnpublishedChirp(req.Context(), database.UpdateUnpublishedChirpParams{
+		ID:         chirpID,
+		UserID:     userID,
+		Body:       chirpBody,
+		Visibility: params.Visibility,
+		Status:     status,
+		PublishAt:  publishAt,
+		ExpiresIn:  expiresIn,
+		ExpiresAt:  chirpExpiresAt(status, expiresIn, time.Now()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Draft not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating draft", err)
+		return
+	}
+
+	if chirp.Status == chirpStatusPublished {
+		err = publishChirp(req.Context(), qtx, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error publishing chirp", err)
+			return
+		}
+	}
+
+	structuredChirps := []Chirp{structureChirp(chirp)}
+	err = attachChirpDetails(req.Context(), qtx, userID, structuredChirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving chirp details from database", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating draft", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredChirps[0])
+}
+
+// handler that throws away a draft or scheduled chirp, unlike published chirps they can't be restored
+func (cfg *apiConfig) handlerDeleteDraft(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to delete drafts", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteUnpublishedChirp(req.Context(), database.DeleteUnpublishedChirpParams{
+		ID:     chirpID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting draft", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Draft not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
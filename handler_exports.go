@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/takeout"
+	"github.com/google/uuid"
+)
+
+const (
+	// archives are deleted this long after they're built
+	dataExportRetention = 7 * 24 * time.Hour
+	// download links only work for this long, fetching the export again hands out a fresh one
+	dataExportLinkLifetime = 24 * time.Hour
+)
+
+// statuses a data export moves through
+const (
+	exportStatusPending    = "pending"
+	exportStatusProcessing = "processing"
+	exportStatusReady      = "ready"
+	exportStatusFailed     = "failed"
+)
+
+type DataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Status      string     `json:"status"`
+	SizeBytes   int64      `json:"size_bytes"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+// handler that queues a ZIP of everything the logged in user has on Chirpy, they're notified once it's ready.
+// asking again while one is still being built returns that one
+func (cfg *apiConfig) handlerRequestDataExport(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to export data", err)
+		return
+	}
+
+	export, err := cfg.db.GetActiveDataExport(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		export, err = cfg.db.CreateDataExport(req.Context(), userID)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error queueing data export", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, cfg.structureDataExport(export))
+}
+
+// handler that lists the logged in user's recent data exports
+func (cfg *apiConfig) handlerGetDataExports(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view data exports", err)
+		return
+	}
+
+	exports, err := cfg.db.GetDataExportsByUserID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving data exports from database", err)
+		return
+	}
+
+	structuredExports := []DataExport{}
+	for _, export := range exports {
+		structuredExports = append(structuredExports, cfg.structureDataExport(export))
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredExports)
+}
+
+// handler that shows how a data export is getting on, polling it is how clients find the download link
+func (cfg *apiConfig) handlerGetDataExport(w http.ResponseWriter, req *http.Request) {
+	exportID, err := uuid.Parse(req.PathValue("exportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid export ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view data exports", err)
+		return
+	}
+
+	export, err := cfg.db.GetDataExportByID(req.Context(), exportID)
+	if err != nil || export.UserID != userID {
+		respondWithError(w, http.StatusNotFound, "Data export not found", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, cfg.structureDataExport(export))
+}
+
+// handler that serves a finished archive, the signed link stands in for a token so it works straight from
+// a browser
+func (cfg *apiConfig) handlerDownloadDataExport(w http.ResponseWriter, req *http.Request) {
+	exportID, err := uuid.Parse(req.PathValue("exportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid export ID format", err)
+		return
+	}
+
+	err = auth.ValidateDownloadLink(cfg.jwtSecret, exportID.String(), req.URL.Query().Get("expires"), req.URL.Query().Get("signature"), time.Now())
+	if errors.Is(err, auth.ErrExpiredDownloadLink) {
+		respondWithError(w, http.StatusGone, "Download link has expired", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Download link is invalid", err)
+		return
+	}
+
+	export, err := cfg.db.GetDataExportByID(req.Context(), exportID)
+	if err != nil || export.Status != exportStatusReady {
+		respondWithError(w, http.StatusNotFound, "Data export not found", err)
+		return
+	}
+
+	archive, err := cfg.db.GetDataExportArchive(req.Context(), export.ID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Data export not found", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, export.CompletedAt.Time.Format("2006-01-02")))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// helper that gathers everything a user has on Chirpy into a ZIP archive
+func (cfg *apiConfig) buildDataExport(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := takeout.Archive{
+		GeneratedAt: time.Now().UTC(),
+		Profile: takeout.Profile{
+			ID:          user.ID,
+			CreatedAt:   user.CreatedAt,
+			Email:       user.Email,
+			Handle:      user.Handle,
+			DisplayName: user.DisplayName,
+			Bio:         user.Bio,
+			Location:    user.Location,
+			Website:     user.Website,
+			AvatarURL:   user.AvatarUrl,
+			IsChirpyRed: user.IsChirpyRed.Bool,
+		},
+	}
+
+	// drafts, scheduled chirps and deleted chirps waiting to be purged are the user's data too
+	chirps, err := cfg.db.GetAllChirpsByAuthorID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, chirp := range chirps {
+		exportedChirp := takeout.Chirp{
+			ID:         chirp.ID,
+			CreatedAt:  chirp.CreatedAt,
+			UpdatedAt:  chirp.UpdatedAt,
+			Body:       chirp.Body,
+			Visibility: chirp.Visibility,
+			Status:     chirp.Status,
+		}
+		if chirp.ReplyToID.Valid {
+			exportedChirp.ReplyToID = &chirp.ReplyToID.UUID
+		}
+		if chirp.QuoteOfID.Valid {
+			exportedChirp.QuoteOfID = &chirp.QuoteOfID.UUID
+		}
+		if chirp.PublishAt.Valid {
+			exportedChirp.PublishAt = &chirp.PublishAt.Time
+		}
+		if chirp.ExpiresAt.Valid {
+			exportedChirp.ExpiresAt = &chirp.ExpiresAt.Time
+		}
+		if chirp.DeletedAt.Valid {
+			exportedChirp.DeletedAt = &chirp.DeletedAt.Time
+		}
+		archive.Chirps = append(archive.Chirps, exportedChirp)
+	}
+
+	messages, err := cfg.db.GetMessagesForExport(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		archive.Messages = append(archive.Messages, takeout.Message{
+			ID:             message.ID,
+			ConversationID: message.ConversationID,
+			CreatedAt:      message.CreatedAt,
+			SenderID:       message.SenderID,
+			Body:           message.Body,
+		})
+	}
+
+	likes, err := cfg.db.GetLikesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, like := range likes {
+		archive.Likes = append(archive.Likes, takeout.Reaction{ChirpID: like.ChirpID, CreatedAt: like.CreatedAt})
+	}
+
+	rechirps, err := cfg.db.GetRechirpsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rechirp := range rechirps {
+		archive.Rechirps = append(archive.Rechirps, takeout.Reaction{ChirpID: rechirp.ChirpID, CreatedAt: rechirp.CreatedAt})
+	}
+
+	following, err := cfg.db.GetFollowing(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, follow := range following {
+		archive.Following = append(archive.Following, takeout.Follow{UserID: follow.FolloweeID, CreatedAt: follow.CreatedAt})
+	}
+
+	followers, err := cfg.db.GetFollowers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, follow := range followers {
+		archive.Followers = append(archive.Followers, takeout.Follow{UserID: follow.FollowerID, CreatedAt: follow.CreatedAt})
+	}
+
+	refreshTokens, err := cfg.db.GetRefreshTokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, refreshToken := range refreshTokens {
+		session := takeout.Session{CreatedAt: refreshToken.CreatedAt, ExpiresAt: refreshToken.ExpiresAt}
+		if refreshToken.RevokedAt.Valid {
+			session.RevokedAt = &refreshToken.RevokedAt.Time
+		}
+		archive.Sessions = append(archive.Sessions, session)
+	}
+
+	credentials, err := cfg.db.GetWebAuthnCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, credential := range credentials {
+		passkey := takeout.Passkey{ID: base64.RawURLEncoding.EncodeToString(credential.ID), CreatedAt: credential.CreatedAt}
+		if credential.LastUsedAt.Valid {
+			passkey.LastUsedAt = &credential.LastUsedAt.Time
+		}
+		archive.Passkeys = append(archive.Passkeys, passkey)
+	}
+
+	var buf bytes.Buffer
+	err = takeout.Write(&buf, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (cfg *apiConfig) structureDataExport(export database.DataExport) DataExport {
+	structuredExport := DataExport{
+		ID:        export.ID,
+		CreatedAt: export.CreatedAt,
+		UpdatedAt: export.UpdatedAt,
+		Status:    export.Status,
+		SizeBytes: export.SizeBytes,
+		Error:     export.Error.String,
+	}
+	if export.CompletedAt.Valid {
+		structuredExport.CompletedAt = &export.CompletedAt.Time
+	}
+	if export.ExpiresAt.Valid {
+		structuredExport.ExpiresAt = &export.ExpiresAt.Time
+	}
+
+	if export.Status == exportStatusReady && export.ExpiresAt.Valid {
+		linkExpires := time.Now().Add(dataExportLinkLifetime)
+		if export.ExpiresAt.Time.Before(linkExpires) {
+			linkExpires = export.ExpiresAt.Time
+		}
+		query := url.Values{}
+		query.Set("expires", strconv.FormatInt(linkExpires.Unix(), 10))
+		query.Set("signature", auth.SignDownloadLink(cfg.jwtSecret, export.ID.String(), linkExpires))
+		structuredExport.DownloadURL = "/api/exports/" + export.ID.String() + "/download?" + query.Encode()
+	}
+
+	return structuredExport
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type FollowRequest struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// handler that has the logged in user follow another user, following a protected account sends a follow
+// request instead which they have to approve
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, req *http.Request) {
+	followeeID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to follow users", err)
+		return
+	}
+
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't follow yourself", nil)
+		return
+	}
+
+	followee, err := cfg.db.GetUserByID(req.Context(), followeeID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// the pair is locked so a block made at the same time can't slip in between the check and the follow
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.LockUserPair(req.Context(), database.LockUserPairParams{
+		UserID:      userID,
+		OtherUserID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error following user", err)
+		return
+	}
+
+	blocked, err := qtx.IsBlockedEitherWay(req.Context(), database.IsBlockedEitherWayParams{
+		UserID:      userID,
+		OtherUserID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking blocked users", err)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, "You can't follow this user", nil)
+		return
+	}
+	if followee.IsProtected {
+		following, err := qtx.IsFollowing(req.Context(), database.IsFollowingParams{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking followed users", err)
+			return
+		}
+		if following {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		requested, err := qtx.CreateFollowRequest(req.Context(), database.CreateFollowRequestParams{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error requesting to follow user", err)
+			return
+		}
+		if requested > 0 {
+			err = notifyUser(req.Context(), qtx, followeeID, userID, notificationFollowRequest, uuid.NullUUID{}, notificationFollowRequest)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Error notifying followed user", err)
+				return
+			}
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error requesting to follow user", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	followed, err := qtx.FollowUser(req.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error following user", err)
+		return
+	}
+
+	// following someone twice shouldn't notify them twice
+	if followed > 0 {
+		err = notifyUser(req.Context(), qtx, followeeID, userID, notificationFollow, uuid.NullUUID{}, notificationFollow)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error notifying followed user", err)
+			return
+		}
+
+		err = recordFollowEvent(req.Context(), qtx, userID, followeeID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording follow event", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error following user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that has the logged in user stop following another user, it also cancels a pending follow request
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, req *http.Request) {
+	followeeID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to unfollow users", err)
+		return
+	}
+
+	err = cfg.db.UnfollowUser(req.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unfollowing user", err)
+		return
+	}
+
+	_, err = cfg.db.DeleteFollowRequest(req.Context(), database.DeleteFollowRequestParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error cancelling follow request", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that lists the people waiting for the logged in user to approve their follow, oldest first
+func (cfg *apiConfig) handlerGetFollowRequests(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view follow requests", err)
+		return
+	}
+
+	requests, err := cfg.db.GetFollowRequests(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving follow requests from database", err)
+		return
+	}
+
+	structuredRequests := []FollowRequest{}
+	for _, request := range requests {
+		structuredRequests = append(structuredRequests, FollowRequest{
+			FollowerID: request.FollowerID,
+			CreatedAt:  request.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredRequests)
+}
+
+// handler that lets someone who asked follow the logged in user
+func (cfg *apiConfig) handlerApproveFollowRequest(w http.ResponseWriter, req *http.Request) {
+	followerID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to approve follow requests", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	approved, err := approveFollowRequest(req.Context(), cfg.db.WithTx(tx), followerID, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error approving follow request", err)
+		return
+	}
+	if !approved {
+		respondWithError(w, http.StatusNotFound, "Follow request not found", nil)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error approving follow request", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that turns down a follow request, the person who asked isn't told
+func (cfg *apiConfig) handlerDenyFollowRequest(w http.ResponseWriter, req *http.Request) {
+	followerID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to deny follow requests", err)
+		return
+	}
+
+	denied, err := cfg.db.DeleteFollowRequest(req.Context(), database.DeleteFollowRequestParams{
+		FollowerID: followerID,
+		FolloweeID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error denying follow request", err)
+		return
+	}
+	if denied == 0 {
+		respondWithError(w, http.StatusNotFound, "Follow request not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that protects the logged in user's account so only approved followers see their chirps, turning it
+// off approves everyone still waiting
+func (cfg *apiConfig) handlerUpdateProtected(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Protected bool `json:"protected"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to update account protection", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	_, err = qtx.SetUserProtected(req.Context(), database.SetUserProtectedParams{
+		ID:          userID,
+		IsProtected: params.Protected,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating account protection", err)
+		return
+	}
+
+	if !params.Protected {
+		requests, err := qtx.GetFollowRequests(req.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving follow requests from database", err)
+			return
+		}
+		for _, request := range requests {
+			_, err = approveFollowRequest(req.Context(), qtx, request.FollowerID, userID)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Error approving follow request", err)
+				return
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating account protection", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, params)
+}
+
+// helper that turns a follow request into a follow and lets the follower know, it reports false when there
+// was no request. Should be given the transaction's queries
+func approveFollowRequest(ctx context.Context, db *database.Queries, followerID, followeeID uuid.UUID) (bool, error) {
+	// a block removes follow requests, so once the pair is locked a request that's still there isn't blocked
+	err := db.LockUserPair(ctx, database.LockUserPairParams{
+		UserID:      followerID,
+		OtherUserID: followeeID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := db.DeleteFollowRequest(ctx, database.DeleteFollowRequestParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil || removed == 0 {
+		return false, err
+	}
+
+	followed, err := db.FollowUser(ctx, database.FollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		return false, err
+	}
+	if followed > 0 {
+		err = recordFollowEvent(ctx, db, followerID, followeeID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, notifyUser(ctx, db, followerID, followeeID, notificationFollowAccepted, uuid.NullUUID{}, notificationFollowAccepted)
+}
+
+// helper that records a user.followed event against the follower, should be given the transaction's queries
+// like recordEvent
+func recordFollowEvent(ctx context.Context, db *database.Queries, followerID, followeeID uuid.UUID) error {
+	return recordEvent(ctx, db, aggregateUser, followerID, EventUserFollowed, struct {
+		FollowerID uuid.UUID `json:"follower_id"`
+		FolloweeID uuid.UUID `json:"followee_id"`
+	}{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+}
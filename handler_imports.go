@@ -0,0 +1,334 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/importer"
+	"github.com/google/uuid"
+)
+
+const (
+	// archives from years of posting get big, anything past this is turned away before it's read
+	maxImportSize      = 64 << 20
+	importItemsPerPage = 100
+)
+
+// statuses an import and each post in it move through
+const (
+	importStatusPending    = "pending"
+	importStatusProcessing = "processing"
+	importStatusCompleted  = "completed"
+	importStatusFailed     = "failed"
+
+	importItemPending  = "pending"
+	importItemImported = "imported"
+	importItemSkipped  = "skipped"
+	importItemFailed   = "failed"
+)
+
+type ChirpImport struct {
+	ID          uuid.UUID           `json:"id"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	Source      string              `json:"source"`
+	Status      string              `json:"status"`
+	Error       string              `json:"error,omitempty"`
+	CompletedAt *time.Time          `json:"completed_at"`
+	Progress    ChirpImportProgress `json:"progress"`
+}
+
+type ChirpImportProgress struct {
+	Total    int64 `json:"total"`
+	Pending  int64 `json:"pending"`
+	Imported int64 `json:"imported"`
+	Skipped  int64 `json:"skipped"`
+	Failed   int64 `json:"failed"`
+}
+
+type ChirpImportItem struct {
+	Position   int32      `json:"position"`
+	ExternalID string     `json:"external_id"`
+	PostedAt   time.Time  `json:"posted_at"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+}
+
+// handler that takes a Twitter/X tweets.js or Mastodon outbox.json as the request body and queues its posts
+// to be brought over as chirps, one import runs per user at a time
+func (cfg *apiConfig) handlerCreateChirpImport(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to import chirps", err)
+		return
+	}
+
+	_, err = cfg.db.GetActiveChirpImport(req.Context(), userID)
+	if err == nil {
+		respondWithError(w, http.StatusConflict, "An import is already running, wait for it to finish", nil)
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Error checking for running imports", err)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxImportSize))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Archive is too large", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error reading archive", err)
+		return
+	}
+
+	source, posts, err := importer.Parse(data)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	chirpImport, err := qtx.CreateChirpImport(req.Context(), database.CreateChirpImportParams{
+		UserID: userID,
+		Source: source,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating import", err)
+		return
+	}
+
+	for i, post := range posts {
+		status := importItemPending
+		skipReason := sql.NullString{}
+		if post.SkipReason != "" {
+			status = importItemSkipped
+			skipReason = sql.NullString{String: post.SkipReason, Valid: true}
+		}
+
+		err = qtx.CreateChirpImportItem(req.Context(), database.CreateChirpImportItemParams{
+			ImportID:          chirpImport.ID,
+			Position:          int32(i + 1),
+			ExternalID:        post.ExternalID,
+			ReplyToExternalID: sql.NullString{String: post.ReplyToExternalID, Valid: post.ReplyToExternalID != ""},
+			PostedAt:          post.PostedAt,
+			Body:              post.Body,
+			Status:            status,
+			Error:             skipReason,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error saving archive posts", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving import", err)
+		return
+	}
+
+	cfg.respondWithChirpImport(w, req, http.StatusAccepted, chirpImport)
+}
+
+// handler that lists the logged in user's recent imports
+func (cfg *apiConfig) handlerGetChirpImports(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view imports", err)
+		return
+	}
+
+	chirpImports, err := cfg.db.GetChirpImportsByUserID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving imports from database", err)
+		return
+	}
+
+	structuredImports := []ChirpImport{}
+	for _, chirpImport := range chirpImports {
+		progress, err := cfg.db.GetChirpImportProgress(req.Context(), chirpImport.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving import progress", err)
+			return
+		}
+		structuredImports = append(structuredImports, structureChirpImport(chirpImport, progress))
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredImports)
+}
+
+// handler that shows how far along an import is, clients poll it until the status is completed
+func (cfg *apiConfig) handlerGetChirpImport(w http.ResponseWriter, req *http.Request) {
+	chirpImport, ok := cfg.authorizeChirpImport(w, req)
+	if !ok {
+		return
+	}
+
+	cfg.respondWithChirpImport(w, req, http.StatusOK, chirpImport)
+}
+
+// handler that pages through what happened to each post in an import, ?status=failed narrows it down to
+// the ones that need attention
+func (cfg *apiConfig) handlerGetChirpImportItems(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Items      []ChirpImportItem `json:"items"`
+		NextCursor string            `json:"next_cursor,omitempty"`
+	}
+
+	chirpImport, ok := cfg.authorizeChirpImport(w, req)
+	if !ok {
+		return
+	}
+
+	status := req.URL.Query().Get("status")
+	switch status {
+	case "", importItemPending, importItemImported, importItemSkipped, importItemFailed:
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid status, must be pending, imported, skipped or failed", nil)
+		return
+	}
+
+	// items are in archive order so their position is all a cursor needs
+	afterPosition := 0
+	if cursor := req.URL.Query().Get("cursor"); cursor != "" {
+		var err error
+		afterPosition, err = strconv.Atoi(cursor)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+	}
+
+	items, err := cfg.db.GetChirpImportItems(req.Context(), database.GetChirpImportItemsParams{
+		ImportID:      chirpImport.ID,
+		Status:        status,
+		AfterPosition: int32(afterPosition),
+		PageSize:      importItemsPerPage,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving import results", err)
+		return
+	}
+
+	structuredItems := []ChirpImportItem{}
+	for _, item := range items {
+		structuredItem := ChirpImportItem{
+			Position:   item.Position,
+			ExternalID: item.ExternalID,
+			PostedAt:   item.PostedAt,
+			Status:     item.Status,
+			Error:      item.Error.String,
+		}
+		if item.ChirpID.Valid {
+			structuredItem.ChirpID = &item.ChirpID.UUID
+		}
+		structuredItems = append(structuredItems, structuredItem)
+	}
+
+	nextCursor := ""
+	if len(items) == importItemsPerPage {
+		nextCursor = strconv.Itoa(int(items[len(items)-1].Position))
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Items:      structuredItems,
+		NextCursor: nextCursor,
+	})
+}
+
+// helper that loads the import in the path, responding 404 unless it belongs to the logged in user
+func (cfg *apiConfig) authorizeChirpImport(w http.ResponseWriter, req *http.Request) (database.ChirpImport, bool) {
+	importID, err := uuid.Parse(req.PathValue("importID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid import ID format", err)
+		return database.ChirpImport{}, false
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return database.ChirpImport{}, false
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view imports", err)
+		return database.ChirpImport{}, false
+	}
+
+	chirpImport, err := cfg.db.GetChirpImportByID(req.Context(), importID)
+	if err != nil || chirpImport.UserID != userID {
+		respondWithError(w, http.StatusNotFound, "Import not found", err)
+		return database.ChirpImport{}, false
+	}
+
+	return chirpImport, true
+}
+
+func (cfg *apiConfig) respondWithChirpImport(w http.ResponseWriter, req *http.Request, code int, chirpImport database.ChirpImport) {
+	progress, err := cfg.db.GetChirpImportProgress(req.Context(), chirpImport.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving import progress", err)
+		return
+	}
+
+	respondWithJSON(w, code, structureChirpImport(chirpImport, progress))
+}
+
+func structureChirpImport(chirpImport database.ChirpImport, progress database.GetChirpImportProgressRow) ChirpImport {
+	structuredImport := ChirpImport{
+		ID:        chirpImport.ID,
+		CreatedAt: chirpImport.CreatedAt,
+		UpdatedAt: chirpImport.UpdatedAt,
+		Source:    chirpImport.Source,
+		Status:    chirpImport.Status,
+		Error:     chirpImport.Error.String,
+		Progress: ChirpImportProgress{
+			Total:    progress.Total,
+			Pending:  progress.Pending,
+			Imported: progress.Imported,
+			Skipped:  progress.Skipped,
+			Failed:   progress.Failed,
+		},
+	}
+	if chirpImport.CompletedAt.Valid {
+		structuredImport.CompletedAt = &chirpImport.CompletedAt.Time
+	}
+
+	return structuredImport
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// handler that likes a chirp for the logged in user
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, req *http.Request) {
+	cfg.reactToChirp(w, req, notificationLike, func(ctx context.Context, db *database.Queries, userID, chirpID uuid.UUID) (int64, error) {
+		return db.LikeChirp(ctx, database.LikeChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+// handler that takes back the logged in user's like
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, req *http.Request) {
+	cfg.reactToChirp(w, req, "", func(ctx context.Context, db *database.Queries, userID, chirpID uuid.UUID) (int64, error) {
+		return 0, db.UnlikeChirp(ctx, database.UnlikeChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+// handler that rechirps a chirp for the logged in user
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, req *http.Request) {
+	cfg.reactToChirp(w, req, notificationRechirp, func(ctx context.Context, db *database.Queries, userID, chirpID uuid.UUID) (int64, error) {
+		return db.Rechirp(ctx, database.RechirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+// handler that takes back the logged in user's rechirp
+func (cfg *apiConfig) handlerUndoRechirp(w http.ResponseWriter, req *http.Request) {
+	cfg.reactToChirp(w, req, "", func(ctx context.Context, db *database.Queries, userID, chirpID uuid.UUID) (int64, error) {
+		return 0, db.UndoRechirp(ctx, database.UndoRechirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+// helper shared by likes and rechirps, the chirp's author is notified when a new one is added
+func (cfg *apiConfig) reactToChirp(w http.ResponseWriter, req *http.Request, notificationType string, apply func(ctx context.Context, db *database.Queries, userID, chirpID uuid.UUID) (int64, error)) {
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to react to chirps", err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+
+	blocked, err := cfg.db.IsBlockedEitherWay(req.Context(), database.IsBlockedEitherWayParams{
+		UserID:      userID,
+		OtherUserID: chirp.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking blocked users", err)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+		return
+	}
+
+	// adding a reaction needs the chirp to be visible, and only chirps anyone can see can be rechirped. Taking
+	// one back always works in case the chirp has since been hidden
+	if notificationType != "" {
+		audience, err := chirpAudience(req.Context(), cfg.db, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking chirp visibility", err)
+			return
+		}
+		canView, err := inAudience(req.Context(), cfg.db, audience, userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking chirp visibility", err)
+			return
+		}
+		if !canView {
+			respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+			return
+		}
+		if notificationType == notificationRechirp && !visibility.Shareable(audience) {
+			respondWithError(w, http.StatusForbidden, "Only public chirps can be rechirped", nil)
+			return
+		}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	added, err := apply(req.Context(), qtx, userID, chirp.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving reaction", err)
+		return
+	}
+
+	if added > 0 && notificationType != "" {
+		err = notifyUser(req.Context(), qtx, chirp.UserID, userID, notificationType, uuid.NullUUID{UUID: chirp.ID, Valid: true}, notificationType+":"+chirp.ID.String())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error notifying chirp author", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving reaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,839 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// roles a user can have, admins can do everything moderators can
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+// actions a moderator can take on a report
+const (
+	moderationRemoveChirp = "remove_chirp"
+	moderationWarn        = "warn"
+	moderationSuspend     = "suspend"
+	moderationBan         = "ban"
+)
+
+type ModerationReport struct {
+	Report
+	ReporterID     *uuid.UUID     `json:"reporter_id"`
+	TargetUserID   *uuid.UUID     `json:"target_user_id"`
+	Content        string         `json:"content"`
+	ClaimedBy      *uuid.UUID     `json:"claimed_by"`
+	ClaimedAt      *time.Time     `json:"claimed_at"`
+	ResolvedBy     *uuid.UUID     `json:"resolved_by"`
+	ResolvedAt     *time.Time     `json:"resolved_at"`
+	ResolutionNote string         `json:"resolution_note"`
+	Actions        []ReportAction `json:"actions,omitempty"`
+}
+
+// AccountStanding is what moderators see after suspending or banning someone
+type AccountStanding struct {
+	ID               uuid.UUID  `json:"id"`
+	Handle           string     `json:"handle"`
+	SuspendedUntil   *time.Time `json:"suspended_until"`
+	SuspensionReason string     `json:"suspension_reason"`
+	BannedAt         *time.Time `json:"banned_at"`
+	BanReason        string     `json:"ban_reason"`
+}
+
+// the standing of a user acted on from a report, as it's kept in the audit log
+type reportedStanding struct {
+	AccountStanding
+	ReportID *uuid.UUID `json:"report_id,omitempty"`
+}
+
+type ReportAction struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModeratorID *uuid.UUID `json:"moderator_id"`
+	Action      string     `json:"action"`
+	Note        string     `json:"note"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// handler that lists reports waiting on the moderators oldest first, ?status= picks another part of the queue
+func (cfg *apiConfig) handlerGetReportQueue(w http.ResponseWriter, req *http.Request) {
+	_, ok := cfg.authorizeRole(w, req, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	status := req.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = reportStatusOpen
+	case reportStatusOpen, reportStatusClaimed, reportStatusResolved, reportStatusDismissed:
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid status, must be open, claimed, resolved or dismissed", nil)
+		return
+	}
+
+	reports, err := cfg.db.GetReportQueue(req.Context(), status)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving reports from database", err)
+		return
+	}
+
+	structuredReports := []ModerationReport{}
+	for _, report := range reports {
+		structuredReports = append(structuredReports, structureModerationReport(report, nil))
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredReports)
+}
+
+// handler that shows a single report along with the actions taken on it
+func (cfg *apiConfig) handlerGetReport(w http.ResponseWriter, req *http.Request) {
+	_, ok := cfg.authorizeRole(w, req, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	reportID, err := uuid.Parse(req.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID format", err)
+		return
+	}
+
+	report, err := cfg.db.GetReportByID(req.Context(), reportID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Report not found", err)
+		return
+	}
+
+	cfg.respondWithModerationReport(w, req, report)
+}
+
+// handler that takes a report off the queue for the calling moderator, nobody else can act on it until it's
+// resolved or released
+func (cfg *apiConfig) handlerClaimReport(w http.ResponseWriter, req *http.Request) {
+	moderator, ok := cfg.authorizeRole(w, req, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	reportID, err := uuid.Parse(req.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID format", err)
+		return
+	}
+
+	report, err := cfg.db.ClaimReport(req.Context(), database.ClaimReportParams{
+		ModeratorID: moderator.ID,
+		ID:          reportID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Report doesn't exist, is claimed by another moderator or is closed", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error claiming report", err)
+		return
+	}
+
+	cfg.respondWithModerationReport(w, req, report)
+}
+
+// handler that puts a report the calling moderator claimed back on the queue
+func (cfg *apiConfig) handlerReleaseReport(w http.ResponseWriter, req *http.Request) {
+	moderator, ok := cfg.authorizeRole(w, req, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	reportID, err := uuid.Parse(req.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID format", err)
+		return
+	}
+
+	report, err := cfg.db.ReleaseReport(req.Context(), database.ReleaseReportParams{
+		ID:          reportID,
+		ModeratorID: moderator.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "You haven't claimed this report", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error releasing report", err)
+		return
+	}
+
+	cfg.respondWithModerationReport(w, req, report)
+}
+
+// handler that acts on a claimed report, the action is recorded against the report. only admins can
+// suspend or ban other moderators and admins
+func (cfg *apiConfig) handlerCreateReportAction(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Action         string     `json:"action"`
+		Note           string     `json:"note"`
+		SuspendedUntil *time.Time `json:"suspended_until"`
+	}
+
+	moderator, ok := cfg.authorizeRole(w, req, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	reportID, err := uuid.Parse(req.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID format", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	note := strings.TrimSpace(params.Note)
+
+	report, err := cfg.db.GetReportByID(req.Context(), reportID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Report not found", err)
+		return
+	}
+	if report.Status != reportStatusClaimed || report.ClaimedBy.UUID != moderator.ID {
+		respondWithError(w, http.StatusConflict, "Claim the report before acting on it", nil)
+		return
+	}
+
+	if !report.TargetUserID.Valid {
+		respondWithError(w, http.StatusNotFound, "Reported user no longer exists", nil)
+		return
+	}
+	target, err := cfg.db.GetUserByID(req.Context(), report.TargetUserID.UUID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Reported user no longer exists", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	expiresAt := sql.NullTime{}
+	// every action is tied back to its report in the audit log
+	audited := auditRecord{
+		ActorType:  auditActorUser,
+		ActorID:    moderator.ID.String(),
+		Action:     "moderation." + params.Action,
+		TargetType: auditTargetUser,
+		TargetID:   target.ID.String(),
+		After:      map[string]any{"report_id": report.ID, "note": note},
+	}
+	switch params.Action {
+	case moderationRemoveChirp:
+		if report.TargetType != reportTargetChirp {
+			respondWithError(w, http.StatusBadRequest, "Only reported chirps can be removed", nil)
+			return
+		}
+		chirp, err := qtx.GetChirpByID(req.Context(), report.TargetID)
+		if err != nil {
+			respondWithError(w, http.StatusConflict, "Chirp has already been deleted", err)
+			return
+		}
+		err = qtx.DeleteChirp(req.Context(), database.DeleteChirpParams{
+			ID:        chirp.ID,
+			UserID:    chirp.UserID,
+			DeletedBy: uuid.NullUUID{UUID: moderator.ID, Valid: true},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error removing chirp", err)
+			return
+		}
+		err = recordChirpEvent(req.Context(), qtx, EventChirpDeleted, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording chirp event", err)
+			return
+		}
+		audited.TargetType = auditTargetChirp
+		audited.TargetID = chirp.ID.String()
+		audited.Before = structureChirp(chirp)
+	case moderationWarn:
+	case moderationSuspend, moderationBan:
+		if target.Role != roleUser && moderator.Role != roleAdmin {
+			respondWithError(w, http.StatusForbidden, "Only admins can suspend or ban staff", nil)
+			return
+		}
+		reason := sql.NullString{String: note, Valid: note != ""}
+		updated := database.User{}
+
+		if params.Action == moderationSuspend {
+			if params.SuspendedUntil == nil || !params.SuspendedUntil.After(time.Now()) {
+				respondWithError(w, http.StatusBadRequest, "Suspensions need a suspended_until in the future", nil)
+				return
+			}
+			expiresAt = sql.NullTime{Time: params.SuspendedUntil.UTC(), Valid: true}
+			updated, err = qtx.SuspendUser(req.Context(), database.SuspendUserParams{
+				ID:               target.ID,
+				SuspendedUntil:   expiresAt,
+				SuspensionReason: reason,
+			})
+		} else {
+			updated, err = banUser(req.Context(), qtx, target.ID, note)
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating reported user", err)
+			return
+		}
+		audited.Before = reportedStanding{AccountStanding: structureAccountStanding(target)}
+		audited.After = reportedStanding{AccountStanding: structureAccountStanding(updated), ReportID: &report.ID}
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid action, must be remove_chirp, warn, suspend or ban", nil)
+		return
+	}
+
+	action, err := qtx.CreateReportAction(req.Context(), database.CreateReportActionParams{
+		ReportID:    report.ID,
+		ModeratorID: uuid.NullUUID{UUID: moderator.ID, Valid: true},
+		Action:      params.Action,
+		Note:        note,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording moderation action", err)
+		return
+	}
+
+	err = recordAudit(req.Context(), qtx, audited)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording moderation action in audit log", err)
+		return
+	}
+
+	if params.Action == moderationWarn {
+		err = notifySystem(req.Context(), qtx, target.ID, notificationModerationWarning, uuid.NullUUID{}, "warning:"+action.ID.String())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error notifying reported user", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving moderation action", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, structureReportAction(action))
+}
+
+// handler that closes a claimed report as resolved or dismissed and lets the reporter know it was looked at
+func (cfg *apiConfig) handlerResolveReport(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+
+	moderator, ok := cfg.authorizeRole(w, req, roleModerator, roleAdmin)
+	if !ok {
+		return
+	}
+
+	reportID, err := uuid.Parse(req.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID format", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	if params.Status != reportStatusResolved && params.Status != reportStatusDismissed {
+		respondWithError(w, http.StatusBadRequest, "Invalid status, must be resolved or dismissed", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	report, err := qtx.ResolveReport(req.Context(), database.ResolveReportParams{
+		Status:         params.Status,
+		ModeratorID:    moderator.ID,
+		ResolutionNote: strings.TrimSpace(params.Note),
+		ID:             reportID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Claim the report before resolving it", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error resolving report", err)
+		return
+	}
+
+	err = recordAudit(req.Context(), qtx, auditRecord{
+		ActorType:  auditActorUser,
+		ActorID:    moderator.ID.String(),
+		Action:     auditReportResolved,
+		TargetType: auditTargetReport,
+		TargetID:   report.ID.String(),
+		Before:     map[string]string{"status": reportStatusClaimed},
+		After:      map[string]string{"status": report.Status, "resolution_note": report.ResolutionNote},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording resolution in audit log", err)
+		return
+	}
+
+	// there's no one to tell once the reporter's account has been deleted
+	if report.ReporterID.Valid {
+		err = notifySystem(req.Context(), qtx, report.ReporterID.UUID, notificationReportResolved, uuid.NullUUID{}, "report:"+report.ID.String())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error notifying reporter", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving report", err)
+		return
+	}
+
+	cfg.respondWithModerationReport(w, req, report)
+}
+
+// handler that suspends a user outside of a report until the given time, they can't log in or write
+// anything and their chirps are hidden until it ends
+func (cfg *apiConfig) handlerSuspendUser(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		SuspendedUntil time.Time `json:"suspended_until"`
+		Reason         string    `json:"reason"`
+	}
+
+	moderator, target, ok := cfg.authorizeAccountAction(w, req)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	if !params.SuspendedUntil.After(time.Now()) {
+		respondWithError(w, http.StatusBadRequest, "suspended_until must be in the future", nil)
+		return
+	}
+	reason := strings.TrimSpace(params.Reason)
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	user, err := qtx.SuspendUser(req.Context(), database.SuspendUserParams{
+		ID:               target.ID,
+		SuspendedUntil:   sql.NullTime{Time: params.SuspendedUntil.UTC(), Valid: true},
+		SuspensionReason: sql.NullString{String: reason, Valid: reason != ""},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error suspending user", err)
+		return
+	}
+
+	err = recordStandingAudit(req.Context(), qtx, moderator, auditUserSuspended, target, user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording suspension in audit log", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving suspension", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structureAccountStanding(user))
+}
+
+// handler that ends a suspension early
+func (cfg *apiConfig) handlerLiftSuspension(w http.ResponseWriter, req *http.Request) {
+	moderator, target, ok := cfg.authorizeAccountAction(w, req)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	user, err := qtx.LiftSuspension(req.Context(), target.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error lifting suspension", err)
+		return
+	}
+
+	err = recordStandingAudit(req.Context(), qtx, moderator, auditSuspensionLifted, target, user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording lifted suspension in audit log", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error lifting suspension", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structureAccountStanding(user))
+}
+
+// handler that bans a user outside of a report, they're logged out everywhere straight away
+func (cfg *apiConfig) handlerBanUser(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	moderator, target, ok := cfg.authorizeAccountAction(w, req)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	user, err := banUser(req.Context(), qtx, target.ID, strings.TrimSpace(params.Reason))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error banning user", err)
+		return
+	}
+
+	err = recordStandingAudit(req.Context(), qtx, moderator, auditUserBanned, target, user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording ban in audit log", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving ban", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structureAccountStanding(user))
+}
+
+// handler that lifts a ban, the user has to log in again since their sessions were ended by it
+func (cfg *apiConfig) handlerUnbanUser(w http.ResponseWriter, req *http.Request) {
+	moderator, target, ok := cfg.authorizeAccountAction(w, req)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	user, err := qtx.UnbanUser(req.Context(), target.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error lifting ban", err)
+		return
+	}
+	err = qtx.DeleteBannedEmails(req.Context(), target.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error lifting ban", err)
+		return
+	}
+
+	err = recordStandingAudit(req.Context(), qtx, moderator, auditUserUnbanned, target, user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording lifted ban in audit log", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error lifting ban", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structureAccountStanding(user))
+}
+
+// handler that makes a user a moderator or admin or takes it away again, anyone can on the dev platform so
+// there's a way to make the first admin
+func (cfg *apiConfig) handlerSetUserRole(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	if cfg.platform != "dev" {
+		_, ok := cfg.authorizeRole(w, req, roleAdmin)
+		if !ok {
+			return
+		}
+	}
+
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	if !slices.Contains([]string{roleUser, roleModerator, roleAdmin}, params.Role) {
+		respondWithError(w, http.StatusBadRequest, "Invalid role, must be user, moderator or admin", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	previous, err := qtx.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+
+	user, err := qtx.SetUserRole(req.Context(), database.SetUserRoleParams{
+		ID:   userID,
+		Role: params.Role,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating role", err)
+		return
+	}
+
+	actorType, actorID := cfg.auditActor(req)
+	err = recordAudit(req.Context(), qtx, auditRecord{
+		ActorType:  actorType,
+		ActorID:    actorID,
+		Action:     auditRoleChanged,
+		TargetType: auditTargetUser,
+		TargetID:   user.ID.String(),
+		Before:     map[string]string{"role": previous.Role},
+		After:      map[string]string{"role": user.Role},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording role change in audit log", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving role", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structureUser(user))
+}
+
+// helper that checks the caller is logged in with one of the given roles, the role is looked up on every
+// request so taking it away works straight away
+func (cfg *apiConfig) authorizeRole(w http.ResponseWriter, req *http.Request, roles ...string) (database.User, bool) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return database.User{}, false
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized", err)
+		return database.User{}, false
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized", err)
+		return database.User{}, false
+	}
+	if !slices.Contains(roles, user.Role) {
+		respondWithError(w, http.StatusForbidden, "You don't have permission to do that", nil)
+		return database.User{}, false
+	}
+
+	return user, true
+}
+
+// helper that loads the moderator making the request and the user in the path for them to suspend or ban,
+// only admins can act on staff
+func (cfg *apiConfig) authorizeAccountAction(w http.ResponseWriter, req *http.Request) (database.User, database.User, bool) {
+	moderator, ok := cfg.authorizeRole(w, req, roleModerator, roleAdmin)
+	if !ok {
+		return database.User{}, database.User{}, false
+	}
+
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return database.User{}, database.User{}, false
+	}
+
+	target, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return database.User{}, database.User{}, false
+	}
+	if target.Role != roleUser && moderator.Role != roleAdmin {
+		respondWithError(w, http.StatusForbidden, "Only admins can suspend or ban staff", nil)
+		return database.User{}, database.User{}, false
+	}
+
+	return moderator, target, true
+}
+
+// helper that records a change to a user's standing in the audit log
+func recordStandingAudit(ctx context.Context, db *database.Queries, moderator database.User, action string, before, after database.User) error {
+	return recordAudit(ctx, db, auditRecord{
+		ActorType:  auditActorUser,
+		ActorID:    moderator.ID.String(),
+		Action:     action,
+		TargetType: auditTargetUser,
+		TargetID:   before.ID.String(),
+		Before:     structureAccountStanding(before),
+		After:      structureAccountStanding(after),
+	})
+}
+
+func (cfg *apiConfig) respondWithModerationReport(w http.ResponseWriter, req *http.Request, report database.Report) {
+	actions, err := cfg.db.GetReportActions(req.Context(), report.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving report actions", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structureModerationReport(report, actions))
+}
+
+func structureModerationReport(report database.Report, actions []database.ReportAction) ModerationReport {
+	structuredReport := ModerationReport{
+		Report:         structureReport(report),
+		Content:        report.Content,
+		ResolutionNote: report.ResolutionNote,
+	}
+	// both are cleared once the account has been deleted, the report itself is kept
+	if report.ReporterID.Valid {
+		structuredReport.ReporterID = &report.ReporterID.UUID
+	}
+	if report.TargetUserID.Valid {
+		structuredReport.TargetUserID = &report.TargetUserID.UUID
+	}
+	if report.ClaimedBy.Valid {
+		structuredReport.ClaimedBy = &report.ClaimedBy.UUID
+	}
+	if report.ClaimedAt.Valid {
+		structuredReport.ClaimedAt = &report.ClaimedAt.Time
+	}
+	if report.ResolvedBy.Valid {
+		structuredReport.ResolvedBy = &report.ResolvedBy.UUID
+	}
+	if report.ResolvedAt.Valid {
+		structuredReport.ResolvedAt = &report.ResolvedAt.Time
+	}
+	for _, action := range actions {
+		structuredReport.Actions = append(structuredReport.Actions, structureReportAction(action))
+	}
+
+	return structuredReport
+}
+
+func structureReportAction(action database.ReportAction) ReportAction {
+	structuredAction := ReportAction{
+		ID:        action.ID,
+		CreatedAt: action.CreatedAt,
+		Action:    action.Action,
+		Note:      action.Note,
+	}
+	if action.ModeratorID.Valid {
+		structuredAction.ModeratorID = &action.ModeratorID.UUID
+	}
+	if action.ExpiresAt.Valid {
+		structuredAction.ExpiresAt = &action.ExpiresAt.Time
+	}
+
+	return structuredAction
+}
+
+func structureAccountStanding(user database.User) AccountStanding {
+	standing := AccountStanding{
+		ID:               user.ID,
+		Handle:           user.Handle,
+		SuspensionReason: user.SuspensionReason.String,
+		BanReason:        user.BanReason.String,
+	}
+	if user.SuspendedUntil.Valid {
+		standing.SuspendedUntil = &user.SuspendedUntil.Time
+	}
+	if user.BannedAt.Valid {
+		standing.BannedAt = &user.BannedAt.Time
+	}
+
+	return standing
+}
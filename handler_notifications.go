@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultNotificationPageSize = 20
+	maxNotificationPageSize     = 100
+)
+
+// handler that lists the logged in user's notifications newest first, pages continue from the next_cursor
+// of the previous page
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, req *http.Request) {
+	type response struct {
+		Notifications []Notification `json:"notifications"`
+		UnreadCount   int64          `json:"unread_count"`
+		NextCursor    string         `json:"next_cursor,omitempty"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view notifications", err)
+		return
+	}
+
+	pageSize := defaultNotificationPageSize
+	if limitStr := req.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxNotificationPageSize {
+			respondWithError(w, http.StatusBadRequest, "Limit must be between 1 and 100", err)
+			return
+		}
+		pageSize = parsed
+	}
+
+	cursorTime, cursorID := firstPageCursor()
+	if cursor := req.URL.Query().Get("cursor"); cursor != "" {
+		cursorTime, cursorID, err = decodeCursor(cursor)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+	}
+
+	notifications, err := cfg.db.GetNotifications(req.Context(), database.GetNotificationsParams{
+		UserID:     userID,
+		CursorTime: cursorTime,
+		CursorID:   cursorID,
+		PageSize:   int32(pageSize),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving notifications from database", err)
+		return
+	}
+
+	unreadCount, err := cfg.db.CountUnreadNotifications(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error counting unread notifications", err)
+		return
+	}
+
+	structuredNotifications := []Notification{}
+	for _, notification := range notifications {
+		structuredNotification := Notification{
+			ID:         notification.ID,
+			CreatedAt:  notification.CreatedAt,
+			UpdatedAt:  notification.UpdatedAt,
+			Type:       notification.Type,
+			ActorIDs:   notification.RecentActorIds,
+			ActorCount: notification.ActorCount,
+			Summary:    notificationSummary(notification.Type, notification.ActorCount),
+			Read:       notification.ReadAt.Valid,
+		}
+		if notification.ChirpID.Valid {
+			structuredNotification.ChirpID = &notification.ChirpID.UUID
+		}
+		structuredNotifications = append(structuredNotifications, structuredNotification)
+	}
+
+	nextCursor := ""
+	if len(notifications) == pageSize {
+		last := notifications[len(notifications)-1]
+		nextCursor = encodeCursor(last.UpdatedAt, last.ID)
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Notifications: structuredNotifications,
+		UnreadCount:   unreadCount,
+		NextCursor:    nextCursor,
+	})
+}
+
+// handler that marks one of the logged in user's notifications as read
+func (cfg *apiConfig) handlerMarkNotificationRead(w http.ResponseWriter, req *http.Request) {
+	notificationID, err := uuid.Parse(req.PathValue("notificationID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid notification ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to update notifications", err)
+		return
+	}
+
+	_, err = cfg.db.MarkNotificationRead(req.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error marking notification as read", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that marks every unread notification of the logged in user as read
+func (cfg *apiConfig) handlerMarkAllNotificationsRead(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to update notifications", err)
+		return
+	}
+
+	_, err = cfg.db.MarkAllNotificationsRead(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error marking notifications as read", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that shows which notification types the logged in user receives
+func (cfg *apiConfig) handlerGetNotificationPreferences(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view notification preferences", err)
+		return
+	}
+
+	preferences, err := cfg.notificationPreferences(req, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving notification preferences from database", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, preferences)
+}
+
+// handler that turns notification types on or off for the logged in user, types left out are unchanged
+func (cfg *apiConfig) handlerUpdateNotificationPreferences(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to update notification preferences", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := map[string]bool{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	for notificationType := range params {
+		if !isNotificationType(notificationType) {
+			respondWithError(w, http.StatusBadRequest, "Unknown notification type: "+notificationType, nil)
+			return
+		}
+	}
+
+	for notificationType, enabled := range params {
+		err = cfg.db.SetNotificationPreference(req.Context(), database.SetNotificationPreferenceParams{
+			UserID:  userID,
+			Type:    notificationType,
+			Enabled: enabled,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error saving notification preferences", err)
+			return
+		}
+	}
+
+	preferences, err := cfg.notificationPreferences(req, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving notification preferences from database", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, preferences)
+}
+
+// helper that fills in every notification type, they're all on unless the user turned them off
+func (cfg *apiConfig) notificationPreferences(req *http.Request, userID uuid.UUID) (map[string]bool, error) {
+	stored, err := cfg.db.GetNotificationPreferences(req.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := map[string]bool{}
+	for _, notificationType := range notificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, preference := range stored {
+		preferences[preference.Type] = preference.Enabled
+	}
+
+	return preferences, nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID                `json:"id"`
+	CreatedAt      time.Time                `json:"created_at"`
+	UpdatedAt      time.Time                `json:"updated_at"`
+	EventID        uuid.UUID                `json:"event_id"`
+	EventType      string                   `json:"event_type"`
+	Payload        json.RawMessage          `json:"payload"`
+	Status         string                   `json:"status"`
+	Attempts       int32                    `json:"attempts"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at"`
+	LastStatusCode *int32                   `json:"last_status_code"`
+	LastError      string                   `json:"last_error,omitempty"`
+	Log            []WebhookDeliveryAttempt `json:"log,omitempty"`
+}
+
+type WebhookDeliveryAttempt struct {
+	CreatedAt  time.Time `json:"created_at"`
+	StatusCode *int32    `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int32     `json:"duration_ms"`
+}
+
+// handler that registers an outbound webhook for the logged in user, the signing secret is only shown here
+func (cfg *apiConfig) handlerCreateWebhook(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to create a webhook", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	webhookURL, err := webhooks.ValidateURL(req.Context(), params.URL)
+	if errors.Is(err, webhooks.ErrInvalidURL) {
+		respondWithError(w, http.StatusBadRequest, "Webhook URL must be an absolute http or https URL", err)
+		return
+	}
+	if errors.Is(err, webhooks.ErrForbiddenAddress) {
+		respondWithError(w, http.StatusBadRequest, "Webhook URL must point at a public address", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't resolve webhook URL", err)
+		return
+	}
+	if len(params.Events) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Webhook must subscribe to at least one event", nil)
+		return
+	}
+	for _, eventType := range params.Events {
+		if !isEventType(eventType) {
+			respondWithError(w, http.StatusBadRequest, "Unknown event type: "+eventType, nil)
+			return
+		}
+	}
+
+	secret, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not make webhook secret", err)
+		return
+	}
+
+	webhook, err := cfg.db.CreateWebhookSubscription(req.Context(), database.CreateWebhookSubscriptionParams{
+		UserID: userID,
+		Url:    webhookURL.String(),
+		Secret: secret,
+		Events: params.Events,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating webhook", err)
+		return
+	}
+
+	structuredWebhook := structureWebhook(webhook)
+	structuredWebhook.Secret = webhook.Secret
+
+	respondWithJSON(w, http.StatusCreated, structuredWebhook)
+}
+
+// handler that lists the logged in user's outbound webhooks
+func (cfg *apiConfig) handlerGetWebhooks(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view webhooks", err)
+		return
+	}
+
+	webhooks, err := cfg.db.GetWebhookSubscriptionsByUserID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving webhooks from database", err)
+		return
+	}
+
+	structuredWebhooks := []Webhook{}
+	for _, webhook := range webhooks {
+		structuredWebhooks = append(structuredWebhooks, structureWebhook(webhook))
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredWebhooks)
+}
+
+// handler that removes an outbound webhook along with its delivery history
+func (cfg *apiConfig) handlerDeleteWebhook(w http.ResponseWriter, req *http.Request) {
+	webhookID, err := uuid.Parse(req.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to delete a webhook", err)
+		return
+	}
+
+	err = cfg.db.DeleteWebhookSubscription(req.Context(), database.DeleteWebhookSubscriptionParams{
+		ID:     webhookID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting webhook", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that lists the 100 most recent deliveries for one of the user's webhooks
+func (cfg *apiConfig) handlerGetWebhookDeliveries(w http.ResponseWriter, req *http.Request) {
+	webhook, ok := cfg.authorizeWebhook(w, req)
+	if !ok {
+		return
+	}
+
+	deliveries, err := cfg.db.GetWebhookDeliveriesBySubscriptionID(req.Context(), webhook.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving webhook deliveries from database", err)
+		return
+	}
+
+	structuredDeliveries := []WebhookDelivery{}
+	for _, delivery := range deliveries {
+		structuredDeliveries = append(structuredDeliveries, structureWebhookDelivery(delivery))
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredDeliveries)
+}
+
+// handler that shows a single delivery along with the log of every attempt made
+func (cfg *apiConfig) handlerGetWebhookDelivery(w http.ResponseWriter, req *http.Request) {
+	delivery, ok := cfg.authorizeWebhookDelivery(w, req)
+	if !ok {
+		return
+	}
+
+	attempts, err := cfg.db.GetWebhookDeliveryAttempts(req.Context(), delivery.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving delivery log from database", err)
+		return
+	}
+
+	structuredDelivery := structureWebhookDelivery(delivery)
+	structuredDelivery.Log = []WebhookDeliveryAttempt{}
+	for _, attempt := range attempts {
+		structuredAttempt := WebhookDeliveryAttempt{
+			CreatedAt:  attempt.CreatedAt,
+			Error:      attempt.Error.String,
+			DurationMs: attempt.DurationMs,
+		}
+		if attempt.StatusCode.Valid {
+			structuredAttempt.StatusCode = &attempt.StatusCode.Int32
+		}
+		structuredDelivery.Log = append(structuredDelivery.Log, structuredAttempt)
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredDelivery)
+}
+
+// handler that queues a delivery to be sent again right away, including ones in the dead letter state
+func (cfg *apiConfig) handlerRedeliverWebhook(w http.ResponseWriter, req *http.Request) {
+	delivery, ok := cfg.authorizeWebhookDelivery(w, req)
+	if !ok {
+		return
+	}
+
+	delivery, err := cfg.db.RedeliverWebhookDelivery(req.Context(), delivery.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error queueing redelivery", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, structureWebhookDelivery(delivery))
+}
+
+// helper that loads the webhook in the path and makes sure it belongs to the logged in user
+func (cfg *apiConfig) authorizeWebhook(w http.ResponseWriter, req *http.Request) (database.WebhookSubscription, bool) {
+	webhookID, err := uuid.Parse(req.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID format", err)
+		return database.WebhookSubscription{}, false
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return database.WebhookSubscription{}, false
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view webhooks", err)
+		return database.WebhookSubscription{}, false
+	}
+
+	webhook, err := cfg.db.GetWebhookSubscriptionByID(req.Context(), webhookID)
+	if err != nil || webhook.UserID != userID {
+		respondWithError(w, http.StatusNotFound, "Webhook not found", err)
+		return database.WebhookSubscription{}, false
+	}
+
+	return webhook, true
+}
+
+// helper that loads the delivery in the path and makes sure it belongs to the webhook in the path
+func (cfg *apiConfig) authorizeWebhookDelivery(w http.ResponseWriter, req *http.Request) (database.WebhookDelivery, bool) {
+	webhook, ok := cfg.authorizeWebhook(w, req)
+	if !ok {
+		return database.WebhookDelivery{}, false
+	}
+
+	deliveryID, err := uuid.Parse(req.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery ID format", err)
+		return database.WebhookDelivery{}, false
+	}
+
+	delivery, err := cfg.db.GetWebhookDeliveryByID(req.Context(), deliveryID)
+	if err != nil || delivery.SubscriptionID != webhook.ID {
+		respondWithError(w, http.StatusNotFound, "Webhook delivery not found", err)
+		return database.WebhookDelivery{}, false
+	}
+
+	return delivery, true
+}
+
+func structureWebhook(webhook database.WebhookSubscription) Webhook {
+	return Webhook{
+		ID:        webhook.ID,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+		URL:       webhook.Url,
+		Events:    webhook.Events,
+		Active:    webhook.Active,
+	}
+}
+
+func structureWebhookDelivery(delivery database.WebhookDelivery) WebhookDelivery {
+	structuredDelivery := WebhookDelivery{
+		ID:        delivery.ID,
+		CreatedAt: delivery.CreatedAt,
+		UpdatedAt: delivery.UpdatedAt,
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Payload:   delivery.Payload,
+		Status:    delivery.Status,
+		Attempts:  delivery.Attempts,
+		LastError: delivery.LastError.String,
+	}
+	if delivery.Status == deliveryStatusPending {
+		structuredDelivery.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.LastStatusCode.Valid {
+		structuredDelivery.LastStatusCode = &delivery.LastStatusCode.Int32
+	}
+
+	return structuredDelivery
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/polls"
+	"github.com/google/uuid"
+)
+
+// Poll is attached to the chirp it was posted with, votes and voter_count are left out until the viewer is
+// allowed to see the results
+type Poll struct {
+	Options        []PollOption `json:"options"`
+	MultipleChoice bool         `json:"multiple_choice"`
+	Duration       int32        `json:"duration"`
+	ClosesAt       *time.Time   `json:"closes_at"`
+	Closed         bool         `json:"closed"`
+	VoterCount     *int64       `json:"voter_count,omitempty"`
+	VotedOptionIDs []uuid.UUID  `json:"voted_option_ids"`
+}
+
+type PollOption struct {
+	ID    uuid.UUID `json:"id"`
+	Text  string    `json:"text"`
+	Votes *int64    `json:"votes,omitempty"`
+}
+
+// pollParameters is how a poll is given when creating a chirp, duration is in seconds
+type pollParameters struct {
+	Options        []string `json:"options"`
+	Duration       int32    `json:"duration"`
+	MultipleChoice bool     `json:"multiple_choice"`
+}
+
+// handler that casts the logged in user's vote on a chirp's poll, everyone gets one vote which can pick several
+// options on multiple choice polls. The updated poll comes back with its results
+func (cfg *apiConfig) handlerVotePoll(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		OptionIDs []uuid.UUID `json:"option_ids"`
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID format", err)
+		return
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to vote in polls", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+
+	blocked, err := cfg.db.IsBlockedEitherWay(req.Context(), database.IsBlockedEitherWayParams{
+		UserID:      userID,
+		OtherUserID: chirp.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking blocked users", err)
+		return
+	}
+	canView, err := cfg.canViewChirp(req.Context(), userID, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking chirp visibility", err)
+		return
+	}
+	if blocked || !canView {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+		return
+	}
+
+	poll, err := cfg.db.GetPoll(req.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "This chirp doesn't have a poll", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving poll from database", err)
+		return
+	}
+	if pollClosed(poll, time.Now()) {
+		respondWithError(w, http.StatusConflict, "This poll has closed", nil)
+		return
+	}
+
+	options, err := cfg.db.GetPollOptions(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving poll from database", err)
+		return
+	}
+	optionIDs := []uuid.UUID{}
+	for _, option := range options {
+		optionIDs = append(optionIDs, option.ID)
+	}
+	err = polls.ValidateBallot(params.OptionIDs, optionIDs, poll.MultipleChoice)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	cast, err := qtx.CreatePollBallot(req.Context(), database.CreatePollBallotParams{
+		ChirpID: chirpID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving vote", err)
+		return
+	}
+	if cast == 0 {
+		respondWithError(w, http.StatusConflict, "You've already voted in this poll", nil)
+		return
+	}
+
+	for _, optionID := range params.OptionIDs {
+		err = qtx.AddPollVote(req.Context(), database.AddPollVoteParams{
+			OptionID: optionID,
+			ChirpID:  chirpID,
+			UserID:   userID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error saving vote", err)
+			return
+		}
+	}
+
+	structuredChirps := []Chirp{structureChirp(chirp)}
+	err = attachPolls(req.Context(), qtx, userID, structuredChirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving poll from database", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving vote", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredChirps[0].Poll)
+}
+
+// helper that checks a poll someone is posting before anything is saved, returning the cleaned up options
+func validatePoll(params pollParameters) ([]string, error) {
+	options, err := polls.ValidateOptions(params.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = polls.ValidateDuration(time.Duration(params.Duration) * time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// helper that saves a chirp's poll, it only starts running once the chirp is published
+func createPoll(ctx context.Context, db *database.Queries, chirpID uuid.UUID, params pollParameters, options []string) error {
+	err := db.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:        chirpID,
+		MultipleChoice: params.MultipleChoice,
+		Duration:       params.Duration,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, option := range options {
+		err = db.AddPollOption(ctx, database.AddPollOptionParams{
+			ChirpID:  chirpID,
+			Position: int32(i),
+			Text:     option,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// helper that fills in the polls on a page of chirps as the viewer gets to see them, uuid.Nil is someone who
+// isn't logged in. Everything is looked up in a few queries however many chirps there are
+func attachPolls(ctx context.Context, db *database.Queries, viewerID uuid.UUID, chirps []Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	pollRows, err := db.GetPollsByChirpIDs(ctx, chirpIDs)
+	if err != nil || len(pollRows) == 0 {
+		return err
+	}
+
+	tallies, err := db.GetPollTallies(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+	voterCounts, err := db.GetPollVoterCounts(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+	viewerVotes := []database.GetUserPollVotesRow{}
+	if viewerID != uuid.Nil {
+		viewerVotes, err = db.GetUserPollVotes(ctx, database.GetUserPollVotesParams{
+			UserID:   viewerID,
+			ChirpIds: chirpIDs,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	pollsByChirp := map[uuid.UUID]database.Poll{}
+	for _, poll := range pollRows {
+		pollsByChirp[poll.ChirpID] = poll
+	}
+	optionsByChirp := map[uuid.UUID][]database.GetPollTalliesRow{}
+	for _, tally := range tallies {
+		optionsByChirp[tally.ChirpID] = append(optionsByChirp[tally.ChirpID], tally)
+	}
+	votersByChirp := map[uuid.UUID]int64{}
+	for _, count := range voterCounts {
+		votersByChirp[count.ChirpID] = count.Voters
+	}
+	votedByChirp := map[uuid.UUID][]uuid.UUID{}
+	for _, vote := range viewerVotes {
+		votedByChirp[vote.ChirpID] = append(votedByChirp[vote.ChirpID], vote.OptionID)
+	}
+
+	now := time.Now()
+	for i := range chirps {
+		poll, ok := pollsByChirp[chirps[i].ID]
+		if !ok {
+			continue
+		}
+
+		voted := votedByChirp[poll.ChirpID]
+		closed := pollClosed(poll, now)
+		showResults := polls.ShowResults(len(voted) > 0, closed, viewerID != uuid.Nil && viewerID == chirps[i].UserID)
+
+		structuredPoll := &Poll{
+			Options:        []PollOption{},
+			MultipleChoice: poll.MultipleChoice,
+			Duration:       poll.Duration,
+			Closed:         closed,
+			VotedOptionIDs: []uuid.UUID{},
+		}
+		if poll.ClosesAt.Valid {
+			structuredPoll.ClosesAt = &poll.ClosesAt.Time
+		}
+		if voted != nil {
+			structuredPoll.VotedOptionIDs = voted
+		}
+		if showResults {
+			voters := votersByChirp[poll.ChirpID]
+			structuredPoll.VoterCount = &voters
+		}
+		for _, option := range optionsByChirp[poll.ChirpID] {
+			structuredOption := PollOption{
+				ID:   option.ID,
+				Text: option.Text,
+			}
+			if showResults {
+				structuredOption.Votes = &option.Votes
+			}
+			structuredPoll.Options = append(structuredPoll.Options, structuredOption)
+		}
+
+		chirps[i].Poll = structuredPoll
+	}
+
+	return nil
+}
+
+// helper that reports whether voting has finished, polls on unpublished chirps haven't started yet
+func pollClosed(poll database.Poll, now time.Time) bool {
+	return poll.ClosedAt.Valid || (poll.ClosesAt.Valid && !now.Before(poll.ClosesAt.Time))
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/profiles"
+	"github.com/google/uuid"
+)
+
+// how long a handle someone moved away from stays theirs
+const handleReservationPeriod = 30 * 24 * time.Hour
+
+type Profile struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Handle         string    `json:"handle"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	Location       string    `json:"location"`
+	Website        string    `json:"website"`
+	AvatarURL      string    `json:"avatar_url"`
+	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	IsProtected    bool      `json:"is_protected"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+	ChirpCount     int64     `json:"chirp_count"`
+}
+
+// handler that shows anyone a user's public profile by handle, a handle the user has since changed
+// redirects to their current one while it's still reserved
+func (cfg *apiConfig) handlerGetProfile(w http.ResponseWriter, req *http.Request) {
+	handle := profiles.NormalizeHandle(req.PathValue("handle"))
+
+	viewerID, err := cfg.optionalUserID(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view profile", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByHandle(req.Context(), handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		reservation, err := cfg.db.GetHandleReservation(req.Context(), handle)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Profile not found", err)
+			return
+		}
+		user, err = cfg.db.GetUserByID(req.Context(), reservation.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Profile not found", err)
+			return
+		}
+		http.Redirect(w, req, "/api/users/"+user.Handle, http.StatusMovedPermanently)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving profile from database", err)
+		return
+	}
+
+	// profiles on the other side of a block or of deactivated accounts look the same as ones that don't exist
+	hidden, err := cfg.blockedAuthors(req.Context(), viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving blocked users from database", err)
+		return
+	}
+	if hidden[user.ID] || user.DeactivatedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Profile not found", nil)
+		return
+	}
+
+	counts, err := cfg.db.GetUserProfileCounts(req.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving profile counts from database", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, Profile{
+		ID:             user.ID,
+		CreatedAt:      user.CreatedAt,
+		Handle:         user.Handle,
+		DisplayName:    user.DisplayName,
+		Bio:            user.Bio,
+		Location:       user.Location,
+		Website:        user.Website,
+		AvatarURL:      user.AvatarUrl,
+		IsChirpyRed:    user.IsChirpyRed.Bool,
+		IsProtected:    user.IsProtected,
+		FollowerCount:  counts.FollowerCount,
+		FollowingCount: counts.FollowingCount,
+		ChirpCount:     counts.ChirpCount,
+	})
+}
+
+// handler that replaces the logged in user's display name, bio, location, website and avatar
+func (cfg *apiConfig) handlerUpdateProfileFields(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		DisplayName string `json:"display_name"`
+		Bio         string `json:"bio"`
+		Location    string `json:"location"`
+		Website     string `json:"website"`
+		AvatarURL   string `json:"avatar_url"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to update profile", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	fields := profiles.Fields{
+		DisplayName: strings.TrimSpace(params.DisplayName),
+		Bio:         strings.TrimSpace(params.Bio),
+		Location:    strings.TrimSpace(params.Location),
+		Website:     strings.TrimSpace(params.Website),
+		AvatarURL:   strings.TrimSpace(params.AvatarURL),
+	}
+	err = profiles.ValidateFields(fields)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	user, err := cfg.db.UpdateUserProfile(req.Context(), database.UpdateUserProfileParams{
+		ID:          userID,
+		DisplayName: fields.DisplayName,
+		Bio:         fields.Bio,
+		Location:    fields.Location,
+		Website:     fields.Website,
+		AvatarUrl:   fields.AvatarURL,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating profile", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structureUser(user))
+}
+
+// handler that changes the logged in user's handle, the old one is held for them so nobody else can take it
+// straight away
+func (cfg *apiConfig) handlerChangeHandle(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Handle string `json:"handle"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to change handle", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	handle := profiles.NormalizeHandle(params.Handle)
+	err = profiles.ValidateHandle(handle)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	current, err := qtx.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+		return
+	}
+
+	// only changing the case keeps the same handle, so there's nothing to reserve
+	changed := !strings.EqualFold(current.Handle, handle)
+	if changed {
+		reservation, err := qtx.GetHandleReservation(req.Context(), handle)
+		if err == nil && reservation.UserID != userID {
+			respondWithError(w, http.StatusConflict, "That handle is already taken", nil)
+			return
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Error checking handle", err)
+			return
+		}
+	}
+
+	user, err := qtx.SetUserHandle(req.Context(), database.SetUserHandleParams{
+		ID:     userID,
+		Handle: handle,
+	})
+	if isUniqueViolation(err, constraintUsersHandle) {
+		respondWithError(w, http.StatusConflict, "That handle is already taken", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error changing handle", err)
+		return
+	}
+
+	if changed {
+		err = qtx.ReserveHandle(req.Context(), database.ReserveHandleParams{
+			Handle:        current.Handle,
+			UserID:        userID,
+			ReservedUntil: time.Now().UTC().Add(handleReservationPeriod),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error reserving old handle", err)
+			return
+		}
+
+		// going back to a handle they held before frees up its reservation
+		err = qtx.ReleaseHandleReservation(req.Context(), database.ReleaseHandleReservationParams{
+			Handle: handle,
+			UserID: userID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error changing handle", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error changing handle", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structureUser(user))
+}
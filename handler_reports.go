@@ -0,0 +1,200 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// things that can be reported
+const (
+	reportTargetChirp   = "chirp"
+	reportTargetUser    = "user"
+	reportTargetMessage = "message"
+)
+
+// statuses a report moves through, a moderator claims it before acting on it so two don't work the same one
+const (
+	reportStatusOpen      = "open"
+	reportStatusClaimed   = "claimed"
+	reportStatusResolved  = "resolved"
+	reportStatusDismissed = "dismissed"
+)
+
+const maxReportDetailsLength = 1000
+
+var reportReasons = []string{
+	"spam",
+	"harassment",
+	"hate",
+	"violence",
+	"self_harm",
+	"sexual_content",
+	"impersonation",
+	"other",
+}
+
+// Report is what the reporter sees, moderators get a ModerationReport with everything else
+type Report struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	TargetType string    `json:"target_type"`
+	TargetID   uuid.UUID `json:"target_id"`
+	Reason     string    `json:"reason"`
+	Details    string    `json:"details"`
+	Status     string    `json:"status"`
+}
+
+// handler that flags a chirp, user or direct message for the moderators, a message can only be reported by
+// someone in its conversation
+func (cfg *apiConfig) handlerCreateReport(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		TargetType string    `json:"target_type"`
+		TargetID   uuid.UUID `json:"target_id"`
+		Reason     string    `json:"reason"`
+		Details    string    `json:"details"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to report content", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if !slices.Contains(reportReasons, params.Reason) {
+		respondWithError(w, http.StatusBadRequest, "Invalid reason, must be one of "+strings.Join(reportReasons, ", "), nil)
+		return
+	}
+	details := strings.TrimSpace(params.Details)
+	if len(details) > maxReportDetailsLength {
+		respondWithError(w, http.StatusBadRequest, "Details are too long", nil)
+		return
+	}
+
+	// a copy of what's reported is kept so moderators can still see it if it's deleted
+	var targetUserID uuid.UUID
+	var content string
+	switch params.TargetType {
+	case reportTargetChirp:
+		chirp, err := cfg.db.GetChirpByID(req.Context(), params.TargetID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+			return
+		}
+		canView, err := cfg.canViewChirp(req.Context(), userID, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking chirp visibility", err)
+			return
+		}
+		if !canView {
+			respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+			return
+		}
+		targetUserID = chirp.UserID
+		content = chirp.Body
+	case reportTargetUser:
+		user, err := cfg.db.GetUserByID(req.Context(), params.TargetID)
+		if err != nil || user.DeactivatedAt.Valid {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		targetUserID = user.ID
+		content = strings.TrimSpace("@" + user.Handle + " " + user.DisplayName + "\n" + user.Bio)
+	case reportTargetMessage:
+		message, err := cfg.db.GetMessageByID(req.Context(), params.TargetID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Message not found", err)
+			return
+		}
+		members, err := cfg.db.GetConversationMembers(req.Context(), message.ConversationID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving conversation members from database", err)
+			return
+		}
+		isMember := slices.ContainsFunc(members, func(member database.ConversationMember) bool {
+			return member.UserID == userID
+		})
+		if !isMember {
+			respondWithError(w, http.StatusNotFound, "Message not found", nil)
+			return
+		}
+		targetUserID = message.SenderID
+		content = message.Body
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid target_type, must be chirp, user or message", nil)
+		return
+	}
+
+	if targetUserID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't report yourself", nil)
+		return
+	}
+
+	report, err := cfg.db.CreateReport(req.Context(), database.CreateReportParams{
+		ReporterID:   uuid.NullUUID{UUID: userID, Valid: true},
+		TargetType:   params.TargetType,
+		TargetID:     params.TargetID,
+		TargetUserID: uuid.NullUUID{UUID: targetUserID, Valid: true},
+		Reason:       params.Reason,
+		Details:      details,
+		Content:      content,
+	})
+	// reporting something again while the first report is open hands back that report
+	if errors.Is(err, sql.ErrNoRows) {
+		report, err = cfg.db.GetOpenReportByReporter(req.Context(), database.GetOpenReportByReporterParams{
+			ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+			TargetType: params.TargetType,
+			TargetID:   params.TargetID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving report", err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, structureReport(report))
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating report", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, structureReport(report))
+}
+
+func structureReport(report database.Report) Report {
+	return Report{
+		ID:         report.ID,
+		CreatedAt:  report.CreatedAt,
+		UpdatedAt:  report.UpdatedAt,
+		TargetType: report.TargetType,
+		TargetID:   report.TargetID,
+		Reason:     report.Reason,
+		Details:    report.Details,
+		Status:     report.Status,
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/keywords"
+	"github.com/Khazz0r/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+const (
+	streamHeartbeatInterval = 15 * time.Second
+	streamBufferSize        = 64
+	// most chirps sent to a reconnecting client, past that it's told to reset and reload instead
+	streamCatchUpLimit = 500
+)
+
+// filters a stream or websocket client can ask for, an empty filter lets every public chirp through.
+// hiddenAuthors and mutedKeywords come from the viewer's blocks and mutes rather than the client, and following
+// is who the viewer follows for checking chirps that aren't public
+type chirpFilter struct {
+	authorID      uuid.UUID
+	replyToID     uuid.UUID
+	hashtag       string
+	followees     map[uuid.UUID]bool
+	hiddenAuthors map[uuid.UUID]bool
+	mutedKeywords []string
+	viewerID      uuid.UUID
+	following     map[uuid.UUID]bool
+}
+
+func (f chirpFilter) matches(chirp Chirp) bool {
+	if f.authorID != uuid.Nil && chirp.UserID != f.authorID {
+		return false
+	}
+	if f.replyToID != uuid.Nil && (chirp.ReplyToID == nil || *chirp.ReplyToID != f.replyToID) {
+		return false
+	}
+	if f.hashtag != "" && !slices.Contains(chirpHashtags(chirp.Body), f.hashtag) {
+		return false
+	}
+	if f.followees != nil && !f.followees[chirp.UserID] {
+		return false
+	}
+	if f.hiddenAuthors[chirp.UserID] || keywords.Match(chirp.Body, f.mutedKeywords) {
+		return false
+	}
+	// like GET /api/chirps, chirps that aren't public only come through when following an author, a thread or
+	// the viewer's timeline
+	if f.authorID == uuid.Nil && f.replyToID == uuid.Nil && f.followees == nil && chirp.UserID != f.viewerID && !visibility.Listed(chirp.Visibility) {
+		return false
+	}
+
+	return true
+}
+
+// helper that checks the viewer is in the audience of a chirp straight off the stream, chirps caught up on from
+// the database were already checked by the query
+func (f chirpFilter) canSee(chirp streamedChirp) bool {
+	return visibility.CanView(chirp.audience(), f.viewerID, f.following[chirp.UserID])
+}
+
+// handler that streams new chirps as Server-Sent Events, clients that reconnect with Last-Event-ID are sent
+// whatever they missed first
+func (cfg *apiConfig) handlerStreamChirps(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Streaming is not supported", nil)
+		return
+	}
+
+	// streaming without a token is allowed, a logged in viewer doesn't see anyone on the other side of a block
+	userID, err := cfg.optionalUserID(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to stream chirps", err)
+		return
+	}
+
+	filter := chirpFilter{}
+	if req.URL.Query().Get("following") == "true" {
+		if userID == uuid.Nil {
+			respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", nil)
+			return
+		}
+
+		followeeIDs, err := cfg.db.GetFolloweeIDs(req.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving followed users from database", err)
+			return
+		}
+		followees := map[uuid.UUID]bool{}
+		for _, followeeID := range followeeIDs {
+			followees[followeeID] = true
+		}
+
+		// following=true is the viewer's timeline, so their mutes apply as well
+		filter, err = cfg.timelineFilter(req.Context(), userID, followees)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving muted users from database", err)
+			return
+		}
+	} else {
+		filter.hiddenAuthors, err = cfg.blockedAuthors(req.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving blocked users from database", err)
+			return
+		}
+	}
+
+	if authorID := req.URL.Query().Get("author_id"); authorID != "" {
+		parsedID, err := uuid.Parse(authorID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Unable to parse author ID", err)
+			return
+		}
+		filter.authorID = parsedID
+	}
+
+	filter.hashtag = strings.ToLower(strings.TrimPrefix(req.URL.Query().Get("hashtag"), "#"))
+
+	filter.viewerID = userID
+	filter.following, err = cfg.followingSet(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving followed users from database", err)
+		return
+	}
+
+	// subscribe before catching up so nothing posted in between is lost
+	sub := cfg.hub.Subscribe(streamBufferSize, topicChirps)
+	defer sub.Close()
+
+	// the event ID carries the chirp's timestamp so catching up works even if that chirp is gone by now. IDs
+	// from before that, which are just the chirp ID, can't be resumed from and get a reset
+	missed := []database.Chirp{}
+	reset := false
+	if lastEventID := req.Header.Get("Last-Event-ID"); lastEventID != "" {
+		resumeAt, resumeID, err := parseStreamCursor(lastEventID)
+		if err != nil {
+			if uuid.Validate(lastEventID) != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid Last-Event-ID", err)
+				return
+			}
+			reset = true
+		} else {
+			missed, err = cfg.db.GetChirpsAfter(req.Context(), database.GetChirpsAfterParams{
+				CreatedAt: resumeAt,
+				ID:        resumeID,
+				ViewerID:  userID,
+				PageSize:  streamCatchUpLimit + 1,
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Error retrieving missed chirps from database", err)
+				return
+			}
+			if len(missed) > streamCatchUpLimit {
+				missed = []database.Chirp{}
+				reset = true
+			}
+		}
+	}
+
+	missedChirps := []Chirp{}
+	for _, chirp := range missed {
+		missedChirps = append(missedChirps, structureChirp(chirp))
+	}
+	err = attachChirpDetails(req.Context(), cfg.db, userID, missedChirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving chirp details from database", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	// too much was missed to send it all, the client reloads from GET /api/chirps and streams on from here
+	if reset {
+		fmt.Fprintf(w, "id: %s\nevent: reset\ndata: {}\n\n", streamCursor(time.Now().UTC(), uuid.Nil))
+	}
+
+	sent := map[uuid.UUID]bool{}
+	for _, chirp := range missedChirps {
+		if filter.matches(chirp) {
+			writeChirpEvent(w, chirp)
+		}
+		sent[chirp.ID] = true
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case msg, ok := <-sub.Messages():
+			// the hub closes subscriptions that fall behind or when shutting down, the client reconnects
+			// with Last-Event-ID and catches up
+			if !ok {
+				return
+			}
+
+			chirp := streamedChirp{}
+			err := json.Unmarshal(msg.Data, &chirp)
+			if err != nil || sent[chirp.ID] || !filter.matches(chirp.Chirp) || !filter.canSee(chirp) {
+				continue
+			}
+			writeChirpEvent(w, chirp.Chirp)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeChirpEvent(w http.ResponseWriter, chirp Chirp) {
+	data, err := json.Marshal(chirp)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "id: %s\nevent: chirp\ndata: %s\n\n", streamCursor(chirp.CreatedAt, chirp.ID), data)
+}
+
+// helper that makes the event ID a chirp is streamed with, chirps are caught up on in (created_at, id) order
+// so both go in
+func streamCursor(createdAt time.Time, chirpID uuid.UUID) string {
+	return strconv.FormatInt(createdAt.UnixMicro(), 10) + "_" + chirpID.String()
+}
+
+func parseStreamCursor(cursor string) (time.Time, uuid.UUID, error) {
+	micros, rawID, ok := strings.Cut(cursor, "_")
+	if !ok {
+		return time.Time{}, uuid.Nil, fmt.Errorf("malformed stream cursor %q", cursor)
+	}
+
+	parsedMicros, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	chirpID, err := uuid.Parse(rawID)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+
+	return time.UnixMicro(parsedMicros).UTC(), chirpID, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/entitlements"
+	"github.com/Khazz0r/chirpy/internal/subscription"
+)
+
+type Subscription struct {
+	Plan         string            `json:"plan"`
+	Status       string            `json:"status"`
+	IsChirpyRed  bool              `json:"is_chirpy_red"`
+	RedUntil     *time.Time        `json:"red_until"`
+	UpdatedAt    *time.Time        `json:"updated_at"`
+	Entitlements entitlements.Plan `json:"entitlements"`
+}
+
+// handler that shows the logged in user where their Chirpy Red subscription stands
+func (cfg *apiConfig) handlerGetSubscription(w http.ResponseWriter, req *http.Request) {
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to view subscription", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+		return
+	}
+
+	plan := cfg.plans.ForUser(user.IsChirpyRed.Bool)
+
+	userSubscription, err := cfg.db.GetSubscriptionByUserID(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJSON(w, http.StatusOK, Subscription{
+			Plan:         plan.Name,
+			Status:       subscription.StatusNone,
+			IsChirpyRed:  user.IsChirpyRed.Bool,
+			Entitlements: plan,
+		})
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving subscription from database", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, Subscription{
+		Plan:         plan.Name,
+		Status:       userSubscription.Status,
+		IsChirpyRed:  user.IsChirpyRed.Bool,
+		RedUntil:     &userSubscription.RedUntil,
+		UpdatedAt:    &userSubscription.UpdatedAt,
+		Entitlements: plan,
+	})
+}
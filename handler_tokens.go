@@ -28,6 +28,10 @@ func (cfg *apiConfig) handlerRefreshToken(w http.ResponseWriter, req *http.Reque
 		respondWithError(w, http.StatusUnauthorized, "Refresh token is expired or doesn't exist", err)
 		return
 	}
+	if restriction := accountRestriction(user, time.Now()); restriction != "" {
+		respondWithError(w, http.StatusForbidden, restriction, nil)
+		return
+	}
 
 	accessToken, err := auth.MakeJWTToken(
 		user.ID,
@@ -1,28 +1,56 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/Khazz0r/chirpy/internal/auth"
 	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/profiles"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// User is only ever sent to the user themselves, everyone else gets a Profile without the email
 type User struct {
 	ID          uuid.UUID `json:"id"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Email       string    `json:"email"`
 	IsChirpyRed bool      `json:"is_chirpy_red"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	Location    string    `json:"location"`
+	Website     string    `json:"website"`
+	AvatarURL   string    `json:"avatar_url"`
+	Role        string    `json:"role"`
+	IsProtected bool      `json:"is_protected"`
+	// seconds new chirps last when they don't say otherwise, null keeps them forever
+	DefaultChirpExpiresIn *int32 `json:"default_chirp_expires_in"`
 }
 
-// handler that creates a user to the chirpy database with the provided email payload
+// how long the link sent to confirm a new email works for
+const emailConfirmationWindow = 24 * time.Hour
+
+// unique constraints on users that clients can run into
+const (
+	constraintUsersEmail  = "users_email_key"
+	constraintUsersHandle = "users_handle_idx"
+)
+
+// handler that creates a user to the chirpy database with the provided email payload, users that don't pick
+// a handle get a placeholder they can change later
 func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, req *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 	type response struct {
 		User
@@ -36,29 +64,90 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, req *http.Request
 		return
 	}
 
+	handle := profiles.NormalizeHandle(params.Handle)
+	if handle == "" {
+		handle = "user_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:10]
+	}
+	err = profiles.ValidateHandle(handle)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	_, err = cfg.db.GetHandleReservation(req.Context(), handle)
+	if err == nil {
+		respondWithError(w, http.StatusConflict, "That handle is already taken", nil)
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Error checking handle", err)
+		return
+	}
+
+	// bans stick to the email even after the banned account is gone
+	banned, err := cfg.db.IsEmailBanned(req.Context(), params.Email)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking email", err)
+		return
+	}
+	if banned {
+		respondWithError(w, http.StatusForbidden, "That email belongs to a banned account", nil)
+		return
+	}
+
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error hashing password", err)
 		return
 	}
 
-	user, err := cfg.db.CreateUser(req.Context(), database.CreateUserParams{
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	user, err := qtx.CreateUser(req.Context(), database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: string(hashedPassword),
+		Handle:         handle,
 	})
+	if isUniqueViolation(err, constraintUsersEmail) {
+		respondWithError(w, http.StatusConflict, "A user with that email already exists", err)
+		return
+	}
+	if isUniqueViolation(err, constraintUsersHandle) {
+		respondWithError(w, http.StatusConflict, "That handle is already taken", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Issue creating user in database", err)
 		return
 	}
 
+	// the email stays out of events since integrators don't need it
+	err = recordEvent(req.Context(), qtx, aggregateUser, user.ID, EventUserCreated, struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+	}{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording user event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving user", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusCreated, response{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed.Bool,
-		},
+		User: structureUser(user),
 	})
 }
 
@@ -70,12 +159,125 @@ func (cfg *apiConfig) handlerDeleteAllUsers(w http.ResponseWriter, req *http.Req
 		return
 	}
 
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// the audit log is left alone by the reset so there's a record of who wiped everything else
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.DeleteUsers(req.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error resetting database", err)
+		return
+	}
+
+	actorType, actorID := cfg.auditActor(req)
+	err = recordAudit(req.Context(), qtx, auditRecord{
+		ActorType: actorType,
+		ActorID:   actorID,
+		Action:    auditAdminReset,
+		Before:    map[string]int32{"fileserver_hits": cfg.fileserverHits.Load()},
+		After:     map[string]int32{"fileserver_hits": 0},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording reset in audit log", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error resetting database", err)
+		return
+	}
+
 	cfg.fileserverHits.Store(0)
-	cfg.db.DeleteUsers(req.Context())
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Hits reset to 0 and database reset to initial state"))
 }
 
+// handler that deactivates the logged in user's account once they confirm their password, everything they
+// posted is hidden straight away and the account is deleted for good after the grace period unless they log
+// back in first
+func (cfg *apiConfig) handlerDeleteOwnAccount(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	type response struct {
+		DeleteAfter time.Time `json:"delete_after"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to delete account", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+		return
+	}
+
+	err = auth.CheckPasswordHash(user.HashedPassword, params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Password is incorrect", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	user, err = qtx.DeactivateUser(req.Context(), database.DeactivateUserParams{
+		ID:          userID,
+		DeleteAfter: time.Now().UTC().Add(cfg.deletionGrace),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deactivating account", err)
+		return
+	}
+
+	// access tokens already handed out are turned away by middlewareAccountStanding from here on
+	err = qtx.RevokeUserRefreshTokens(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking refresh tokens", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deactivating account", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, response{
+		DeleteAfter: user.DeleteAfter.Time,
+	})
+}
+
 // handler that will log in the user as long as email exists in database and passwords match
 func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 	type parameters struct {
@@ -83,12 +285,6 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		Password string `json:"password"`
 	}
 
-	type response struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
-	}
-
 	decoder := json.NewDecoder(req.Body)
 	params := parameters{}
 	err := decoder.Decode(&params)
@@ -109,6 +305,32 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	cfg.loginUser(w, req, user)
+}
+
+// helper that issues a new access and refresh token pair for a user that has been authenticated
+func (cfg *apiConfig) loginUser(w http.ResponseWriter, req *http.Request, user database.User) {
+	type response struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if restriction := accountRestriction(user, time.Now()); restriction != "" {
+		respondWithError(w, http.StatusForbidden, restriction, nil)
+		return
+	}
+
+	// logging in during the grace period brings a deactivated account back
+	if user.DeactivatedAt.Valid {
+		reactivated, err := cfg.db.ReactivateUser(req.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error reactivating account", err)
+			return
+		}
+		user = reactivated
+	}
+
 	accessToken, err := auth.MakeJWTToken(user.ID, cfg.jwtSecret, time.Hour)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Could not make JWT token", err)
@@ -131,27 +353,19 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 	}
 
 	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed.Bool,
-		},
+		User:         structureUser(user),
 		Token:        accessToken,
 		RefreshToken: refreshToken,
 	})
 }
 
-// handler that will handle updating a user's email and password, as long as it is their own
-func (cfg *apiConfig) handlerUpdateProfile(w http.ResponseWriter, req *http.Request) {
-	type parameters struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
+// handler that applies a JSON Merge Patch to the logged in user, fields left out stay as they are and null
+// clears them. Changing the email or password needs the current password, and a new email only takes effect
+// once the link sent to it is confirmed
+func (cfg *apiConfig) handlerPatchUser(w http.ResponseWriter, req *http.Request) {
 	type response struct {
 		User
+		PendingEmail string `json:"pending_email,omitempty"`
 	}
 
 	// obtain token for verifying if user is authorized
@@ -164,37 +378,359 @@ func (cfg *apiConfig) handlerUpdateProfile(w http.ResponseWriter, req *http.Requ
 	// validate to ensure an access token matches
 	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Not authorized to update profile", err)
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to update user", err)
+		return
+	}
+
+	patch := map[string]json.RawMessage{}
+	err = json.NewDecoder(req.Body).Decode(&patch)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Request body must be a JSON object", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+		return
+	}
+
+	fields := profiles.Fields{
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Location:    user.Location,
+		Website:     user.Website,
+		AvatarURL:   user.AvatarUrl,
+	}
+	profileTargets := map[string]*string{
+		"display_name": &fields.DisplayName,
+		"bio":          &fields.Bio,
+		"location":     &fields.Location,
+		"website":      &fields.Website,
+		"avatar_url":   &fields.AvatarURL,
+	}
+
+	var newEmail, newPassword, currentPassword *string
+	var defaultExpiresIn *sql.NullInt32
+	profileChanged := false
+	for key, raw := range patch {
+		// the only field that isn't a string
+		if key == "default_chirp_expires_in" {
+			var seconds *int32
+			err := json.Unmarshal(raw, &seconds)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, key+" must be a number of seconds or null", err)
+				return
+			}
+			expiresIn, err := parseChirpExpiry(seconds, sql.NullInt32{})
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, err.Error(), err)
+				return
+			}
+			defaultExpiresIn = &expiresIn
+			continue
+		}
+
+		value, isNull, err := mergePatchString(raw)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, key+" must be a string or null", err)
+			return
+		}
+
+		switch key {
+		case "email", "password", "current_password":
+			if isNull {
+				respondWithError(w, http.StatusBadRequest, key+" can't be removed", nil)
+				return
+			}
+			switch key {
+			case "email":
+				newEmail = &value
+			case "password":
+				newPassword = &value
+			default:
+				currentPassword = &value
+			}
+		default:
+			target, ok := profileTargets[key]
+			if !ok {
+				respondWithError(w, http.StatusBadRequest, "Unknown field: "+key, nil)
+				return
+			}
+			*target = strings.TrimSpace(value)
+			profileChanged = true
+		}
+	}
+
+	err = profiles.ValidateFields(fields)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	if newEmail != nil {
+		address, err := mail.ParseAddress(*newEmail)
+		if err != nil || address.Address != *newEmail {
+			respondWithError(w, http.StatusBadRequest, "Email address is invalid", err)
+			return
+		}
+		// asking for the email already on the account changes nothing
+		if strings.EqualFold(*newEmail, user.Email) {
+			newEmail = nil
+		}
+	}
+	if newPassword != nil && *newPassword == "" {
+		respondWithError(w, http.StatusBadRequest, "Password can't be empty", nil)
+		return
+	}
+
+	// whoever holds the access token has to prove they know the password before taking over the account
+	if newEmail != nil || newPassword != nil {
+		if currentPassword == nil {
+			respondWithError(w, http.StatusBadRequest, "current_password is required to change email or password", nil)
+			return
+		}
+		err = auth.CheckPasswordHash(user.HashedPassword, *currentPassword)
+		if err != nil {
+			respondWithError(w, http.StatusForbidden, "Current password is incorrect", err)
+			return
+		}
+	}
+
+	if newEmail != nil {
+		_, err = cfg.db.GetUserByEmail(req.Context(), *newEmail)
+		if err == nil {
+			respondWithError(w, http.StatusConflict, "A user with that email already exists", nil)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Error checking email", err)
+			return
+		}
+
+		banned, err := cfg.db.IsEmailBanned(req.Context(), *newEmail)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking email", err)
+			return
+		}
+		if banned {
+			respondWithError(w, http.StatusForbidden, "That email belongs to a banned account", nil)
+			return
+		}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	if profileChanged {
+		user, err = qtx.UpdateUserProfile(req.Context(), database.UpdateUserProfileParams{
+			ID:          userID,
+			DisplayName: fields.DisplayName,
+			Bio:         fields.Bio,
+			Location:    fields.Location,
+			Website:     fields.Website,
+			AvatarUrl:   fields.AvatarURL,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating user in database", err)
+			return
+		}
+	}
+
+	if defaultExpiresIn != nil {
+		user, err = qtx.SetDefaultChirpExpiry(req.Context(), database.SetDefaultChirpExpiryParams{
+			ID:                    userID,
+			DefaultChirpExpiresIn: *defaultExpiresIn,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating user in database", err)
+			return
+		}
+	}
+
+	if newPassword != nil {
+		hashedPassword, err := auth.HashPassword(*newPassword)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error hashing password given", err)
+			return
+		}
+
+		user, err = qtx.SetUserPassword(req.Context(), database.SetUserPasswordParams{
+			ID:             userID,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating user in database", err)
+			return
+		}
+
+		// a new password logs out every other session once their access token runs out
+		err = qtx.RevokeUserRefreshTokens(req.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error revoking refresh tokens", err)
+			return
+		}
+	}
+
+	confirmationToken := ""
+	if newEmail != nil {
+		confirmationToken, err = auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Could not make confirmation token", err)
+			return
+		}
+
+		_, err = qtx.UpsertEmailChangeRequest(req.Context(), database.UpsertEmailChangeRequestParams{
+			UserID:    userID,
+			NewEmail:  *newEmail,
+			TokenHash: auth.HashToken(confirmationToken),
+			ExpiresAt: time.Now().UTC().Add(emailConfirmationWindow),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error saving email change", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user in database", err)
 		return
 	}
 
+	if newEmail != nil {
+		err = cfg.mailer.Send(req.Context(), *newEmail, "Confirm your new Chirpy email",
+			"Someone asked to move their Chirpy account to this address. If it was you, confirm it within 24 hours by sending this token to POST /api/users/email/confirm:\n\n"+confirmationToken)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error sending confirmation email", err)
+			return
+		}
+	}
+
+	pending, err := cfg.db.GetEmailChangeRequestByUserID(req.Context(), userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving pending email change", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		User:         structureUser(user),
+		PendingEmail: pending.NewEmail,
+	})
+}
+
+// handler that swaps in a new email once the token sent to it comes back, no access token is needed since
+// it usually arrives from the email itself
+func (cfg *apiConfig) handlerConfirmEmailChange(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
 	decoder := json.NewDecoder(req.Body)
 	params := parameters{}
-	err = decoder.Decode(&params)
+	err := decoder.Decode(&params)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error decoding request", err)
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(params.Password)
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error hashing password given", err)
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
 		return
 	}
+	defer tx.Rollback()
 
-	err = cfg.db.UpdateUser(req.Context(), database.UpdateUserParams{
-		Email:          params.Email,
-		HashedPassword: hashedPassword,
-		ID:             userID,
+	qtx := cfg.db.WithTx(tx)
+	change, err := qtx.ConsumeEmailChangeRequest(req.Context(), auth.HashToken(params.Token))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Confirmation token is invalid or has expired", err)
+		return
+	}
+
+	banned, err := qtx.IsEmailBanned(req.Context(), change.NewEmail)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking email", err)
+		return
+	}
+	if banned {
+		respondWithError(w, http.StatusForbidden, "That email belongs to a banned account", nil)
+		return
+	}
+
+	// someone else may have taken the address while the confirmation was pending
+	user, err := qtx.SetUserEmail(req.Context(), database.SetUserEmailParams{
+		ID:    change.UserID,
+		Email: change.NewEmail,
 	})
+	if isUniqueViolation(err, constraintUsersEmail) {
+		respondWithError(w, http.StatusConflict, "A user with that email already exists", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error updating user in database", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, response{
-		User{
-			Email: params.Email,
-		},
-	})
+	// a banned account moving to a new address takes the ban with it
+	if user.BannedAt.Valid {
+		err = qtx.RecordBannedEmail(req.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating user in database", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user in database", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, structureUser(user))
+}
+
+// helper for merge patch fields, null is reported separately from an empty string
+func mergePatchString(raw json.RawMessage) (string, bool, error) {
+	if string(raw) == "null" {
+		return "", true, nil
+	}
+
+	value := ""
+	err := json.Unmarshal(raw, &value)
+	return value, false, err
+}
+
+func structureUser(user database.User) User {
+	structuredUser := User{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.IsChirpyRed.Bool,
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Location:    user.Location,
+		Website:     user.Website,
+		AvatarURL:   user.AvatarUrl,
+		Role:        user.Role,
+		IsProtected: user.IsProtected,
+	}
+	if user.DefaultChirpExpiresIn.Valid {
+		structuredUser.DefaultChirpExpiresIn = &user.DefaultChirpExpiresIn.Int32
+	}
+
+	return structuredUser
+}
+
+// helper that reports whether err is Postgres rejecting a duplicate on the given unique constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
 }
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/webauthn"
+	"github.com/google/uuid"
+)
+
+// binary WebAuthn fields travel as unpadded base64url strings, same as the browser's PublicKeyCredential JSON
+type base64URL []byte
+
+func (b base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *base64URL) UnmarshalJSON(data []byte) error {
+	var encoded string
+	err := json.Unmarshal(data, &encoded)
+	if err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+
+	return nil
+}
+
+type PublicKeyCredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   base64URL `json:"id"`
+}
+
+// handler that starts registering a passkey for the logged in user
+func (cfg *apiConfig) handlerBeginPasskeyRegistration(w http.ResponseWriter, req *http.Request) {
+	type relyingParty struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	type userEntity struct {
+		ID          base64URL `json:"id"`
+		Name        string    `json:"name"`
+		DisplayName string    `json:"displayName"`
+	}
+	type credentialParameter struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	}
+	type response struct {
+		Challenge          string                          `json:"challenge"`
+		RP                 relyingParty                    `json:"rp"`
+		User               userEntity                      `json:"user"`
+		PubKeyCredParams   []credentialParameter           `json:"pubKeyCredParams"`
+		ExcludeCredentials []PublicKeyCredentialDescriptor `json:"excludeCredentials"`
+		Attestation        string                          `json:"attestation"`
+		Timeout            int                             `json:"timeout"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to register a passkey", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+		return
+	}
+
+	credentials, err := cfg.db.GetWebAuthnCredentialsByUserID(req.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving passkeys from database", err)
+		return
+	}
+
+	challenge, err := cfg.createWebAuthnChallenge(req, webauthn.CeremonyRegistration, uuid.NullUUID{UUID: user.ID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not create passkey challenge", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Challenge: challenge,
+		RP: relyingParty{
+			ID:   cfg.webauthn.ID,
+			Name: cfg.webauthn.Name,
+		},
+		User: userEntity{
+			ID:          user.ID[:],
+			Name:        user.Email,
+			DisplayName: user.Email,
+		},
+		PubKeyCredParams: []credentialParameter{
+			{Type: "public-key", Alg: webauthn.AlgES256},
+		},
+		ExcludeCredentials: credentialDescriptors(credentials),
+		Attestation:        "none",
+		Timeout:            300000,
+	})
+}
+
+// handler that verifies the authenticator's attestation and stores the new passkey
+func (cfg *apiConfig) handlerFinishPasskeyRegistration(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Response struct {
+			ClientDataJSON    base64URL `json:"clientDataJSON"`
+			AttestationObject base64URL `json:"attestationObject"`
+		} `json:"response"`
+	}
+	type response struct {
+		ID        base64URL `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to get bearer token from Authorization header", err)
+		return
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to register a passkey", err)
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	challenge, err := cfg.consumeWebAuthnChallenge(req, webauthn.CeremonyRegistration, params.Response.ClientDataJSON)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Passkey challenge is expired or doesn't exist", err)
+		return
+	}
+	if challenge.UserID.UUID != userID {
+		respondWithError(w, http.StatusForbidden, "Passkey challenge was issued to another user", nil)
+		return
+	}
+
+	credential, err := cfg.webauthn.VerifyRegistration(challenge.Challenge, params.Response.ClientDataJSON, params.Response.AttestationObject)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Unable to verify passkey", err)
+		return
+	}
+
+	storedCredential, err := cfg.db.CreateWebAuthnCredential(req.Context(), database.CreateWebAuthnCredentialParams{
+		ID:        credential.ID,
+		UserID:    userID,
+		PublicKey: credential.PublicKey,
+		SignCount: int64(credential.SignCount),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not save passkey into database", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response{
+		ID:        storedCredential.ID,
+		CreatedAt: storedCredential.CreatedAt,
+	})
+}
+
+// handler that starts a passkey login, an email narrows it down to that user's passkeys
+func (cfg *apiConfig) handlerBeginPasskeyLogin(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+	type response struct {
+		Challenge        string                          `json:"challenge"`
+		RPID             string                          `json:"rpId"`
+		AllowCredentials []PublicKeyCredentialDescriptor `json:"allowCredentials"`
+		UserVerification string                          `json:"userVerification"`
+		Timeout          int                             `json:"timeout"`
+	}
+
+	params := parameters{}
+	if req.ContentLength != 0 {
+		decoder := json.NewDecoder(req.Body)
+		err := decoder.Decode(&params)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+			return
+		}
+	}
+
+	// without an email the browser will offer any discoverable passkey for this site
+	userID := uuid.NullUUID{}
+	allowCredentials := []PublicKeyCredentialDescriptor{}
+	if params.Email != "" {
+		user, err := cfg.db.GetUserByEmail(req.Context(), params.Email)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "The email provided does not exist", err)
+			return
+		}
+
+		credentials, err := cfg.db.GetWebAuthnCredentialsByUserID(req.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving passkeys from database", err)
+			return
+		}
+		if len(credentials) == 0 {
+			respondWithError(w, http.StatusBadRequest, "No passkeys registered for this user", nil)
+			return
+		}
+
+		userID = uuid.NullUUID{UUID: user.ID, Valid: true}
+		allowCredentials = credentialDescriptors(credentials)
+	}
+
+	challenge, err := cfg.createWebAuthnChallenge(req, webauthn.CeremonyAssertion, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not create passkey challenge", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Challenge:        challenge,
+		RPID:             cfg.webauthn.ID,
+		AllowCredentials: allowCredentials,
+		UserVerification: "preferred",
+		Timeout:          300000,
+	})
+}
+
+// handler that verifies a passkey assertion and logs the user in the same way a password login does
+func (cfg *apiConfig) handlerFinishPasskeyLogin(w http.ResponseWriter, req *http.Request) {
+	type parameters struct {
+		RawID    base64URL `json:"rawId"`
+		Response struct {
+			ClientDataJSON    base64URL `json:"clientDataJSON"`
+			AuthenticatorData base64URL `json:"authenticatorData"`
+			Signature         base64URL `json:"signature"`
+		} `json:"response"`
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	challenge, err := cfg.consumeWebAuthnChallenge(req, webauthn.CeremonyAssertion, params.Response.ClientDataJSON)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Passkey challenge is expired or doesn't exist", err)
+		return
+	}
+
+	storedCredential, err := cfg.db.GetWebAuthnCredentialByID(req.Context(), params.RawID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Passkey is not registered", err)
+		return
+	}
+	if challenge.UserID.Valid && challenge.UserID.UUID != storedCredential.UserID {
+		respondWithError(w, http.StatusUnauthorized, "Passkey does not belong to this user", nil)
+		return
+	}
+
+	signCount, err := cfg.webauthn.VerifyAssertion(
+		challenge.Challenge,
+		webauthn.Credential{
+			ID:        storedCredential.ID,
+			PublicKey: storedCredential.PublicKey,
+			SignCount: uint32(storedCredential.SignCount),
+		},
+		params.Response.ClientDataJSON,
+		params.Response.AuthenticatorData,
+		params.Response.Signature,
+	)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unable to verify passkey", err)
+		return
+	}
+
+	// checked again as it's saved, another login may have used the same count since it was read
+	updated, err := cfg.db.UpdateWebAuthnSignCount(req.Context(), database.UpdateWebAuthnSignCountParams{
+		ID:        storedCredential.ID,
+		SignCount: int64(signCount),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not update passkey in database", err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Unable to verify passkey", webauthn.ErrSignCount)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(req.Context(), storedCredential.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+		return
+	}
+
+	cfg.loginUser(w, req, user)
+}
+
+// helper that makes a challenge and remembers it for the finishing half of a ceremony
+func (cfg *apiConfig) createWebAuthnChallenge(req *http.Request, ceremony string, userID uuid.NullUUID) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	err = cfg.db.CreateWebAuthnChallenge(req.Context(), database.CreateWebAuthnChallengeParams{
+		Challenge: challenge,
+		Ceremony:  ceremony,
+		UserID:    userID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// helper that pulls the challenge out of the client data and deletes it so it can only be used once
+func (cfg *apiConfig) consumeWebAuthnChallenge(req *http.Request, ceremony string, clientDataJSON []byte) (database.WebauthnChallenge, error) {
+	clientData, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		return database.WebauthnChallenge{}, err
+	}
+
+	return cfg.db.ConsumeWebAuthnChallenge(req.Context(), database.ConsumeWebAuthnChallengeParams{
+		Challenge: clientData.Challenge,
+		Ceremony:  ceremony,
+	})
+}
+
+func credentialDescriptors(credentials []database.WebauthnCredential) []PublicKeyCredentialDescriptor {
+	descriptors := []PublicKeyCredentialDescriptor{}
+	for _, credential := range credentials {
+		descriptors = append(descriptors, PublicKeyCredentialDescriptor{
+			Type: "public-key",
+			ID:   credential.ID,
+		})
+	}
+
+	return descriptors
+}
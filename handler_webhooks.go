@@ -1,59 +1,298 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/subscription"
 	"github.com/google/uuid"
 )
 
+const (
+	polkaSignatureTolerance = 5 * time.Minute
+	maxWebhookBodySize      = 1 << 20
+)
+
 type Data struct {
-	UserID string `json:"user_id"`
+	UserID   string    `json:"user_id"`
+	RedUntil time.Time `json:"red_until"`
+}
+
+type PolkaEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  `json:"data"`
+}
+
+type WebhookEvent struct {
+	ID          string          `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Source      string          `json:"source"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	Deliveries  int32           `json:"deliveries"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+	LastError   string          `json:"last_error,omitempty"`
 }
 
-// handler that upgrades user to Chirpy Red when it receives a request from the Polka webhook
+var errPolkaUserNotFound = errors.New("polka event references a user that does not exist")
+
+// handler that keeps a user's Chirpy Red subscription in sync with the events sent by the Polka webhook
 func (cfg *apiConfig) handlerUpgradeUser(w http.ResponseWriter, req *http.Request) {
-	type parameters struct {
-		Event string `json:"event"`
-		Data  `json:"data"`
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxWebhookBodySize))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error reading request body", err)
+		return
 	}
 
-	polkaAPIKey, err := auth.GetAPIKey(req.Header)
+	// the signature covers the raw body, so it has to be checked before anything is decoded
+	signature := req.Header.Get("Polka-Signature")
+	err = auth.ValidateWebhookSignature(signature, body, cfg.polkaKey, polkaSignatureTolerance)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Could not get API key from header", err)
+		respondWithError(w, http.StatusUnauthorized, "Polka signature is invalid", err)
 		return
 	}
 
-	if polkaAPIKey != cfg.polkaKey {
-		respondWithError(w, http.StatusUnauthorized, "Polka API key does not match", err)
+	params := PolkaEvent{}
+	err = json.Unmarshal(body, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
 		return
 	}
 
-	decoder := json.NewDecoder(req.Body)
-	params := parameters{}
-	err = decoder.Decode(&params)
+	// older Polka payloads don't carry an ID, identical bodies are then treated as the same event
+	eventID := params.ID
+	if eventID == "" {
+		bodyHash := sha256.Sum256(body)
+		eventID = hex.EncodeToString(bodyHash[:])
+	}
+
+	event, err := cfg.db.RecordWebhookEvent(req.Context(), database.RecordWebhookEventParams{
+		ID:        eventID,
+		Source:    "polka",
+		Event:     params.Event,
+		Payload:   body,
+		Signature: signature,
+	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error decoding parameters", err)
+		respondWithError(w, http.StatusInternalServerError, "Error recording webhook event", err)
 		return
 	}
 
-	if params.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent)
+	cfg.processWebhookEvent(w, req, event)
+}
+
+// handler that lists the most recent raw webhook events for auditing, only to be used in dev environment
+func (cfg *apiConfig) handlerGetWebhookEvents(w http.ResponseWriter, req *http.Request) {
+	if cfg.platform != "dev" {
+		respondWithError(w, http.StatusForbidden, "Webhook events are only viewable in dev environment", nil)
 		return
 	}
 
-	userID, err := uuid.Parse(params.Data.UserID)
+	limit := 50
+	if limitStr := req.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > 500 {
+			respondWithError(w, http.StatusBadRequest, "Limit must be between 1 and 500", err)
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := cfg.db.GetWebhookEvents(req.Context(), int32(limit))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error parsing user ID", err)
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving webhook events from database", err)
 		return
 	}
 
-	_, err = cfg.db.UpgradeUser(req.Context(), userID)
+	structuredEvents := []WebhookEvent{}
+	for _, event := range events {
+		structuredEvents = append(structuredEvents, structureWebhookEvent(event))
+	}
+
+	respondWithJSON(w, http.StatusOK, structuredEvents)
+}
+
+// handler that runs a stored webhook event that hasn't been processed yet, like one that failed, only to be used
+// in dev environment. Events that were already applied are left alone so replaying can't apply one twice
+func (cfg *apiConfig) handlerReplayWebhookEvent(w http.ResponseWriter, req *http.Request) {
+	if cfg.platform != "dev" {
+		respondWithError(w, http.StatusForbidden, "Webhook replay is only allowed in dev environment", nil)
+		return
+	}
+
+	event, err := cfg.db.GetWebhookEventByID(req.Context(), req.PathValue("eventID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Webhook event not found", err)
+		return
+	}
+
+	cfg.processWebhookEvent(w, req, event)
+}
+
+// helper that applies a recorded Polka event and marks it processed in the same transaction. Marking it is
+// what claims the event, so when the same event arrives twice at once only one of them gets to apply it
+func (cfg *apiConfig) processWebhookEvent(w http.ResponseWriter, req *http.Request, event database.WebhookEvent) {
+	params := PolkaEvent{}
+	err := json.Unmarshal(event.Payload, &params)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+		respondWithError(w, http.StatusBadRequest, "Error decoding stored webhook event", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	// Polka retries until it gets a 2XX, so an event that's already been applied is just acknowledged
+	_, err = qtx.ClaimWebhookEvent(req.Context(), event.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error claiming webhook event", err)
+		return
+	}
+
+	updated, err := applyPolkaEvent(req.Context(), qtx, event.ID, event.CreatedAt.UTC(), params)
+	if err == nil && updated.UserID != uuid.Nil {
+		err = recordEvent(req.Context(), qtx, aggregateSubscription, updated.UserID, EventSubscriptionUpdated, struct {
+			UserID   uuid.UUID `json:"user_id"`
+			Status   string    `json:"status"`
+			RedUntil time.Time `json:"red_until"`
+		}{
+			UserID:   updated.UserID,
+			Status:   updated.Status,
+			RedUntil: updated.RedUntil,
+		})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		// the claim holds a lock on the event until the transaction ends, so it has to be rolled back before
+		// the failure can be recorded on another connection
+		tx.Rollback()
+
+		failErr := cfg.db.MarkWebhookEventFailed(req.Context(), database.MarkWebhookEventFailedParams{
+			ID:        event.ID,
+			LastError: sql.NullString{String: err.Error(), Valid: true},
+		})
+		if failErr != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording webhook failure", failErr)
+			return
+		}
+
+		if errors.Is(err, errPolkaUserNotFound) {
+			respondWithError(w, http.StatusNotFound, "Unable to find user by ID", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error processing webhook event", err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// helper that updates a user's subscription from a Polka event, periods are counted from when the event was first
+// received so one that's retried after failing doesn't get a later end date than it should
+func applyPolkaEvent(ctx context.Context, db *database.Queries, eventID string, receivedAt time.Time, params PolkaEvent) (database.Subscription, error) {
+	// events we don't care about are still stored and acknowledged
+	if !subscription.IsKnownEvent(params.Event) {
+		return database.Subscription{}, nil
+	}
+
+	userID, err := uuid.Parse(params.Data.UserID)
+	if err != nil {
+		return database.Subscription{}, err
+	}
+
+	// locking the user lines up events for the same subscription, it may not have a row of its own to lock yet
+	_, err = db.GetUserByIDForUpdate(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Subscription{}, errPolkaUserNotFound
+	}
+	if err != nil {
+		return database.Subscription{}, err
+	}
+
+	current := subscription.State{Status: subscription.StatusNone}
+	existing, err := db.GetSubscriptionByUserID(ctx, userID)
+	if err == nil {
+		current = subscription.State{Status: existing.Status, RedUntil: existing.RedUntil}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return database.Subscription{}, err
+	}
+
+	next, err := subscription.Apply(current, params.Event, receivedAt, params.Data.RedUntil.UTC())
+	if err != nil {
+		return database.Subscription{}, err
+	}
+
+	updated, err := db.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+		UserID:      userID,
+		Status:      next.Status,
+		RedUntil:    next.RedUntil,
+		LastEventID: eventID,
+	})
+	if err != nil {
+		return database.Subscription{}, err
+	}
+
+	err = db.SetUserChirpyRed(ctx, database.SetUserChirpyRedParams{
+		ID:          userID,
+		IsChirpyRed: sql.NullBool{Bool: next.IsRed(time.Now().UTC()), Valid: true},
+	})
+	if err != nil {
+		return database.Subscription{}, err
+	}
+
+	err = recordAudit(ctx, db, auditRecord{
+		ActorType:  auditActorWebhook,
+		ActorID:    "polka",
+		Action:     auditSubscriptionUpdated,
+		TargetType: auditTargetUser,
+		TargetID:   userID.String(),
+		Before:     map[string]any{"status": current.Status, "red_until": current.RedUntil, "event_id": existing.LastEventID},
+		After:      map[string]any{"status": next.Status, "red_until": next.RedUntil, "event_id": eventID},
+	})
+	if err != nil {
+		return database.Subscription{}, err
+	}
+
+	return updated, nil
+}
+
+func structureWebhookEvent(event database.WebhookEvent) WebhookEvent {
+	structuredEvent := WebhookEvent{
+		ID:         event.ID,
+		CreatedAt:  event.CreatedAt,
+		UpdatedAt:  event.UpdatedAt,
+		Source:     event.Source,
+		Event:      event.Event,
+		Payload:    event.Payload,
+		Deliveries: event.Deliveries,
+		LastError:  event.LastError.String,
+	}
+	if event.ProcessedAt.Valid {
+		structuredEvent.ProcessedAt = &event.ProcessedAt.Time
+	}
+
+	return structuredEvent
+}
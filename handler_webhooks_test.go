@@ -0,0 +1,172 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func init() {
+	sql.Register("webhook-test", webhookTestDriver)
+}
+
+// webhookTestDriver stands in for Postgres while a Polka event is processed. Claiming the event locks it until
+// the transaction ends, and recording a failure from another connection while it's locked errors out rather than
+// hanging like Postgres would
+var webhookTestDriver = &webhookTestDB{}
+
+var queryNamePattern = regexp.MustCompile(`-- name: (\w+)`)
+
+type webhookTestDB struct {
+	mu      sync.Mutex
+	event   database.WebhookEvent
+	locked  bool
+	queries []string
+}
+
+func (db *webhookTestDB) Open(name string) (driver.Conn, error) { return &webhookTestConn{db: db}, nil }
+
+func (db *webhookTestDB) record(query string) string {
+	name := ""
+	if match := queryNamePattern.FindStringSubmatch(query); match != nil {
+		name = match[1]
+	}
+	db.queries = append(db.queries, name)
+	return name
+}
+
+type webhookTestConn struct {
+	db   *webhookTestDB
+	inTx bool
+}
+
+func (c *webhookTestConn) Prepare(query string) (driver.Stmt, error) {
+	return &webhookTestStmt{conn: c, query: query}, nil
+}
+func (c *webhookTestConn) Close() error { return nil }
+func (c *webhookTestConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	return c, nil
+}
+
+// ending the transaction releases the lock taken by the claim
+func (c *webhookTestConn) Commit() error   { return c.end() }
+func (c *webhookTestConn) Rollback() error { return c.end() }
+func (c *webhookTestConn) end() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	if c.inTx {
+		c.db.locked = false
+	}
+	c.inTx = false
+	return nil
+}
+
+type webhookTestStmt struct {
+	conn  *webhookTestConn
+	query string
+}
+
+func (s *webhookTestStmt) Close() error  { return nil }
+func (s *webhookTestStmt) NumInput() int { return -1 }
+
+func (s *webhookTestStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.record(s.query) == "MarkWebhookEventFailed" && db.locked && !s.conn.inTx {
+		return nil, errors.New("webhook event is locked by an open transaction")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *webhookTestStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// every other query finds nothing, which is what an event for an unknown user runs into
+	if db.record(s.query) != "ClaimWebhookEvent" {
+		return &webhookTestRows{}, nil
+	}
+	db.locked = true
+	return &webhookTestRows{event: &db.event}, nil
+}
+
+type webhookTestRows struct {
+	event *database.WebhookEvent
+}
+
+func (r *webhookTestRows) Columns() []string {
+	return make([]string, reflect.TypeOf(database.WebhookEvent{}).NumField())
+}
+
+func (r *webhookTestRows) Close() error { return nil }
+
+func (r *webhookTestRows) Next(dest []driver.Value) error {
+	if r.event == nil {
+		return io.EOF
+	}
+
+	fields := reflect.ValueOf(*r.event)
+	r.event = nil
+	for i := range dest {
+		field := fields.Field(i).Interface()
+		if valuer, ok := field.(driver.Valuer); ok {
+			value, err := valuer.Value()
+			if err != nil {
+				return err
+			}
+			dest[i] = value
+			continue
+		}
+		dest[i] = field
+	}
+	return nil
+}
+
+func TestProcessWebhookEventRecordsFailure(t *testing.T) {
+	conn, err := sql.Open("webhook-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	cfg := &apiConfig{db: database.New(conn), dbConn: conn}
+
+	event := database.WebhookEvent{
+		ID:        "evt_unknown_user",
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Source:    "polka",
+		Event:     "user.upgraded",
+		Payload:   []byte(`{"id":"evt_unknown_user","event":"user.upgraded","data":{"user_id":"` + uuid.NewString() + `"}}`),
+	}
+	webhookTestDriver.event = event
+	webhookTestDriver.queries = nil
+
+	req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", nil)
+	rec := httptest.NewRecorder()
+	cfg.processWebhookEvent(rec, req, event)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown user, got %d: %s", rec.Code, rec.Body.String())
+	}
+	webhookTestDriver.mu.Lock()
+	defer webhookTestDriver.mu.Unlock()
+	want := []string{"ClaimWebhookEvent", "GetUserByIDForUpdate", "MarkWebhookEventFailed"}
+	if !reflect.DeepEqual(webhookTestDriver.queries, want) {
+		t.Errorf("Expected queries %v, got %v", want, webhookTestDriver.queries)
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/pubsub"
+	"github.com/Khazz0r/chirpy/internal/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	wsSendBufferSize    = 64
+	wsMaxMessageSize    = 4096
+	wsPingInterval      = 30 * time.Second
+	wsPongWait          = 60 * time.Second
+	wsCloseWait         = time.Second
+	wsReauthWarning     = time.Minute
+	wsCloseTokenExpired = 4001
+	// browsers can't set headers on websockets, so they offer this subprotocol along with "bearer." and the
+	// access token instead. Unlike a query the token stays out of URLs and request logs
+	wsProtocol            = "chirpy"
+	wsTokenProtocolPrefix = "bearer."
+)
+
+// channels a websocket client can subscribe to
+const (
+	wsChannelTimeline      = "timeline"
+	wsChannelThreadPrefix  = "thread:"
+	wsChannelNotifications = "notifications"
+)
+
+type wsClientMessage struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Channel string `json:"channel"`
+	Token   string `json:"token"`
+}
+
+type wsServerMessage struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Channel   string          `json:"channel,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+}
+
+type wsSession struct {
+	cfg       *apiConfig
+	ctx       context.Context
+	conn      *websocket.Conn
+	sub       *pubsub.Subscription
+	readErr   chan error
+	userID    uuid.UUID
+	expiresAt time.Time
+	// subscribed channels and the filter chirps have to pass to be sent on them
+	channels map[string]chirpFilter
+}
+
+// handler that upgrades to a websocket for real-time timelines, threads and notifications, the access token can
+// also be given as a subprotocol since browsers can't set headers on websockets
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, req *http.Request) {
+	protocol := ""
+	protocolToken := ""
+	for _, offered := range websocket.Subprotocols(req) {
+		if offered == wsProtocol {
+			protocol = wsProtocol
+		} else if strings.HasPrefix(offered, wsTokenProtocolPrefix) {
+			protocolToken = strings.TrimPrefix(offered, wsTokenProtocolPrefix)
+		}
+	}
+
+	// obtain token for verifying if user is authorized
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		token = protocolToken
+	}
+
+	// validate to ensure an access token matches
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to open a websocket", err)
+		return
+	}
+	expiresAt, err := auth.GetJWTExpiry(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Not authorized to open a websocket", err)
+		return
+	}
+
+	// counted before the upgrade hijacks the connection, until then server shutdown still waits for this
+	// request so the count can't be missed by the Wait in main
+	cfg.websockets.Add(1)
+	defer cfg.websockets.Done()
+
+	conn, err := websocket.Upgrade(w, req, protocol)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Expected a websocket upgrade request", err)
+		return
+	}
+	defer conn.Close()
+
+	session := &wsSession{
+		cfg:       cfg,
+		ctx:       req.Context(),
+		conn:      conn,
+		sub:       cfg.hub.Subscribe(wsSendBufferSize),
+		readErr:   make(chan error, 1),
+		userID:    userID,
+		expiresAt: expiresAt,
+		channels:  map[string]chirpFilter{},
+	}
+	defer session.sub.Close()
+
+	session.run()
+}
+
+func (s *wsSession) run() {
+	s.conn.SetReadLimit(wsMaxMessageSize)
+	s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	s.conn.SetPongHandler(func() {
+		s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// reads happen on their own goroutine, it blocks until the loop below takes each message which keeps a
+	// chatty client from queueing up unbounded work
+	incoming := make(chan wsClientMessage)
+	go func() {
+		for {
+			opcode, data, err := s.conn.ReadMessage()
+			if err != nil {
+				s.readErr <- err
+				return
+			}
+			s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+			msg := wsClientMessage{}
+			if opcode != websocket.TextMessage || json.Unmarshal(data, &msg) != nil {
+				msg = wsClientMessage{Type: "invalid"}
+			}
+
+			select {
+			case incoming <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	reauthWarning := time.NewTimer(time.Until(s.expiresAt.Add(-wsReauthWarning)))
+	defer reauthWarning.Stop()
+	expiry := time.NewTimer(time.Until(s.expiresAt))
+	defer expiry.Stop()
+
+	if !s.send(wsServerMessage{Type: "ready", ExpiresAt: &s.expiresAt}) {
+		return
+	}
+
+	for {
+		select {
+		case <-s.readErr:
+			return
+		case msg := <-incoming:
+			if !s.handleClientMessage(msg, reauthWarning, expiry) {
+				return
+			}
+		case msg, ok := <-s.sub.Messages():
+			if !ok {
+				// the hub drops subscribers that fall behind and closes everyone when the server shuts down
+				if s.sub.Lagged() {
+					s.close(websocket.CloseTryAgainLater, "connection fell behind")
+				} else {
+					s.close(websocket.CloseGoingAway, "server shutting down")
+				}
+				return
+			}
+			if !s.deliver(msg) {
+				return
+			}
+		case <-ping.C:
+			err := s.conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				return
+			}
+		case <-reauthWarning.C:
+			if !s.send(wsServerMessage{Type: "reauth_required", ExpiresAt: &s.expiresAt}) {
+				return
+			}
+		case <-expiry.C:
+			s.close(wsCloseTokenExpired, "access token expired")
+			return
+		}
+	}
+}
+
+func (s *wsSession) handleClientMessage(msg wsClientMessage, reauthWarning, expiry *time.Timer) bool {
+	switch msg.Type {
+	case "auth":
+		userID, err := auth.ValidateJWT(msg.Token, s.cfg.jwtSecret)
+		if err != nil || userID != s.userID {
+			return s.sendError(msg.ID, "Access token is invalid or belongs to another user")
+		}
+		expiresAt, err := auth.GetJWTExpiry(msg.Token, s.cfg.jwtSecret)
+		if err != nil {
+			return s.sendError(msg.ID, "Access token is invalid or belongs to another user")
+		}
+
+		s.expiresAt = expiresAt
+		reauthWarning.Reset(time.Until(expiresAt.Add(-wsReauthWarning)))
+		expiry.Reset(time.Until(expiresAt))
+
+		return s.send(wsServerMessage{Type: "ack", ID: msg.ID, ExpiresAt: &s.expiresAt})
+	case "subscribe":
+		filter, errMessage := s.channelFilter(msg.Channel)
+		if errMessage != "" {
+			return s.sendError(msg.ID, errMessage)
+		}
+
+		s.channels[msg.Channel] = filter
+		if msg.Channel == wsChannelNotifications {
+			s.sub.AddTopic(notificationsTopic(s.userID))
+		} else {
+			s.sub.AddTopic(topicChirps)
+		}
+
+		return s.send(wsServerMessage{Type: "ack", ID: msg.ID, Channel: msg.Channel})
+	case "unsubscribe":
+		delete(s.channels, msg.Channel)
+		if msg.Channel == wsChannelNotifications {
+			s.sub.RemoveTopic(notificationsTopic(s.userID))
+		} else if !s.hasChirpChannel() {
+			s.sub.RemoveTopic(topicChirps)
+		}
+
+		return s.send(wsServerMessage{Type: "ack", ID: msg.ID, Channel: msg.Channel})
+	case "ping":
+		return s.send(wsServerMessage{Type: "pong", ID: msg.ID})
+	default:
+		return s.sendError(msg.ID, "Unknown message type")
+	}
+}
+
+// helper that works out what a channel should receive, the timeline is loaded when subscribing so
+// follows made afterwards need a fresh subscribe
+func (s *wsSession) channelFilter(channel string) (chirpFilter, string) {
+	if channel == wsChannelNotifications {
+		return chirpFilter{}, ""
+	}
+
+	// followers-only chirps are checked against who the viewer followed when subscribing as well
+	following, err := s.cfg.followingSet(s.ctx, s.userID)
+	if err != nil {
+		return chirpFilter{}, "Error retrieving followed users"
+	}
+
+	switch {
+	case channel == wsChannelTimeline:
+		followees := map[uuid.UUID]bool{s.userID: true}
+		for followeeID := range following {
+			followees[followeeID] = true
+		}
+		filter, err := s.cfg.timelineFilter(s.ctx, s.userID, followees)
+		if err != nil {
+			return chirpFilter{}, "Error retrieving muted users"
+		}
+		filter.viewerID = s.userID
+		filter.following = following
+		return filter, ""
+	case strings.HasPrefix(channel, wsChannelThreadPrefix):
+		chirpID, err := uuid.Parse(strings.TrimPrefix(channel, wsChannelThreadPrefix))
+		if err != nil {
+			return chirpFilter{}, "Invalid Chirp ID format"
+		}
+		chirp, err := s.cfg.db.GetChirpByID(s.ctx, chirpID)
+		if err != nil {
+			return chirpFilter{}, "Chirp not found"
+		}
+		hidden, err := s.cfg.blockedAuthors(s.ctx, s.userID)
+		if err != nil {
+			return chirpFilter{}, "Error retrieving blocked users"
+		}
+		if hidden[chirp.UserID] {
+			return chirpFilter{}, "Chirp not found"
+		}
+		canView, err := s.cfg.canViewChirp(s.ctx, s.userID, chirp)
+		if err != nil {
+			return chirpFilter{}, "Error checking chirp visibility"
+		}
+		if !canView {
+			return chirpFilter{}, "Chirp not found"
+		}
+		return chirpFilter{replyToID: chirpID, hiddenAuthors: hidden, viewerID: s.userID, following: following}, ""
+	default:
+		return chirpFilter{}, "Unknown channel"
+	}
+}
+
+func (s *wsSession) hasChirpChannel() bool {
+	for channel := range s.channels {
+		if channel != wsChannelNotifications {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *wsSession) deliver(msg pubsub.Message) bool {
+	if msg.Topic != topicChirps {
+		return s.send(wsServerMessage{Type: "event", Channel: wsChannelNotifications, ID: msg.ID, Data: msg.Data})
+	}
+
+	chirp := streamedChirp{}
+	err := json.Unmarshal(msg.Data, &chirp)
+	if err != nil {
+		return true
+	}
+	// who can see the chirp is only for filtering, clients just get the chirp
+	data, err := json.Marshal(chirp.Chirp)
+	if err != nil {
+		return true
+	}
+
+	for channel, filter := range s.channels {
+		if channel == wsChannelNotifications || !filter.matches(chirp.Chirp) || !filter.canSee(chirp) {
+			continue
+		}
+		if !s.send(wsServerMessage{Type: "event", Channel: channel, ID: msg.ID, Data: data}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *wsSession) send(msg wsServerMessage) bool {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return true
+	}
+
+	return s.conn.WriteMessage(websocket.TextMessage, data) == nil
+}
+
+func (s *wsSession) sendError(id, message string) bool {
+	return s.send(wsServerMessage{Type: "error", ID: id, Message: message})
+}
+
+// helper that starts the closing handshake and gives the client a moment to answer before the connection drops
+func (s *wsSession) close(code int, reason string) {
+	err := s.conn.WriteClose(code, reason)
+	if err != nil {
+		return
+	}
+
+	select {
+	case <-s.readErr:
+	case <-time.After(wsCloseWait):
+	}
+}
@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GenesisHash is what the first entry in the log chains from
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+var ErrBrokenChain = errors.New("audit log hash chain is broken")
+
+// Entry is one line of the audit log, every field is covered by its hash
+type Entry struct {
+	CreatedAt  time.Time       `json:"created_at"`
+	ActorType  string          `json:"actor_type"`
+	ActorID    string          `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+}
+
+// Record is an entry as it was stored, along with the hashes that chain it to the one before
+type Record struct {
+	Entry
+	PrevHash string
+	Hash     string
+}
+
+// Hash chains an entry onto the hash of the entry before it. before and after are hashed in a canonical
+// form so it doesn't matter how the database hands the JSON back
+func Hash(prevHash string, entry Entry) (string, error) {
+	var err error
+	entry.CreatedAt = entry.CreatedAt.UTC()
+	entry.Before, err = canonicalJSON(entry.Before)
+	if err != nil {
+		return "", err
+	}
+	entry.After, err = canonicalJSON(entry.After)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(prevHash+"\n"), encoded...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify walks records oldest first starting from the hash before the first of them, it returns the hash to
+// carry on from with the next batch. When the chain is broken it also returns the index of the first bad record
+func Verify(prevHash string, records []Record) (string, int, error) {
+	for i, record := range records {
+		if record.PrevHash != prevHash {
+			return prevHash, i, fmt.Errorf("%w: entry doesn't follow the one before it", ErrBrokenChain)
+		}
+
+		hash, err := Hash(prevHash, record.Entry)
+		if err != nil {
+			return prevHash, i, err
+		}
+		if hash != record.Hash {
+			return prevHash, i, fmt.Errorf("%w: entry has been changed", ErrBrokenChain)
+		}
+
+		prevHash = hash
+	}
+
+	return prevHash, -1, nil
+}
+
+// Diff keeps only the top level fields that differ between before and after, either can be nil for things
+// that were created or deleted
+func Diff(before, after any) (json.RawMessage, json.RawMessage, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changedBefore := map[string]json.RawMessage{}
+	changedAfter := map[string]json.RawMessage{}
+	for key, value := range beforeFields {
+		if other, ok := afterFields[key]; !ok || !bytes.Equal(value, other) {
+			changedBefore[key] = value
+		}
+	}
+	for key, value := range afterFields {
+		if other, ok := beforeFields[key]; !ok || !bytes.Equal(value, other) {
+			changedAfter[key] = value
+		}
+	}
+
+	beforeJSON, err := json.Marshal(changedBefore)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterJSON, err := json.Marshal(changedAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return beforeJSON, afterJSON, nil
+}
+
+// helper that breaks a value down into its top level JSON fields, each in canonical form so they compare equal
+func fields(value any) (map[string]json.RawMessage, error) {
+	result := map[string]json.RawMessage{}
+	if value == nil {
+		return result, nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if string(encoded) == "null" {
+		return result, nil
+	}
+
+	err = json.Unmarshal(encoded, &result)
+	if err != nil {
+		return nil, fmt.Errorf("audited values must be JSON objects: %w", err)
+	}
+	for key, field := range result {
+		result[key], err = canonicalJSON(field)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// helper that re-encodes JSON with sorted keys and no extra whitespace, numbers are kept exactly as written
+func canonicalJSON(raw json.RawMessage) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return json.RawMessage("{}"), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
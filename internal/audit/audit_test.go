@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	type standing struct {
+		Role      string `json:"role"`
+		Handle    string `json:"handle"`
+		BanReason string `json:"ban_reason"`
+	}
+
+	before, after, err := Diff(
+		standing{Role: "user", Handle: "chirper"},
+		standing{Role: "moderator", Handle: "chirper"},
+	)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if string(before) != `{"role":"user"}` || string(after) != `{"role":"moderator"}` {
+		t.Errorf("Diff() = %s, %s", before, after)
+	}
+
+	before, after, err = Diff(nil, standing{Role: "user"})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if string(before) != `{}` || string(after) != `{"ban_reason":"","handle":"","role":"user"}` {
+		t.Errorf("Diff() of a created value = %s, %s", before, after)
+	}
+
+	_, _, err = Diff("not an object", nil)
+	if err == nil {
+		t.Errorf("Expected an error for a value that isn't a JSON object")
+	}
+}
+
+func TestHashIgnoresJSONFormatting(t *testing.T) {
+	entry := Entry{
+		CreatedAt: time.Date(2025, time.May, 1, 12, 0, 0, 123456000, time.UTC),
+		ActorType: "user",
+		ActorID:   "3311741c-680c-4546-99f3-fc9efac2036c",
+		Action:    "user.role_changed",
+		Before:    json.RawMessage(`{"role":"user","count":10}`),
+		After:     json.RawMessage(`{"role":"admin"}`),
+	}
+	hash, err := Hash(GenesisHash, entry)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	// Postgres hands JSONB back with its own spacing and key order
+	entry.Before = json.RawMessage(`{"count": 10, "role": "user"}`)
+	entry.CreatedAt = entry.CreatedAt.In(time.FixedZone("", 0))
+	again, err := Hash(GenesisHash, entry)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if hash != again {
+		t.Errorf("Expected the same hash for equivalent JSON, got %s and %s", hash, again)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	records := []Record{}
+	prevHash := GenesisHash
+	for _, action := range []string{"admin.reset", "user.banned", "user.unbanned"} {
+		entry := Entry{
+			CreatedAt: time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC),
+			ActorType: "user",
+			Action:    action,
+			Before:    json.RawMessage(`{}`),
+			After:     json.RawMessage(`{}`),
+		}
+		hash, err := Hash(prevHash, entry)
+		if err != nil {
+			t.Fatalf("Hash() error = %v", err)
+		}
+		records = append(records, Record{Entry: entry, PrevHash: prevHash, Hash: hash})
+		prevHash = hash
+	}
+
+	last, bad, err := Verify(GenesisHash, records)
+	if err != nil || bad != -1 || last != prevHash {
+		t.Fatalf("Verify() of an intact chain = %s, %d, %v", last, bad, err)
+	}
+
+	// verifying in batches carries on from the last hash
+	last, _, err = Verify(GenesisHash, records[:1])
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	_, bad, err = Verify(last, records[1:])
+	if err != nil || bad != -1 {
+		t.Errorf("Verify() of the second batch = %d, %v", bad, err)
+	}
+
+	tampered := append([]Record{}, records...)
+	tampered[1].Action = "user.unbanned"
+	_, bad, err = Verify(GenesisHash, tampered)
+	if !errors.Is(err, ErrBrokenChain) || bad != 1 {
+		t.Errorf("Verify() of an edited entry = %d, %v", bad, err)
+	}
+
+	removed := []Record{records[0], records[2]}
+	_, bad, err = Verify(GenesisHash, removed)
+	if !errors.Is(err, ErrBrokenChain) || bad != 1 {
+		t.Errorf("Verify() with an entry removed = %d, %v", bad, err)
+	}
+}
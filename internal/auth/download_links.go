@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidDownloadSignature = errors.New("download link signature does not match")
+	ErrExpiredDownloadLink      = errors.New("download link has expired")
+)
+
+// SignDownloadLink signs "<resource ID>.<unix expiry>" so a link can be handed out without a token and
+// stops working once it expires
+func SignDownloadLink(secret, resourceID string, expires time.Time) string {
+	return computeDownloadMAC(secret, resourceID, expires.Unix())
+}
+
+func ValidateDownloadLink(secret, resourceID, expires, signature string, now time.Time) error {
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidDownloadSignature
+	}
+
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidDownloadSignature
+	}
+	expected, err := hex.DecodeString(computeDownloadMAC(secret, resourceID, expiresUnix))
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(decoded, expected) {
+		return ErrInvalidDownloadSignature
+	}
+
+	if now.After(time.Unix(expiresUnix, 0)) {
+		return ErrExpiredDownloadLink
+	}
+
+	return nil
+}
+
+func computeDownloadMAC(secret, resourceID string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(resourceID))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestValidateDownloadLink(t *testing.T) {
+	secret := "jwt-secret"
+	now := time.Now()
+	expires := now.Add(time.Hour)
+	expiresStr := strconv.FormatInt(expires.Unix(), 10)
+	signature := SignDownloadLink(secret, "export-1", expires)
+
+	tests := []struct {
+		name        string
+		resourceID  string
+		expires     string
+		signature   string
+		now         time.Time
+		expectedErr error
+	}{
+		{
+			name:       "Valid link",
+			resourceID: "export-1",
+			expires:    expiresStr,
+			signature:  signature,
+			now:        now,
+		},
+		{
+			name:        "Link for another resource",
+			resourceID:  "export-2",
+			expires:     expiresStr,
+			signature:   signature,
+			now:         now,
+			expectedErr: ErrInvalidDownloadSignature,
+		},
+		{
+			name:        "Expiry pushed back",
+			resourceID:  "export-1",
+			expires:     strconv.FormatInt(expires.Add(time.Hour).Unix(), 10),
+			signature:   signature,
+			now:         now,
+			expectedErr: ErrInvalidDownloadSignature,
+		},
+		{
+			name:        "Expired link",
+			resourceID:  "export-1",
+			expires:     expiresStr,
+			signature:   signature,
+			now:         now.Add(2 * time.Hour),
+			expectedErr: ErrExpiredDownloadLink,
+		},
+		{
+			name:        "Malformed signature",
+			resourceID:  "export-1",
+			expires:     expiresStr,
+			signature:   "not-hex",
+			now:         now,
+			expectedErr: ErrInvalidDownloadSignature,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDownloadLink(secret, tc.resourceID, tc.expires, tc.signature, tc.now)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("ValidateDownloadLink() error = %v, want %v", err, tc.expectedErr)
+			}
+		})
+	}
+}
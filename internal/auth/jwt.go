@@ -61,6 +61,24 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	return userID, nil
 }
 
+// GetJWTExpiry returns when a valid token stops being accepted, for connections that outlive a single request
+func GetJWTExpiry(tokenString, tokenSecret string) (time.Time, error) {
+	claims := jwt.RegisteredClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(tokenSecret), nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if claims.ExpiresAt == nil {
+		return time.Time{}, errors.New("token has no expiry")
+	}
+
+	return claims.ExpiresAt.Time, nil
+}
+
 func GetBearerToken(headers http.Header) (string, error) {
 	authParts := strings.Fields(headers.Get("Authorization"))
 	if len(authParts) != 2 || strings.ToLower(authParts[0]) != "bearer" {
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 )
 
@@ -16,3 +17,9 @@ func MakeRefreshToken() (string, error) {
 
 	return encodedData, nil
 }
+
+// HashToken is for single use tokens that are stored, only the hash is kept so a database leak can't be replayed
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
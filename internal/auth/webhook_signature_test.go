@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateWebhookSignature(t *testing.T) {
+	secret := "polka-secret"
+	body := []byte(`{"id":"evt_1","event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+
+	tests := []struct {
+		name        string
+		header      string
+		body        []byte
+		expectedErr error
+		expectError bool
+	}{
+		{
+			name:   "Valid signature",
+			header: MakeWebhookSignature(secret, time.Now(), body),
+			body:   body,
+		},
+		{
+			name:   "Rotated secret alongside the current one",
+			header: MakeWebhookSignature("old-secret", time.Now(), body) + ",v1=" + computeWebhookMAC(secret, time.Now().Unix(), body),
+			body:   body,
+		},
+		{
+			name:        "Wrong secret",
+			header:      MakeWebhookSignature("wrong", time.Now(), body),
+			body:        body,
+			expectedErr: ErrInvalidSignature,
+			expectError: true,
+		},
+		{
+			name:        "Tampered body",
+			header:      MakeWebhookSignature(secret, time.Now(), body),
+			body:        []byte(`{"id":"evt_1","event":"user.upgraded","data":{"user_id":"someone-else"}}`),
+			expectedErr: ErrInvalidSignature,
+			expectError: true,
+		},
+		{
+			name:        "Stale timestamp",
+			header:      MakeWebhookSignature(secret, time.Now().Add(-10*time.Minute), body),
+			body:        body,
+			expectedErr: ErrStaleSignature,
+			expectError: true,
+		},
+		{
+			name:        "Missing header",
+			header:      "",
+			body:        body,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWebhookSignature(tt.header, tt.body, secret, 5*time.Minute)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Expected error %v, got: %v", tt.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("Did not expect error, got: %v", err)
+			}
+		})
+	}
+}
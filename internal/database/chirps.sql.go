@@ -7,29 +7,170 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const addChirpMention = `-- name: AddChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT DO NOTHING
+`
+
+type AddChirpMentionParams struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) AddChirpMention(ctx context.Context, arg AddChirpMentionParams) error {
+	_, err := q.db.ExecContext(ctx, addChirpMention, arg.ChirpID, arg.UserID)
+	return err
+}
+
+const claimDueScheduledChirp = `-- name: ClaimDueScheduledChirp :one
+-- like UpdateUnpublishedChirp a chirp goes out with a fresh creation time and starts counting down to when it
+-- expires. The row lock is held until the publishing transaction commits, so other servers skip over it and a chirp
+-- is only ever published once. Chirps from suspended and banned users wait until they're allowed to post again
+UPDATE chirps
+SET status = 'published', created_at = NOW(), updated_at = NOW(), expires_at = NOW() + make_interval(secs => expires_in)
+WHERE id = (
+    SELECT due.id FROM chirps AS due
+    JOIN users ON users.id = due.user_id
+    WHERE due.status = 'scheduled' AND due.publish_at <= NOW()
+    AND (due.publish_retry_at IS NULL OR due.publish_retry_at <= NOW())
+    AND users.deactivated_at IS NULL AND users.banned_at IS NULL
+    AND (users.suspended_until IS NULL OR users.suspended_until <= NOW())
+    ORDER BY due.publish_at
+    LIMIT 1
+    FOR UPDATE OF due SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at
+`
+
+func (q *Queries) ClaimDueScheduledChirp(ctx context.Context) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, claimDueScheduledChirp)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Visibility,
+		&i.Status,
+		&i.PublishAt,
+		&i.ExpiresIn,
+		&i.ExpiresAt,
+		&i.QuoteOfID,
+		&i.PublishAttempts,
+		&i.PublishRetryAt,
+	)
+	return i, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id, visibility, status, publish_at, expires_in, expires_at, quote_of_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body       string
+	UserID     uuid.UUID
+	ReplyToID  uuid.NullUUID
+	Visibility string
+	Status     string
+	PublishAt  sql.NullTime
+	ExpiresIn  sql.NullInt32
+	ExpiresAt  sql.NullTime
+	QuoteOfID  uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ReplyToID,
+		arg.Visibility,
+		arg.Status,
+		arg.PublishAt,
+		arg.ExpiresIn,
+		arg.ExpiresAt,
+		arg.QuoteOfID,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Visibility,
+		&i.Status,
+		&i.PublishAt,
+		&i.ExpiresIn,
+		&i.ExpiresAt,
+		&i.QuoteOfID,
+		&i.PublishAttempts,
+		&i.PublishRetryAt,
+	)
+	return i, err
+}
+
+const createImportedChirp = `-- name: CreateImportedChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at
+`
+
+type CreateImportedChirpParams struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ReplyToID uuid.NullUUID
+}
+
+func (q *Queries) CreateImportedChirp(ctx context.Context, arg CreateImportedChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createImportedChirp,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Body,
+		arg.UserID,
+		arg.ReplyToID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,32 +178,226 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Visibility,
+		&i.Status,
+		&i.PublishAt,
+		&i.ExpiresIn,
+		&i.ExpiresAt,
+		&i.QuoteOfID,
+		&i.PublishAttempts,
+		&i.PublishRetryAt,
 	)
 	return i, err
 }
 
 const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps
-WHERE chirps.id = $1 AND user_id = $2
+UPDATE chirps
+SET deleted_at = NOW(), deleted_by = $3
+WHERE chirps.id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
 type DeleteChirpParams struct {
-	ID     uuid.UUID
-	UserID uuid.UUID
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	DeletedBy uuid.NullUUID
 }
 
 func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) error {
-	_, err := q.db.ExecContext(ctx, deleteChirp, arg.ID, arg.UserID)
+	_, err := q.db.ExecContext(ctx, deleteChirp, arg.ID, arg.UserID, arg.DeletedBy)
 	return err
 }
 
+const deleteExpiredChirps = `-- name: DeleteExpiredChirps :many
+-- expired chirps are already hidden from every read, this just clears them out. Locked rows are left for
+-- whichever server got to them first
+DELETE FROM chirps
+WHERE id IN (
+    SELECT expired.id FROM chirps AS expired
+    WHERE expired.expires_at <= NOW()
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at
+`
+
+func (q *Queries) DeleteExpiredChirps(ctx context.Context, limit int32) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, deleteExpiredChirps, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Visibility,
+			&i.Status,
+			&i.PublishAt,
+			&i.ExpiresIn,
+			&i.ExpiresAt,
+			&i.QuoteOfID,
+			&i.PublishAttempts,
+			&i.PublishRetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteUnpublishedChirp = `-- name: DeleteUnpublishedChirp :execrows
+DELETE FROM chirps
+WHERE id = $1 AND user_id = $2 AND status <> 'published'
+`
+
+type DeleteUnpublishedChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteUnpublishedChirp(ctx context.Context, arg DeleteUnpublishedChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUnpublishedChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const editChirp = `-- name: EditChirp :one
+-- only the body of a published chirp can be changed, and only while it's inside its author's edit window
+UPDATE chirps
+SET body = $1::text, updated_at = NOW()
+WHERE id = $2::uuid AND user_id = $3::uuid AND status = 'published' AND deleted_at IS NULL
+AND created_at >= $4::timestamp
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at
+`
+
+type EditChirpParams struct {
+	Body           string
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	PublishedAfter time.Time
+}
+
+func (q *Queries) EditChirp(ctx context.Context, arg EditChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, editChirp,
+		arg.Body,
+		arg.ID,
+		arg.UserID,
+		arg.PublishedAfter,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Visibility,
+		&i.Status,
+		&i.PublishAt,
+		&i.ExpiresIn,
+		&i.ExpiresAt,
+		&i.QuoteOfID,
+		&i.PublishAttempts,
+		&i.PublishRetryAt,
+	)
+	return i, err
+}
+
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at
+-- only public chirps are listed here, the rest are found through their author, a thread or the timeline
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.reply_to_id, chirps.deleted_at, chirps.deleted_by, chirps.visibility, chirps.status, chirps.publish_at, chirps.expires_in, chirps.expires_at, chirps.quote_of_id, chirps.publish_attempts, chirps.publish_retry_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.deactivated_at IS NULL AND chirps.status = 'published'
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND ($1::boolean OR chirps.deleted_at IS NULL)
+AND (chirps.visibility = 'public' OR chirps.user_id = $2::uuid)
+AND (chirps.user_id = $2::uuid OR (
+    (NOT users.is_protected OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id))
+    AND (chirps.visibility IN ('public', 'unlisted')
+        OR (chirps.visibility = 'followers' AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id))
+        OR (chirps.visibility = 'mentioned' AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = $2::uuid)))
+))
+AND ($3::boolean OR (users.banned_at IS NULL AND (users.suspended_until IS NULL OR users.suspended_until <= NOW())))
+ORDER BY chirps.created_at
+`
+
+type GetAllChirpsParams struct {
+	IncludeDeleted    bool
+	ViewerID          uuid.UUID
+	IncludeRestricted bool
+}
+
+func (q *Queries) GetAllChirps(ctx context.Context, arg GetAllChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllChirps, arg.IncludeDeleted, arg.ViewerID, arg.IncludeRestricted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Visibility,
+			&i.Status,
+			&i.PublishAt,
+			&i.ExpiresIn,
+			&i.ExpiresAt,
+			&i.QuoteOfID,
+			&i.PublishAttempts,
+			&i.PublishRetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllChirpsByAuthorID = `-- name: GetAllChirpsByAuthorID :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at FROM chirps
+WHERE chirps.user_id = $1
+ORDER BY chirps.created_at
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+func (q *Queries) GetAllChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllChirpsByAuthorID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +411,17 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Visibility,
+			&i.Status,
+			&i.PublishAt,
+			&i.ExpiresIn,
+			&i.ExpiresAt,
+			&i.QuoteOfID,
+			&i.PublishAttempts,
+			&i.PublishRetryAt,
 		); err != nil {
 			return nil, err
 		}
@@ -91,8 +437,10 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE chirps.id = $1
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.reply_to_id, chirps.deleted_at, chirps.deleted_by, chirps.visibility, chirps.status, chirps.publish_at, chirps.expires_in, chirps.expires_at, chirps.quote_of_id, chirps.publish_attempts, chirps.publish_retry_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND users.deactivated_at IS NULL AND chirps.status = 'published' AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
 `
 
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -104,17 +452,394 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Visibility,
+		&i.Status,
+		&i.PublishAt,
+		&i.ExpiresIn,
+		&i.ExpiresAt,
+		&i.QuoteOfID,
+		&i.PublishAttempts,
+		&i.PublishRetryAt,
 	)
 	return i, err
 }
 
+const getChirpByIDIncludingDeleted = `-- name: GetChirpByIDIncludingDeleted :one
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.reply_to_id, chirps.deleted_at, chirps.deleted_by, chirps.visibility, chirps.status, chirps.publish_at, chirps.expires_in, chirps.expires_at, chirps.quote_of_id, chirps.publish_attempts, chirps.publish_retry_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND users.deactivated_at IS NULL AND chirps.status = 'published'
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+`
+
+func (q *Queries) GetChirpByIDIncludingDeleted(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByIDIncludingDeleted, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Visibility,
+		&i.Status,
+		&i.PublishAt,
+		&i.ExpiresIn,
+		&i.ExpiresAt,
+		&i.QuoteOfID,
+		&i.PublishAttempts,
+		&i.PublishRetryAt,
+	)
+	return i, err
+}
+
+const getChirpMentionIDs = `-- name: GetChirpMentionIDs :many
+SELECT user_id FROM chirp_mentions
+WHERE chirp_mentions.chirp_id = $1
+`
+
+func (q *Queries) GetChirpMentionIDs(ctx context.Context, chirpID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpMentionIDs, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpShareCounts = `-- name: GetChirpShareCounts :many
+-- quotes are chirps of their own so only the ones still up are counted, rechirps go with their chirp
+SELECT chirps.id,
+    (SELECT COUNT(*) FROM rechirps WHERE rechirps.chirp_id = chirps.id) AS rechirp_count,
+    (SELECT COUNT(*) FROM chirps AS quotes
+        WHERE quotes.quote_of_id = chirps.id AND quotes.status = 'published' AND quotes.deleted_at IS NULL
+        AND (quotes.expires_at IS NULL OR quotes.expires_at > NOW())) AS quote_count
+FROM chirps
+WHERE chirps.id = ANY($1::uuid[])
+`
+
+type GetChirpShareCountsRow struct {
+	ID           uuid.UUID
+	RechirpCount int64
+	QuoteCount   int64
+}
+
+func (q *Queries) GetChirpShareCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetChirpShareCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpShareCounts, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpShareCountsRow
+	for rows.Next() {
+		var i GetChirpShareCountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsAfter = `-- name: GetChirpsAfter :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.reply_to_id, chirps.deleted_at, chirps.deleted_by, chirps.visibility, chirps.status, chirps.publish_at, chirps.expires_in, chirps.expires_at, chirps.quote_of_id, chirps.publish_attempts, chirps.publish_retry_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE (chirps.created_at, chirps.id) > ($1::timestamp, $2::uuid)
+AND users.deactivated_at IS NULL AND chirps.status = 'published' AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND (chirps.user_id = $3::uuid OR (
+    (NOT users.is_protected OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $3::uuid AND follows.followee_id = chirps.user_id))
+    AND (chirps.visibility IN ('public', 'unlisted')
+        OR (chirps.visibility = 'followers' AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $3::uuid AND follows.followee_id = chirps.user_id))
+        OR (chirps.visibility = 'mentioned' AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = $3::uuid)))
+))
+ORDER BY chirps.created_at, chirps.id
+LIMIT $4::int
+`
+
+type GetChirpsAfterParams struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	ViewerID  uuid.UUID
+	PageSize  int32
+}
+
+func (q *Queries) GetChirpsAfter(ctx context.Context, arg GetChirpsAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsAfter,
+		arg.CreatedAt,
+		arg.ID,
+		arg.ViewerID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Visibility,
+			&i.Status,
+			&i.PublishAt,
+			&i.ExpiresIn,
+			&i.ExpiresAt,
+			&i.QuoteOfID,
+			&i.PublishAttempts,
+			&i.PublishRetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirpsByAuthorID = `-- name: GetChirpsByAuthorID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE chirps.user_id = $1
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.reply_to_id, chirps.deleted_at, chirps.deleted_by, chirps.visibility, chirps.status, chirps.publish_at, chirps.expires_in, chirps.expires_at, chirps.quote_of_id, chirps.publish_attempts, chirps.publish_retry_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = $1::uuid AND users.deactivated_at IS NULL AND chirps.status = 'published'
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND ($2::boolean OR chirps.deleted_at IS NULL)
+AND (chirps.user_id = $3::uuid OR (
+    (NOT users.is_protected OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $3::uuid AND follows.followee_id = chirps.user_id))
+    AND (chirps.visibility IN ('public', 'unlisted')
+        OR (chirps.visibility = 'followers' AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $3::uuid AND follows.followee_id = chirps.user_id))
+        OR (chirps.visibility = 'mentioned' AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = $3::uuid)))
+))
+AND ($4::boolean OR (users.banned_at IS NULL AND (users.suspended_until IS NULL OR users.suspended_until <= NOW())));
+
+-- every chirp still stored for a user whatever state it's in, for their data export
+`
+
+type GetChirpsByAuthorIDParams struct {
+	UserID            uuid.UUID
+	IncludeDeleted    bool
+	ViewerID          uuid.UUID
+	IncludeRestricted bool
+}
+
+func (q *Queries) GetChirpsByAuthorID(ctx context.Context, arg GetChirpsByAuthorIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByAuthorID,
+		arg.UserID,
+		arg.IncludeDeleted,
+		arg.ViewerID,
+		arg.IncludeRestricted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Visibility,
+			&i.Status,
+			&i.PublishAt,
+			&i.ExpiresIn,
+			&i.ExpiresAt,
+			&i.QuoteOfID,
+			&i.PublishAttempts,
+			&i.PublishRetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRestorableChirps = `-- name: GetRestorableChirps :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at FROM chirps
+WHERE user_id = $1::uuid AND deleted_by = user_id AND deleted_at > $2::timestamp
+AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY deleted_at DESC
+`
+
+type GetRestorableChirpsParams struct {
+	UserID       uuid.UUID
+	DeletedAfter time.Time
+}
+
+func (q *Queries) GetRestorableChirps(ctx context.Context, arg GetRestorableChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getRestorableChirps, arg.UserID, arg.DeletedAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Visibility,
+			&i.Status,
+			&i.PublishAt,
+			&i.ExpiresIn,
+			&i.ExpiresAt,
+			&i.QuoteOfID,
+			&i.PublishAttempts,
+			&i.PublishRetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnpublishedChirps = `-- name: GetUnpublishedChirps :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at FROM chirps
+WHERE user_id = $1::uuid AND status <> 'published'
+AND ($2::text = '' OR status = $2::text)
+ORDER BY COALESCE(publish_at, updated_at)
+`
+
+type GetUnpublishedChirpsParams struct {
+	UserID uuid.UUID
+	Status string
+}
+
+func (q *Queries) GetUnpublishedChirps(ctx context.Context, arg GetUnpublishedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getUnpublishedChirps, arg.UserID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Visibility,
+			&i.Status,
+			&i.PublishAt,
+			&i.ExpiresIn,
+			&i.ExpiresAt,
+			&i.QuoteOfID,
+			&i.PublishAttempts,
+			&i.PublishRetryAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleChirpsByIDs = `-- name: GetVisibleChirpsByIDs :many
+-- the chirps out of ids the viewer is allowed to see, used for quoted chirps so anything deleted, expired,
+-- hidden from them or behind a block is simply missing
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.reply_to_id, chirps.deleted_at, chirps.deleted_by, chirps.visibility, chirps.status, chirps.publish_at, chirps.expires_in, chirps.expires_at, chirps.quote_of_id, chirps.publish_attempts, chirps.publish_retry_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = ANY($1::uuid[])
+AND users.deactivated_at IS NULL AND chirps.status = 'published' AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND users.banned_at IS NULL AND (users.suspended_until IS NULL OR users.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2::uuid)
+)
+AND (chirps.user_id = $2::uuid OR (
+    (NOT users.is_protected OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id))
+    AND (chirps.visibility IN ('public', 'unlisted')
+        OR (chirps.visibility = 'followers' AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id))
+        OR (chirps.visibility = 'mentioned' AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = $2::uuid)))
+))
 `
 
-func (q *Queries) GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthorID, userID)
+type GetVisibleChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetVisibleChirpsByIDs(ctx context.Context, arg GetVisibleChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getVisibleChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -128,6 +853,17 @@ func (q *Queries) GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Visibility,
+			&i.Status,
+			&i.PublishAt,
+			&i.ExpiresIn,
+			&i.ExpiresAt,
+			&i.QuoteOfID,
+			&i.PublishAttempts,
+			&i.PublishRetryAt,
 		); err != nil {
 			return nil, err
 		}
@@ -141,3 +877,139 @@ func (q *Queries) GetChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]
 	}
 	return items, nil
 }
+
+const markScheduledChirpFailed = `-- name: MarkScheduledChirpFailed :exec
+-- run outside the publishing transaction since that's rolled back, the chirp goes back to being a draft once it's
+-- used up its attempts so its author can see it didn't go out
+UPDATE chirps
+SET publish_attempts = publish_attempts + 1, publish_retry_at = $1::timestamp,
+    status = CASE WHEN publish_attempts + 1 >= $2::int THEN 'draft' ELSE status END
+WHERE id = $3::uuid AND status = 'scheduled'
+`
+
+type MarkScheduledChirpFailedParams struct {
+	RetryAt     time.Time
+	MaxAttempts int32
+	ID          uuid.UUID
+}
+
+func (q *Queries) MarkScheduledChirpFailed(ctx context.Context, arg MarkScheduledChirpFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markScheduledChirpFailed, arg.RetryAt, arg.MaxAttempts, arg.ID)
+	return err
+}
+
+const notifyStream = `-- name: NotifyStream :exec
+SELECT pg_notify('chirpy_stream', $1::text)
+`
+
+func (q *Queries) NotifyStream(ctx context.Context, payload string) error {
+	_, err := q.db.ExecContext(ctx, notifyStream, payload)
+	return err
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, deleted_by = NULL
+WHERE chirps.id = $1::uuid AND user_id = $2::uuid
+AND deleted_by = user_id AND deleted_at > $3::timestamp
+AND (expires_at IS NULL OR expires_at > NOW())
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at
+`
+
+type RestoreChirpParams struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	DeletedAfter time.Time
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.UserID, arg.DeletedAfter)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Visibility,
+		&i.Status,
+		&i.PublishAt,
+		&i.ExpiresIn,
+		&i.ExpiresAt,
+		&i.QuoteOfID,
+		&i.PublishAttempts,
+		&i.PublishRetryAt,
+	)
+	return i, err
+}
+
+const updateUnpublishedChirp = `-- name: UpdateUnpublishedChirp :one
+-- a chirp published from here takes the time it went out as its creation time so it lands at the top of
+-- timelines and streams
+UPDATE chirps
+SET body = $3, visibility = $4, status = $5, publish_at = $6, expires_in = $7, expires_at = $8, updated_at = NOW(),
+    created_at = CASE WHEN $5 = 'published' THEN NOW() ELSE created_at END,
+    publish_attempts = 0, publish_retry_at = NULL
+WHERE id = $1 AND user_id = $2 AND status <> 'published'
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, deleted_at, deleted_by, visibility, status, publish_at, expires_in, expires_at, quote_of_id, publish_attempts, publish_retry_at
+`
+
+type UpdateUnpublishedChirpParams struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Body       string
+	Visibility string
+	Status     string
+	PublishAt  sql.NullTime
+	ExpiresIn  sql.NullInt32
+	ExpiresAt  sql.NullTime
+}
+
+func (q *Queries) UpdateUnpublishedChirp(ctx context.Context, arg UpdateUnpublishedChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateUnpublishedChirp,
+		arg.ID,
+		arg.UserID,
+		arg.Body,
+		arg.Visibility,
+		arg.Status,
+		arg.PublishAt,
+		arg.ExpiresIn,
+		arg.ExpiresAt,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Visibility,
+		&i.Status,
+		&i.PublishAt,
+		&i.ExpiresIn,
+		&i.ExpiresAt,
+		&i.QuoteOfID,
+		&i.PublishAttempts,
+		&i.PublishRetryAt,
+	)
+	return i, err
+}
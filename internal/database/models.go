@@ -6,17 +6,265 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AuditLog struct {
+	ID         int64
+	CreatedAt  time.Time
+	ActorType  string
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     json.RawMessage
+	After      json.RawMessage
+	Ip         string
+	RequestID  string
+	PrevHash   string
+	Hash       string
+}
+
+type BannedEmail struct {
+	Email     string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Reason    sql.NullString
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Body            string
+	UserID          uuid.UUID
+	ReplyToID       uuid.NullUUID
+	DeletedAt       sql.NullTime
+	DeletedBy       uuid.NullUUID
+	Visibility      string
+	Status          string
+	PublishAt       sql.NullTime
+	ExpiresIn       sql.NullInt32
+	ExpiresAt       sql.NullTime
+	QuoteOfID       uuid.NullUUID
+	PublishAttempts int32
+	PublishRetryAt  sql.NullTime
+}
+
+type ChirpImport struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Source      string
+	Status      string
+	Error       sql.NullString
+	CompletedAt sql.NullTime
+}
+
+type ChirpImportItem struct {
+	ID                uuid.UUID
+	ImportID          uuid.UUID
+	Position          int32
+	ExternalID        string
+	ReplyToExternalID sql.NullString
+	PostedAt          time.Time
+	Body              string
+	Status            string
+	Error             sql.NullString
+	ChirpID           uuid.NullUUID
+}
+
+type ChirpMedium struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Url      string
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+type Conversation struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+	CreatedBy uuid.UUID
+	IsGroup   bool
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+	ClearedAt      sql.NullTime
+}
+
+type DataExport struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Status      string
+	SizeBytes   int64
+	Error       sql.NullString
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+type DataExportArchive struct {
+	ExportID uuid.UUID
+	Archive  []byte
+}
+
+type EmailChangeRequest struct {
 	UserID    uuid.UUID
+	CreatedAt time.Time
+	NewEmail  string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type FollowRequest struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type HandleReservation struct {
+	Handle        string
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	ReservedUntil time.Time
+}
+
+type Like struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+type MessageDeletion struct {
+	MessageID uuid.UUID
+	UserID    uuid.UUID
+	DeletedAt time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type MutedKeyword struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Keyword   string
+	ExpiresAt sql.NullTime
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Type      string
+	GroupKey  string
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+	CreatedAt      time.Time
+}
+
+type NotificationPreference struct {
+	UserID    uuid.UUID
+	Type      string
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
+type Outbox struct {
+	ID            int64
+	CreatedAt     time.Time
+	EventID       uuid.UUID
+	AggregateType string
+	AggregateID   uuid.UUID
+	EventType     string
+	Payload       json.RawMessage
+	Attempts      int32
+	LastError     sql.NullString
+	PublishedAt   sql.NullTime
+	NextAttemptAt time.Time
+	DeadAt        sql.NullTime
+}
+
+type OutboxRelayLease struct {
+	ID        int32
+	Holder    uuid.UUID
+	ExpiresAt time.Time
+}
+
+type Poll struct {
+	ChirpID        uuid.UUID
+	MultipleChoice bool
+	Duration       int32
+	ClosesAt       sql.NullTime
+	ClosedAt       sql.NullTime
+}
+
+type PollBallot struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type PollOption struct {
+	ID       uuid.UUID
+	ChirpID  uuid.UUID
+	Position int32
+	Text     string
+}
+
+type PollVote struct {
+	OptionID uuid.UUID
+	ChirpID  uuid.UUID
+	UserID   uuid.UUID
+}
+
+type Rechirp struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +276,131 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type Report struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	ReporterID     uuid.NullUUID
+	TargetType     string
+	TargetID       uuid.UUID
+	TargetUserID   uuid.NullUUID
+	Reason         string
+	Details        string
+	Content        string
+	Status         string
+	ClaimedBy      uuid.NullUUID
+	ClaimedAt      sql.NullTime
+	ResolvedBy     uuid.NullUUID
+	ResolvedAt     sql.NullTime
+	ResolutionNote string
+}
+
+type ReportAction struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	ReportID    uuid.UUID
+	ModeratorID uuid.NullUUID
+	Action      string
+	Note        string
+	ExpiresAt   sql.NullTime
+}
+
+type Subscription struct {
+	UserID      uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Status      string
+	RedUntil    time.Time
+	LastEventID string
+}
+
 type User struct {
+	ID                    uuid.UUID
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	Email                 string
+	HashedPassword        string
+	IsChirpyRed           sql.NullBool
+	DmsOpen               bool
+	Handle                string
+	DisplayName           string
+	Bio                   string
+	Location              string
+	Website               string
+	AvatarUrl             string
+	DeactivatedAt         sql.NullTime
+	DeleteAfter           sql.NullTime
+	Role                  string
+	SuspendedUntil        sql.NullTime
+	SuspensionReason      sql.NullString
+	BannedAt              sql.NullTime
+	BanReason             sql.NullString
+	IsProtected           bool
+	DefaultChirpExpiresIn sql.NullInt32
+}
+
+type WebauthnChallenge struct {
+	Challenge string
+	CreatedAt time.Time
+	Ceremony  string
+	UserID    uuid.NullUUID
+	ExpiresAt time.Time
+}
+
+type WebauthnCredential struct {
+	ID         []byte
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	PublicKey  []byte
+	SignCount  int64
+	LastUsedAt sql.NullTime
+}
+
+type WebhookDelivery struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    sql.NullBool
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastStatusCode sql.NullInt32
+	LastError      sql.NullString
+}
+
+type WebhookDeliveryAttempt struct {
+	ID         int64
+	CreatedAt  time.Time
+	DeliveryID uuid.UUID
+	StatusCode sql.NullInt32
+	Error      sql.NullString
+	DurationMs int32
+}
+
+type WebhookEvent struct {
+	ID          string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Source      string
+	Event       string
+	Payload     []byte
+	Signature   string
+	Deliveries  int32
+	ProcessedAt sql.NullTime
+	LastError   sql.NullString
+}
+
+type WebhookSubscription struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Url       string
+	Secret    string
+	Events    []string
+	Active    bool
 }
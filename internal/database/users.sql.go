@@ -7,29 +7,75 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const banUser = `-- name: BanUser :one
+UPDATE users
+SET banned_at = NOW(), ban_reason = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type BanUserParams struct {
+	ID        uuid.UUID
+	BanReason sql.NullString
+}
+
+func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, banUser, arg.ID, arg.BanReason)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,10 +84,107 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
 	)
 	return i, err
 }
 
+const deactivateUser = `-- name: DeactivateUser :one
+UPDATE users
+SET deactivated_at = NOW(), delete_after = $1::timestamp, updated_at = NOW()
+WHERE id = $2::uuid
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type DeactivateUserParams struct {
+	DeleteAfter time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) DeactivateUser(ctx context.Context, arg DeactivateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, deactivateUser, arg.DeleteAfter, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const deleteBannedEmails = `-- name: DeleteBannedEmails :exec
+DELETE FROM banned_emails
+WHERE banned_emails.user_id = $1
+`
+
+func (q *Queries) DeleteBannedEmails(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteBannedEmails, userID)
+	return err
+}
+
+const deleteDeactivatedUsers = `-- name: DeleteDeactivatedUsers :many
+DELETE FROM users
+WHERE delete_after <= NOW()
+RETURNING id
+`
+
+func (q *Queries) DeleteDeactivatedUsers(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, deleteDeactivatedUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deleteUsers = `-- name: DeleteUsers :exec
 DELETE FROM users
 `
@@ -51,8 +194,25 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const getHandleReservation = `-- name: GetHandleReservation :one
+SELECT handle, user_id, created_at, reserved_until FROM handle_reservations
+WHERE handle_reservations.handle = LOWER($1::text) AND reserved_until > NOW()
+`
+
+func (q *Queries) GetHandleReservation(ctx context.Context, handle string) (HandleReservation, error) {
+	row := q.db.QueryRowContext(ctx, getHandleReservation, handle)
+	var i HandleReservation
+	err := row.Scan(
+		&i.Handle,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.ReservedUntil,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in FROM users
 WHERE users.email = $1
 `
 
@@ -66,32 +226,719 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in FROM users
+WHERE LOWER(users.handle) = LOWER($1::text)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in FROM users
+WHERE users.id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const getUserByIDForUpdate = `-- name: GetUserByIDForUpdate :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in FROM users
+WHERE users.id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetUserByIDForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByIDForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :exec
+const getUserProfileCounts = `-- name: GetUserProfileCounts :one
+SELECT
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = $1::uuid) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = $1::uuid) AS following_count,
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = $1::uuid AND chirps.status = 'published' AND chirps.deleted_at IS NULL
+        AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())) AS chirp_count
+`
+
+type GetUserProfileCountsRow struct {
+	FollowerCount  int64
+	FollowingCount int64
+	ChirpCount     int64
+}
+
+func (q *Queries) GetUserProfileCounts(ctx context.Context, userID uuid.UUID) (GetUserProfileCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfileCounts, userID)
+	var i GetUserProfileCountsRow
+	err := row.Scan(
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.ChirpCount,
+	)
+	return i, err
+}
+
+const isEmailBanned = `-- name: IsEmailBanned :one
+SELECT EXISTS (
+    SELECT 1 FROM banned_emails
+    WHERE banned_emails.email = lower($1)
+) AS banned
+`
+
+func (q *Queries) IsEmailBanned(ctx context.Context, email string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isEmailBanned, email)
+	var banned bool
+	err := row.Scan(&banned)
+	return banned, err
+}
+
+const liftSuspension = `-- name: LiftSuspension :one
 UPDATE users
-SET email = $1, hashed_password = $2
-WHERE id = $3
+SET suspended_until = NULL, suspension_reason = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
 `
 
-type UpdateUserParams struct {
-	Email          string
-	HashedPassword string
-	ID             uuid.UUID
+func (q *Queries) LiftSuspension(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, liftSuspension, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const reactivateUser = `-- name: ReactivateUser :one
+UPDATE users
+SET deactivated_at = NULL, delete_after = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+func (q *Queries) ReactivateUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, reactivateUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const recordBannedEmail = `-- name: RecordBannedEmail :exec
+INSERT INTO banned_emails (email, created_at, user_id, reason)
+SELECT lower(users.email), NOW(), users.id, users.ban_reason
+FROM users
+WHERE users.id = $1
+ON CONFLICT (email) DO UPDATE
+SET user_id = EXCLUDED.user_id, reason = EXCLUDED.reason
+`
+
+func (q *Queries) RecordBannedEmail(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordBannedEmail, id)
+	return err
+}
+
+const releaseHandleReservation = `-- name: ReleaseHandleReservation :exec
+DELETE FROM handle_reservations
+WHERE handle = LOWER($1::text) AND user_id = $2::uuid
+`
+
+type ReleaseHandleReservationParams struct {
+	Handle string
+	UserID uuid.UUID
+}
+
+func (q *Queries) ReleaseHandleReservation(ctx context.Context, arg ReleaseHandleReservationParams) error {
+	_, err := q.db.ExecContext(ctx, releaseHandleReservation, arg.Handle, arg.UserID)
+	return err
+}
+
+const reserveHandle = `-- name: ReserveHandle :exec
+INSERT INTO handle_reservations (handle, user_id, created_at, reserved_until)
+VALUES (
+    LOWER($1::text),
+    $2::uuid,
+    NOW(),
+    $3::timestamp
+)
+ON CONFLICT (handle) DO UPDATE
+SET user_id = EXCLUDED.user_id, created_at = EXCLUDED.created_at, reserved_until = EXCLUDED.reserved_until
+`
+
+type ReserveHandleParams struct {
+	Handle        string
+	UserID        uuid.UUID
+	ReservedUntil time.Time
+}
+
+func (q *Queries) ReserveHandle(ctx context.Context, arg ReserveHandleParams) error {
+	_, err := q.db.ExecContext(ctx, reserveHandle, arg.Handle, arg.UserID, arg.ReservedUntil)
+	return err
+}
+
+const setDefaultChirpExpiry = `-- name: SetDefaultChirpExpiry :one
+UPDATE users
+SET default_chirp_expires_in = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type SetDefaultChirpExpiryParams struct {
+	ID                    uuid.UUID
+	DefaultChirpExpiresIn sql.NullInt32
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
-	_, err := q.db.ExecContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+func (q *Queries) SetDefaultChirpExpiry(ctx context.Context, arg SetDefaultChirpExpiryParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setDefaultChirpExpiry, arg.ID, arg.DefaultChirpExpiresIn)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const setUserChirpyRed = `-- name: SetUserChirpyRed :exec
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserChirpyRedParams struct {
+	ID          uuid.UUID
+	IsChirpyRed sql.NullBool
+}
+
+func (q *Queries) SetUserChirpyRed(ctx context.Context, arg SetUserChirpyRedParams) error {
+	_, err := q.db.ExecContext(ctx, setUserChirpyRed, arg.ID, arg.IsChirpyRed)
 	return err
 }
 
+const setUserDMsOpen = `-- name: SetUserDMsOpen :exec
+UPDATE users
+SET dms_open = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserDMsOpenParams struct {
+	ID      uuid.UUID
+	DmsOpen bool
+}
+
+func (q *Queries) SetUserDMsOpen(ctx context.Context, arg SetUserDMsOpenParams) error {
+	_, err := q.db.ExecContext(ctx, setUserDMsOpen, arg.ID, arg.DmsOpen)
+	return err
+}
+
+const setUserEmail = `-- name: SetUserEmail :one
+UPDATE users
+SET email = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type SetUserEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) SetUserEmail(ctx context.Context, arg SetUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const setUserHandle = `-- name: SetUserHandle :one
+UPDATE users
+SET handle = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type SetUserHandleParams struct {
+	ID     uuid.UUID
+	Handle string
+}
+
+func (q *Queries) SetUserHandle(ctx context.Context, arg SetUserHandleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserHandle, arg.ID, arg.Handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const setUserPassword = `-- name: SetUserPassword :one
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type SetUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg SetUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserPassword, arg.ID, arg.HashedPassword)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const setUserProtected = `-- name: SetUserProtected :one
+UPDATE users
+SET is_protected = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type SetUserProtectedParams struct {
+	ID          uuid.UUID
+	IsProtected bool
+}
+
+func (q *Queries) SetUserProtected(ctx context.Context, arg SetUserProtectedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserProtected, arg.ID, arg.IsProtected)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET suspended_until = $2, suspension_reason = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type SuspendUserParams struct {
+	ID               uuid.UUID
+	SuspendedUntil   sql.NullTime
+	SuspensionReason sql.NullString
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, arg.ID, arg.SuspendedUntil, arg.SuspensionReason)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const unbanUser = `-- name: UnbanUser :one
+UPDATE users
+SET banned_at = NULL, ban_reason = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+func (q *Queries) UnbanUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, unbanUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET display_name = $2, bio = $3, location = $4, website = $5, avatar_url = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	DisplayName string
+	Bio         string
+	Location    string
+	Website     string
+	AvatarUrl   string
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.DisplayName,
+		arg.Bio,
+		arg.Location,
+		arg.Website,
+		arg.AvatarUrl,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
+	)
+	return i, err
+}
+
 const upgradeUser = `-- name: UpgradeUser :one
 UPDATE users
 SET is_chirpy_red = true
 WHERe id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, dms_open, handle, display_name, bio, location, website, avatar_url, deactivated_at, delete_after, role, suspended_until, suspension_reason, banned_at, ban_reason, is_protected, default_chirp_expires_in
 `
 
 func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (User, error) {
@@ -104,6 +951,22 @@ func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DmsOpen,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.DeactivatedAt,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.IsProtected,
+		&i.DefaultChirpExpiresIn,
 	)
 	return i, err
 }
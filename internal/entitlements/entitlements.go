@@ -0,0 +1,149 @@
+package entitlements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	PlanFree      = "free"
+	PlanChirpyRed = "chirpy_red"
+)
+
+type Capability string
+
+const (
+	CapabilityEditChirps     Capability = "edit_chirps"
+	CapabilityAttachMedia    Capability = "attach_media"
+	CapabilityScheduledPosts Capability = "scheduled_posts"
+)
+
+// Duration lets plan files use strings like "15m" instead of nanoseconds
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+
+	return nil
+}
+
+type Plan struct {
+	Name             string   `json:"name"`
+	MaxChirpLength   int      `json:"max_chirp_length"`
+	EditWindow       Duration `json:"edit_window"`
+	ChirpsPerMinute  int      `json:"chirps_per_minute"`
+	MaxMediaPerChirp int      `json:"max_media_per_chirp"`
+	ScheduledPosts   bool     `json:"scheduled_posts"`
+}
+
+// Plans maps a plan name to what it is allowed to do
+type Plans map[string]Plan
+
+func DefaultPlans() Plans {
+	return Plans{
+		PlanFree: {
+			Name:             PlanFree,
+			MaxChirpLength:   140,
+			ChirpsPerMinute:  10,
+			MaxMediaPerChirp: 1,
+		},
+		PlanChirpyRed: {
+			Name:             PlanChirpyRed,
+			MaxChirpLength:   500,
+			EditWindow:       Duration(15 * time.Minute),
+			ChirpsPerMinute:  60,
+			MaxMediaPerChirp: 4,
+			ScheduledPosts:   true,
+		},
+	}
+}
+
+// LoadPlans reads plan definitions from a JSON file, plans missing from the file keep their defaults and so do
+// any settings left out of a plan that has one
+func LoadPlans(path string) (Plans, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := map[string]json.RawMessage{}
+	err = json.Unmarshal(data, &loaded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plans config: %w", err)
+	}
+
+	plans := DefaultPlans()
+	for name, raw := range loaded {
+		// decoding over the default only replaces what the file sets
+		plan := plans[name]
+		err = json.Unmarshal(raw, &plan)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plan %q: %w", name, err)
+		}
+		plan.Name = name
+		err = plan.validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid plan %q: %w", name, err)
+		}
+		plans[name] = plan
+	}
+
+	return plans, nil
+}
+
+// ForUser picks the plan a user is on, Chirpy Red is currently the only paid plan
+func (p Plans) ForUser(isChirpyRed bool) Plan {
+	if isChirpyRed {
+		return p[PlanChirpyRed]
+	}
+	return p[PlanFree]
+}
+
+func (p Plan) Can(capability Capability) bool {
+	switch capability {
+	case CapabilityEditChirps:
+		return p.EditWindow > 0
+	case CapabilityAttachMedia:
+		return p.MaxMediaPerChirp > 0
+	case CapabilityScheduledPosts:
+		return p.ScheduledPosts
+	default:
+		return false
+	}
+}
+
+// CanEditChirp reports whether a chirp made at createdAt is still inside the plan's edit window
+func (p Plan) CanEditChirp(createdAt, now time.Time) bool {
+	return p.Can(CapabilityEditChirps) && now.Sub(createdAt) <= time.Duration(p.EditWindow)
+}
+
+func (p Plan) validate() error {
+	if p.MaxChirpLength <= 0 {
+		return errors.New("max_chirp_length must be positive")
+	}
+	if p.ChirpsPerMinute <= 0 {
+		return errors.New("chirps_per_minute must be positive")
+	}
+	if p.EditWindow < 0 || p.MaxMediaPerChirp < 0 {
+		return errors.New("edit_window and max_media_per_chirp can't be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package entitlements
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadPlansOverridesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plans.json")
+	config := `{
+		"chirpy_red": {"max_chirp_length": 1000, "edit_window": "1h", "chirps_per_minute": 120, "max_media_per_chirp": 8, "scheduled_posts": true}
+	}`
+	err := os.WriteFile(path, []byte(config), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+
+	plans, err := LoadPlans(path)
+	if err != nil {
+		t.Fatalf("Did not expect error, got: %v", err)
+	}
+
+	red := plans.ForUser(true)
+	if red.Name != PlanChirpyRed || red.MaxChirpLength != 1000 || time.Duration(red.EditWindow) != time.Hour {
+		t.Errorf("Chirpy Red plan was not loaded from config: %+v", red)
+	}
+
+	free := plans.ForUser(false)
+	if free.MaxChirpLength != 140 {
+		t.Errorf("Expected free plan to keep its default chirp length, got %d", free.MaxChirpLength)
+	}
+}
+
+func TestLoadPlansMergesPartialPlan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plans.json")
+	err := os.WriteFile(path, []byte(`{"chirpy_red": {"max_chirp_length": 1000}}`), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+
+	plans, err := LoadPlans(path)
+	if err != nil {
+		t.Fatalf("Did not expect error, got: %v", err)
+	}
+
+	red := plans.ForUser(true)
+	defaults := DefaultPlans()[PlanChirpyRed]
+	if red.MaxChirpLength != 1000 {
+		t.Errorf("Expected max_chirp_length to be overridden, got %d", red.MaxChirpLength)
+	}
+	if red.ChirpsPerMinute != defaults.ChirpsPerMinute || red.EditWindow != defaults.EditWindow ||
+		red.MaxMediaPerChirp != defaults.MaxMediaPerChirp || !red.ScheduledPosts {
+		t.Errorf("Expected settings left out to keep their defaults, got %+v", red)
+	}
+}
+
+func TestLoadPlansRejectsInvalidPlan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plans.json")
+	err := os.WriteFile(path, []byte(`{"free": {"max_chirp_length": 0, "chirps_per_minute": 5}}`), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+
+	_, err = LoadPlans(path)
+	if err == nil {
+		t.Error("Expected error for plan with no chirp length, got nil")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	plans := DefaultPlans()
+	free := plans.ForUser(false)
+	red := plans.ForUser(true)
+	now := time.Now()
+
+	if free.Can(CapabilityScheduledPosts) || !red.Can(CapabilityScheduledPosts) {
+		t.Error("Only Chirpy Red should be able to schedule posts")
+	}
+	if free.CanEditChirp(now, now) {
+		t.Error("Free plan should not be able to edit chirps")
+	}
+	if !red.CanEditChirp(now.Add(-time.Minute), now) {
+		t.Error("Chirpy Red should be able to edit a chirp inside the edit window")
+	}
+	if red.CanEditChirp(now.Add(-time.Hour), now) {
+		t.Error("Chirpy Red should not be able to edit a chirp after the edit window")
+	}
+}
@@ -0,0 +1,227 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// archives that can be imported, the source is kept with each import so the same post is never brought
+// over twice
+const (
+	SourceTwitter  = "twitter"
+	SourceMastodon = "mastodon"
+)
+
+const activityStreamsPublic = "https://www.w3.org/ns/activitystreams#Public"
+
+var (
+	ErrUnrecognizedArchive = errors.New("archive must be a Twitter/X tweets.js file or a Mastodon outbox.json file")
+	ErrNoPosts             = errors.New("archive has no posts in it")
+)
+
+// Post is one post found in an archive, oldest first so a reply always comes after the post it answers
+type Post struct {
+	ExternalID        string
+	ReplyToExternalID string
+	PostedAt          time.Time
+	Body              string
+	// set when the post is left out on purpose, like a repost of someone else's post
+	SkipReason string
+}
+
+var (
+	lineBreakTags  = regexp.MustCompile(`(?i)<br\s*/?>`)
+	paragraphBreak = regexp.MustCompile(`(?i)</p>\s*<p[^>]*>`)
+	htmlTags       = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Parse works out what kind of archive it was given and reads the posts out of it
+func Parse(data []byte) (string, []Post, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+
+	var source string
+	var posts []Post
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("window.YTD.")):
+		// tweets.js is a script assigning the JSON to a global, the JSON starts after the =
+		_, data, _ = bytes.Cut(data, []byte("="))
+		source = SourceTwitter
+		posts, err = parseTweets(data)
+	case bytes.HasPrefix(data, []byte("[")):
+		source = SourceTwitter
+		posts, err = parseTweets(data)
+	case bytes.HasPrefix(data, []byte("{")):
+		source = SourceMastodon
+		posts, err = parseOutbox(data)
+	default:
+		return "", nil, ErrUnrecognizedArchive
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if len(posts) == 0 {
+		return "", nil, ErrNoPosts
+	}
+
+	slices.SortStableFunc(posts, func(a, b Post) int {
+		return a.PostedAt.Compare(b.PostedAt)
+	})
+
+	return source, posts, nil
+}
+
+type tweet struct {
+	IDStr                string `json:"id_str"`
+	FullText             string `json:"full_text"`
+	CreatedAt            string `json:"created_at"`
+	InReplyToStatusIDStr string `json:"in_reply_to_status_id_str"`
+	Retweeted            bool   `json:"retweeted"`
+	Entities             struct {
+		URLs []struct {
+			URL         string `json:"url"`
+			ExpandedURL string `json:"expanded_url"`
+		} `json:"urls"`
+	} `json:"entities"`
+}
+
+func parseTweets(data []byte) ([]Post, error) {
+	// newer archives wrap every tweet in {"tweet": ...}, older ones don't
+	entries := []struct {
+		Tweet *tweet `json:"tweet"`
+		tweet
+	}{}
+	err := json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, ErrUnrecognizedArchive
+	}
+
+	posts := []Post{}
+	for _, entry := range entries {
+		t := entry.tweet
+		if entry.Tweet != nil {
+			t = *entry.Tweet
+		}
+		if t.IDStr == "" {
+			continue
+		}
+
+		// links are shortened to t.co in the text, the real address is kept alongside
+		body := html.UnescapeString(t.FullText)
+		for _, link := range t.Entities.URLs {
+			if link.URL != "" && link.ExpandedURL != "" {
+				body = strings.ReplaceAll(body, link.URL, link.ExpandedURL)
+			}
+		}
+
+		post := Post{
+			ExternalID:        t.IDStr,
+			ReplyToExternalID: t.InReplyToStatusIDStr,
+			Body:              strings.TrimSpace(body),
+		}
+
+		postedAt, err := time.Parse(time.RubyDate, t.CreatedAt)
+		if err != nil {
+			post.SkipReason = "post has no valid timestamp"
+		} else {
+			post.PostedAt = postedAt.UTC()
+		}
+		if t.Retweeted || strings.HasPrefix(t.FullText, "RT @") {
+			post.SkipReason = "reposts aren't imported"
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+type outbox struct {
+	OrderedItems []struct {
+		Type      string          `json:"type"`
+		Published string          `json:"published"`
+		Object    json.RawMessage `json:"object"`
+	} `json:"orderedItems"`
+}
+
+type note struct {
+	ID        string   `json:"id"`
+	Published string   `json:"published"`
+	Content   string   `json:"content"`
+	Summary   string   `json:"summary"`
+	InReplyTo string   `json:"inReplyTo"`
+	To        []string `json:"to"`
+	CC        []string `json:"cc"`
+}
+
+func parseOutbox(data []byte) ([]Post, error) {
+	collection := outbox{}
+	err := json.Unmarshal(data, &collection)
+	if err != nil || collection.OrderedItems == nil {
+		return nil, ErrUnrecognizedArchive
+	}
+
+	posts := []Post{}
+	for _, activity := range collection.OrderedItems {
+		// boosts only point at someone else's post, the id is all there is
+		if activity.Type == "Announce" {
+			id := ""
+			json.Unmarshal(activity.Object, &id)
+			postedAt, _ := time.Parse(time.RFC3339, activity.Published)
+			posts = append(posts, Post{ExternalID: id, PostedAt: postedAt.UTC(), SkipReason: "reposts aren't imported"})
+			continue
+		}
+		if activity.Type != "Create" {
+			continue
+		}
+
+		n := note{}
+		err := json.Unmarshal(activity.Object, &n)
+		if err != nil || n.ID == "" {
+			continue
+		}
+
+		body := htmlToText(n.Content)
+		if n.Summary != "" {
+			body = "CW: " + htmlToText(n.Summary) + "\n\n" + body
+		}
+
+		post := Post{
+			ExternalID:        n.ID,
+			ReplyToExternalID: n.InReplyTo,
+			Body:              body,
+		}
+
+		postedAt, err := time.Parse(time.RFC3339, n.Published)
+		if err != nil {
+			post.SkipReason = "post has no valid timestamp"
+		} else {
+			post.PostedAt = postedAt.UTC()
+		}
+		// every chirp is public, so followers-only posts and direct messages stay behind
+		if !slices.Contains(n.To, activityStreamsPublic) && !slices.Contains(n.CC, activityStreamsPublic) {
+			post.SkipReason = "only public posts are imported"
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// helper that turns the HTML Mastodon stores posts as back into plain text
+func htmlToText(content string) string {
+	content = paragraphBreak.ReplaceAllString(content, "\n\n")
+	content = lineBreakTags.ReplaceAllString(content, "\n")
+	content = htmlTags.ReplaceAllString(content, "")
+
+	content = strings.ReplaceAll(html.UnescapeString(content), "\u00a0", " ")
+
+	return strings.TrimSpace(content)
+}
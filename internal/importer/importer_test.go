@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTweets(t *testing.T) {
+	archive := `window.YTD.tweets.part0 = [
+  {
+    "tweet" : {
+      "id_str" : "2",
+      "full_text" : "replying to myself &amp; reading https://t.co/abc",
+      "created_at" : "Thu Oct 11 09:00:00 +0000 2018",
+      "in_reply_to_status_id_str" : "1",
+      "entities" : { "urls" : [ { "url" : "https://t.co/abc", "expanded_url" : "https://example.com/post" } ] }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "3",
+      "full_text" : "RT @someone: not mine",
+      "created_at" : "Fri Oct 12 09:00:00 +0000 2018"
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "1",
+      "full_text" : "first post",
+      "created_at" : "Wed Oct 10 20:19:24 +0000 2018"
+    }
+  }
+]`
+
+	source, posts, err := Parse([]byte(archive))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if source != SourceTwitter {
+		t.Errorf("source = %q, want %q", source, SourceTwitter)
+	}
+	if len(posts) != 3 {
+		t.Fatalf("got %d posts, want 3", len(posts))
+	}
+
+	if posts[0].ExternalID != "1" || !posts[0].PostedAt.Equal(time.Date(2018, time.October, 10, 20, 19, 24, 0, time.UTC)) {
+		t.Errorf("posts aren't oldest first, got %+v", posts[0])
+	}
+	if posts[1].Body != "replying to myself & reading https://example.com/post" {
+		t.Errorf("Body = %q", posts[1].Body)
+	}
+	if posts[1].ReplyToExternalID != "1" {
+		t.Errorf("ReplyToExternalID = %q, want %q", posts[1].ReplyToExternalID, "1")
+	}
+	if posts[2].SkipReason == "" {
+		t.Errorf("retweet wasn't skipped")
+	}
+}
+
+func TestParseOutbox(t *testing.T) {
+	archive := `{
+  "@context": "https://www.w3.org/ns/activitystreams",
+  "type": "OrderedCollection",
+  "orderedItems": [
+    {
+      "type": "Create",
+      "object": {
+        "id": "https://social.example/users/me/statuses/1",
+        "published": "2022-11-05T10:00:00Z",
+        "content": "<p>Hello <span class=\"h-card\"><a href=\"https://social.example/@friend\">@<span>friend</span></a></span></p><p>second&nbsp;line<br />third</p>",
+        "to": ["https://www.w3.org/ns/activitystreams#Public"]
+      }
+    },
+    {
+      "type": "Create",
+      "object": {
+        "id": "https://social.example/users/me/statuses/2",
+        "published": "2022-11-06T10:00:00Z",
+        "content": "<p>just for followers</p>",
+        "to": ["https://social.example/users/me/followers"]
+      }
+    },
+    {
+      "type": "Announce",
+      "published": "2022-11-07T10:00:00Z",
+      "object": "https://elsewhere.example/statuses/9"
+    }
+  ]
+}`
+
+	source, posts, err := Parse([]byte(archive))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if source != SourceMastodon {
+		t.Errorf("source = %q, want %q", source, SourceMastodon)
+	}
+	if len(posts) != 3 {
+		t.Fatalf("got %d posts, want 3", len(posts))
+	}
+
+	if want := "Hello @friend\n\nsecond line\nthird"; posts[0].Body != want {
+		t.Errorf("Body = %q, want %q", posts[0].Body, want)
+	}
+	if posts[0].SkipReason != "" {
+		t.Errorf("public post was skipped: %q", posts[0].SkipReason)
+	}
+	if posts[1].SkipReason == "" {
+		t.Errorf("followers-only post wasn't skipped")
+	}
+	if posts[2].SkipReason == "" {
+		t.Errorf("boost wasn't skipped")
+	}
+}
+
+func TestParseRejectsOtherFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+		wantErr error
+	}{
+		{name: "Plain text", archive: "hello", wantErr: ErrUnrecognizedArchive},
+		{name: "Other JSON", archive: `{"users": []}`, wantErr: ErrUnrecognizedArchive},
+		{name: "Empty archive", archive: "window.YTD.tweets.part0 = []", wantErr: ErrNoPosts},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := Parse([]byte(tc.archive))
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package keywords
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+const MaxLength = 100
+
+var ErrInvalidKeyword = errors.New("keyword must be between 1 and 100 characters")
+
+// Normalize lowercases a muted keyword and collapses its whitespace so the same phrase is only stored once
+func Normalize(keyword string) (string, error) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(keyword)), " ")
+	if normalized == "" || normalized == "#" || len(normalized) > MaxLength {
+		return "", ErrInvalidKeyword
+	}
+
+	return normalized, nil
+}
+
+// Match reports whether body contains any of the normalized keywords as whole words, a plain keyword also
+// matches its hashtag while a keyword starting with # only matches the hashtag
+func Match(body string, keywords []string) bool {
+	if len(keywords) == 0 {
+		return false
+	}
+
+	words := tokenize(body)
+	for _, keyword := range keywords {
+		phrase := tokenize(keyword)
+		if len(phrase) == 0 {
+			continue
+		}
+		for i := 0; i+len(phrase) <= len(words); i++ {
+			if matchesAt(words[i:i+len(phrase)], phrase) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchesAt(words, phrase []string) bool {
+	for i, part := range phrase {
+		word := words[i]
+		if word != part && !(!strings.HasPrefix(part, "#") && word == "#"+part) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tokenize splits text into lowercased words with surrounding punctuation removed, a leading # is kept
+func tokenize(text string) []string {
+	words := []string{}
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		hashtag := strings.HasPrefix(field, "#")
+		word := strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		if word == "" {
+			continue
+		}
+		if hashtag {
+			word = "#" + word
+		}
+		words = append(words, word)
+	}
+
+	return words
+}
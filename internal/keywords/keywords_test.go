@@ -0,0 +1,54 @@
+package keywords
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		keyword string
+		want    string
+		wantErr bool
+	}{
+		{keyword: "Spoilers", want: "spoilers"},
+		{keyword: "  Game   of Thrones ", want: "game of thrones"},
+		{keyword: "#WWDC", want: "#wwdc"},
+		{keyword: "   ", wantErr: true},
+		{keyword: "#", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		got, err := Normalize(tc.keyword)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("Normalize(%q) error = %v, wantErr %v", tc.keyword, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tc.keyword, got, tc.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		keywords []string
+		want     bool
+	}{
+		{name: "whole word", body: "No spoilers please!", keywords: []string{"spoilers"}, want: true},
+		{name: "part of a word", body: "The spoilersport arrived", keywords: []string{"spoilers"}, want: false},
+		{name: "phrase", body: "Watching Game of Thrones tonight", keywords: []string{"game of thrones"}, want: true},
+		{name: "phrase out of order", body: "thrones of game", keywords: []string{"game of thrones"}, want: false},
+		{name: "plain keyword matches hashtag", body: "Loving #WWDC so far", keywords: []string{"wwdc"}, want: true},
+		{name: "hashtag keyword skips plain word", body: "Loving wwdc so far", keywords: []string{"#wwdc"}, want: false},
+		{name: "hashtag keyword", body: "Loving #wwdc.", keywords: []string{"#wwdc"}, want: true},
+		{name: "no keywords", body: "anything", keywords: nil, want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Match(tc.body, tc.keywords); got != tc.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tc.body, tc.keywords, got, tc.want)
+			}
+		})
+	}
+}
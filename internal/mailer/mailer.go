@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+var ErrInvalidHeader = errors.New("email headers can't contain line breaks")
+
+// Sender delivers plain text emails
+type Sender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// LogSender writes emails to the log instead of sending them, for running without an SMTP server
+type LogSender struct {
+	Logger *log.Logger
+}
+
+func (s LogSender) Send(ctx context.Context, to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return ErrInvalidHeader
+	}
+
+	logger := s.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("email to %s: %s\n%s", to, subject, body)
+
+	return nil
+}
+
+// SMTPSender sends emails through an SMTP server, credentials are only sent once the connection is encrypted
+// unless the server is on localhost
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPSender(addr, from, username, password string) *SMTPSender {
+	sender := &SMTPSender{addr: addr, from: from}
+	if username != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return sender
+}
+
+func (s *SMTPSender) Send(ctx context.Context, to, subject, body string) error {
+	msg, err := buildMessage(s.from, to, subject, body)
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{to}, msg)
+}
+
+func buildMessage(from, to, subject, body string) ([]byte, error) {
+	if strings.ContainsAny(from+to+subject, "\r\n") {
+		return nil, ErrInvalidHeader
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	return []byte(msg.String()), nil
+}
@@ -0,0 +1,113 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts a single message and hands back everything sent after DATA
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		write := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		write("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				write("250 localhost")
+			case strings.HasPrefix(command, "DATA"):
+				write("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- data.String()
+				write("250 queued")
+			case strings.HasPrefix(command, "QUIT"):
+				write("221 bye")
+				return
+			default:
+				write("250 ok")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestSMTPSenderSends(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+
+	sender := NewSMTPSender(addr, "noreply@chirpy.test", "", "")
+	err := sender.Send(context.Background(), "user@example.com", "Confirm your email", "Line one\nLine two")
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	data := <-received
+	for _, want := range []string{"From: noreply@chirpy.test\r\n", "To: user@example.com\r\n", "Subject: Confirm your email\r\n", "\r\n\r\nLine one\r\nLine two\r\n"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Expected message to contain %q, got %q", want, data)
+		}
+	}
+}
+
+func TestSendersRejectHeaderInjection(t *testing.T) {
+	senders := []Sender{
+		LogSender{Logger: log.New(&bytes.Buffer{}, "", 0)},
+		NewSMTPSender("127.0.0.1:1", "noreply@chirpy.test", "", ""),
+	}
+
+	for _, sender := range senders {
+		err := sender.Send(context.Background(), "user@example.com\r\nBcc: everyone@example.com", "Hi", "body")
+		if !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("%T: Send() error = %v, want %v", sender, err, ErrInvalidHeader)
+		}
+	}
+}
+
+func TestLogSenderLogs(t *testing.T) {
+	var buf bytes.Buffer
+	sender := LogSender{Logger: log.New(&buf, "", 0)}
+
+	err := sender.Send(context.Background(), "user@example.com", "Confirm your email", "token: abc")
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "user@example.com") || !strings.Contains(buf.String(), "token: abc") {
+		t.Errorf("Expected email in log output, got %q", buf.String())
+	}
+}
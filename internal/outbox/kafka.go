@@ -0,0 +1,83 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// KafkaRESTSink publishes through a Kafka REST Proxy (v2 API), records are keyed by aggregate ID so every
+// event for the same aggregate lands on the same partition and stays in order
+type KafkaRESTSink struct {
+	baseURL string
+	topic   string
+	client  *http.Client
+}
+
+func NewKafkaRESTSink(baseURL, topic string, client *http.Client) *KafkaRESTSink {
+	return &KafkaRESTSink{
+		baseURL: baseURL,
+		topic:   topic,
+		client:  client,
+	}
+}
+
+func (k *KafkaRESTSink) Publish(ctx context.Context, msg Message) error {
+	type record struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+	type request struct {
+		Records []record `json:"records"`
+	}
+	type response struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+
+	body, err := json.Marshal(request{
+		Records: []record{{Key: msg.AggregateID, Value: msg.Payload}},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := k.baseURL + "/topics/" + url.PathEscape(k.topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka rest proxy responded with %d: %s", resp.StatusCode, detail)
+	}
+
+	// the proxy answers 200 even when a record was rejected, the real outcome is per offset
+	result := response{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return err
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka rejected record: %s", offset.Error)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSSink publishes to a NATS server using the plain text client protocol, each message is sent with a
+// Nats-Msg-Id header so JetStream can drop the duplicates at-least-once delivery produces
+type NATSSink struct {
+	addr          string
+	subjectPrefix string
+	timeout       time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewNATSSink(addr, subjectPrefix string) *NATSSink {
+	return &NATSSink{
+		addr:          addr,
+		subjectPrefix: subjectPrefix,
+		timeout:       5 * time.Second,
+	}
+}
+
+func (n *NATSSink) Publish(ctx context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	err := n.publish(ctx, msg)
+	if err != nil {
+		// drop the connection so the next publish starts from a clean handshake
+		n.close()
+	}
+
+	return err
+}
+
+func (n *NATSSink) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.close()
+}
+
+func (n *NATSSink) publish(ctx context.Context, msg Message) error {
+	if n.conn == nil {
+		err := n.connect(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(n.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	n.conn.SetDeadline(deadline)
+
+	subject := n.subjectPrefix + "." + msg.Type
+	headers := fmt.Sprintf("NATS/1.0\r\nNats-Msg-Id: %s\r\n\r\n", msg.ID)
+	frame := fmt.Sprintf("HPUB %s %d %d\r\n%s%s\r\n", subject, len(headers), len(headers)+len(msg.Payload), headers, msg.Payload)
+
+	// the PING makes the server answer once it has processed the HPUB, so a PONG means it was accepted
+	_, err := n.conn.Write([]byte(frame + "PING\r\n"))
+	if err != nil {
+		return err
+	}
+
+	for {
+		line, err := n.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			_, err = n.conn.Write([]byte("PONG\r\n"))
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", line)
+		}
+	}
+}
+
+func (n *NATSSink) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: n.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(n.timeout))
+	reader := bufio.NewReader(conn)
+
+	info, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(info, "INFO ") {
+		conn.Close()
+		return errors.New("nats: server did not send INFO")
+	}
+
+	_, err = conn.Write([]byte(`CONNECT {"verbose":false,"pedantic":false,"headers":true,"name":"chirpy-outbox"}` + "\r\n"))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	n.conn = conn
+	n.reader = reader
+
+	return nil
+}
+
+func (n *NATSSink) close() error {
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+	n.reader = nil
+
+	return err
+}
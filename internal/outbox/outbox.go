@@ -0,0 +1,124 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxAttempts is how many times a message is tried before it's given up on and marked dead, with RetryDelay
+	// that's a little over an hour of a sink being down
+	MaxAttempts = 20
+
+	firstRetryDelay = time.Second
+	maxRetryDelay   = 5 * time.Minute
+)
+
+// Message is one row of the outbox, Sequence is the row's position so the relay can keep order
+type Message struct {
+	Sequence      int64
+	ID            string
+	AggregateType string
+	AggregateID   string
+	Type          string
+	Payload       []byte
+}
+
+// Sink is somewhere the relay publishes messages to, a message counts as published once every sink accepted it
+// so sinks have to tolerate seeing the same message ID more than once
+type Sink interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+type Result struct {
+	Sequence int64
+	Err      error
+}
+
+var ErrHeldBack = errors.New("outbox: an earlier message for this aggregate has not been published")
+
+// Dispatch publishes messages in sequence order, once a message for an aggregate fails the rest of
+// that aggregate's messages are held back so consumers never see them out of order
+func Dispatch(ctx context.Context, sink Sink, messages []Message) []Result {
+	results := make([]Result, 0, len(messages))
+	blocked := map[string]bool{}
+
+	for _, msg := range messages {
+		key := msg.AggregateType + "/" + msg.AggregateID
+		if blocked[key] {
+			results = append(results, Result{Sequence: msg.Sequence, Err: ErrHeldBack})
+			continue
+		}
+
+		err := sink.Publish(ctx, msg)
+		if err != nil {
+			blocked[key] = true
+		}
+		results = append(results, Result{Sequence: msg.Sequence, Err: err})
+	}
+
+	return results
+}
+
+// RetryDelay returns how long to wait before trying a message again after the given number of failed attempts,
+// doubling each time up to 5 minutes
+func RetryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+// Fanout publishes to several sinks and only succeeds if all of them do
+type Fanout []Sink
+
+func (f Fanout) Publish(ctx context.Context, msg Message) error {
+	for _, sink := range f {
+		err := sink.Publish(ctx, msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type Handler func(ctx context.Context, msg Message) error
+
+// Bus is an in-process sink that hands messages to handlers subscribed to their type
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]Handler{}}
+}
+
+// Subscribe registers a handler for a message type, "*" receives every message
+func (b *Bus) Subscribe(msgType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[msgType] = append(b.handlers[msgType], handler)
+}
+
+func (b *Bus) Publish(ctx context.Context, msg Message) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler{}, b.handlers[msg.Type]...), b.handlers["*"]...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		err := handler(ctx, msg)
+		if err != nil {
+			return fmt.Errorf("outbox: %s handler failed: %w", msg.Type, err)
+		}
+	}
+
+	return nil
+}
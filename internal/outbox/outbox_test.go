@@ -0,0 +1,188 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type recordingSink struct {
+	published []int64
+	failOn    map[int64]bool
+}
+
+func (r *recordingSink) Publish(ctx context.Context, msg Message) error {
+	if r.failOn[msg.Sequence] {
+		return errors.New("sink unavailable")
+	}
+	r.published = append(r.published, msg.Sequence)
+	return nil
+}
+
+func TestDispatchHoldsBackAggregateAfterFailure(t *testing.T) {
+	sink := &recordingSink{failOn: map[int64]bool{2: true}}
+	messages := []Message{
+		{Sequence: 1, AggregateType: "chirp", AggregateID: "a"},
+		{Sequence: 2, AggregateType: "chirp", AggregateID: "b"},
+		{Sequence: 3, AggregateType: "chirp", AggregateID: "a"},
+		{Sequence: 4, AggregateType: "chirp", AggregateID: "b"},
+		{Sequence: 5, AggregateType: "user", AggregateID: "b"},
+	}
+
+	results := Dispatch(context.Background(), sink, messages)
+
+	if fmt.Sprint(sink.published) != "[1 3 5]" {
+		t.Errorf("Expected messages 1, 3 and 5 to be published, got %v", sink.published)
+	}
+	if results[1].Err == nil || errors.Is(results[1].Err, ErrHeldBack) {
+		t.Errorf("Expected message 2 to report the sink error, got %v", results[1].Err)
+	}
+	if !errors.Is(results[3].Err, ErrHeldBack) {
+		t.Errorf("Expected message 4 to be held back, got %v", results[3].Err)
+	}
+}
+
+func TestRetryDelayGrowsAndCaps(t *testing.T) {
+	if delay := RetryDelay(1); delay != firstRetryDelay {
+		t.Errorf("Expected first retry after %v, got %v", firstRetryDelay, delay)
+	}
+	if delay := RetryDelay(3); delay != 4*firstRetryDelay {
+		t.Errorf("Expected third retry after %v, got %v", 4*firstRetryDelay, delay)
+	}
+	if delay := RetryDelay(MaxAttempts); delay != maxRetryDelay {
+		t.Errorf("Expected retries to be capped at %v, got %v", maxRetryDelay, delay)
+	}
+}
+
+func TestBusDeliversToSubscribers(t *testing.T) {
+	bus := NewBus()
+	received := []string{}
+	bus.Subscribe("chirp.created", func(ctx context.Context, msg Message) error {
+		received = append(received, "typed:"+msg.ID)
+		return nil
+	})
+	bus.Subscribe("*", func(ctx context.Context, msg Message) error {
+		received = append(received, "all:"+msg.ID)
+		return nil
+	})
+
+	bus.Publish(context.Background(), Message{ID: "1", Type: "chirp.created"})
+	bus.Publish(context.Background(), Message{ID: "2", Type: "user.created"})
+
+	if strings.Join(received, ",") != "typed:1,all:1,all:2" {
+		t.Errorf("Unexpected deliveries: %v", received)
+	}
+}
+
+func TestBusReportsHandlerFailure(t *testing.T) {
+	bus := NewBus()
+	bus.Subscribe("*", func(ctx context.Context, msg Message) error {
+		return errors.New("database down")
+	})
+
+	err := bus.Publish(context.Background(), Message{ID: "1", Type: "chirp.created"})
+	if err == nil {
+		t.Error("Expected handler error to fail the publish, got nil")
+	}
+}
+
+// a stand-in NATS server that speaks just enough of the protocol for a publisher
+func TestNATSSinkPublishes(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not listen: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+
+		conn.Write([]byte(`INFO {"server_id":"test","headers":true}` + "\r\n"))
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "HPUB":
+				total, _ := strconv.Atoi(fields[3])
+				frame := make([]byte, total+2)
+				io.ReadFull(reader, frame)
+				received <- fields[1] + "\n" + string(frame[:total])
+			case "PING":
+				conn.Write([]byte("PONG\r\n"))
+			}
+		}
+	}()
+
+	sink := NewNATSSink(listener.Addr().String(), "chirpy")
+	defer sink.Close()
+
+	err = sink.Publish(context.Background(), Message{ID: "event-1", Type: "chirp.created", Payload: []byte(`{"id":"event-1"}`)})
+	if err != nil {
+		t.Fatalf("Did not expect error, got: %v", err)
+	}
+
+	frame := <-received
+	if !strings.HasPrefix(frame, "chirpy.chirp.created\n") {
+		t.Errorf("Expected subject chirpy.chirp.created, got %q", frame)
+	}
+	if !strings.Contains(frame, "Nats-Msg-Id: event-1") || !strings.HasSuffix(frame, `{"id":"event-1"}`) {
+		t.Errorf("Expected message ID header and payload, got %q", frame)
+	}
+}
+
+func TestKafkaRESTSinkKeysByAggregate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/topics/chirpy-events" {
+			t.Errorf("Expected topic path, got %s", req.URL.Path)
+		}
+		body := struct {
+			Records []struct {
+				Key   string          `json:"key"`
+				Value json.RawMessage `json:"value"`
+			} `json:"records"`
+		}{}
+		json.NewDecoder(req.Body).Decode(&body)
+		if len(body.Records) != 1 || body.Records[0].Key != "chirp-1" {
+			t.Errorf("Expected one record keyed by aggregate, got %+v", body.Records)
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":12}]}`))
+	}))
+	defer server.Close()
+
+	sink := NewKafkaRESTSink(server.URL, "chirpy-events", server.Client())
+	err := sink.Publish(context.Background(), Message{AggregateID: "chirp-1", Payload: []byte(`{}`)})
+	if err != nil {
+		t.Fatalf("Did not expect error, got: %v", err)
+	}
+}
+
+func TestKafkaRESTSinkFailsOnRejectedRecord(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"offsets":[{"error_code":50002,"error":"broker unavailable"}]}`))
+	}))
+	defer server.Close()
+
+	sink := NewKafkaRESTSink(server.URL, "chirpy-events", server.Client())
+	err := sink.Publish(context.Background(), Message{AggregateID: "chirp-1", Payload: []byte(`{}`)})
+	if err == nil {
+		t.Error("Expected rejected record to fail the publish, got nil")
+	}
+}
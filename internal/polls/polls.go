@@ -0,0 +1,91 @@
+package polls
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MinOptions      = 2
+	MaxOptions      = 4
+	MaxOptionLength = 50
+	MinDuration     = 5 * time.Minute
+	MaxDuration     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrOptionCount    = errors.New("polls need 2 to 4 options")
+	ErrOptionLength   = errors.New("poll options must be 1 to 50 characters")
+	ErrDuplicate      = errors.New("poll options must all be different")
+	ErrDuration       = errors.New("polls must run for between 5 minutes and 7 days")
+	ErrEmptyBallot    = errors.New("pick at least one option")
+	ErrSingleChoice   = errors.New("this poll only allows one option")
+	ErrUnknownOption  = errors.New("that option isn't part of this poll")
+	ErrDuplicateVotes = errors.New("each option can only be picked once")
+)
+
+// ValidateOptions checks the options a poll is being created with and returns them trimmed, options differing
+// only in case count as the same
+func ValidateOptions(options []string) ([]string, error) {
+	if len(options) < MinOptions || len(options) > MaxOptions {
+		return nil, ErrOptionCount
+	}
+
+	cleaned := []string{}
+	seen := []string{}
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > MaxOptionLength {
+			return nil, ErrOptionLength
+		}
+		if slices.Contains(seen, strings.ToLower(option)) {
+			return nil, ErrDuplicate
+		}
+		seen = append(seen, strings.ToLower(option))
+		cleaned = append(cleaned, option)
+	}
+
+	return cleaned, nil
+}
+
+// ValidateDuration checks how long a poll is set to stay open
+func ValidateDuration(duration time.Duration) error {
+	if duration < MinDuration || duration > MaxDuration {
+		return ErrDuration
+	}
+
+	return nil
+}
+
+// ValidateBallot checks the options someone is voting for are all on the poll, single choice polls take
+// exactly one
+func ValidateBallot(picked, options []uuid.UUID, multipleChoice bool) error {
+	if len(picked) == 0 {
+		return ErrEmptyBallot
+	}
+	if !multipleChoice && len(picked) > 1 {
+		return ErrSingleChoice
+	}
+
+	for i, optionID := range picked {
+		if !slices.Contains(options, optionID) {
+			return ErrUnknownOption
+		}
+		if slices.Contains(picked[:i], optionID) {
+			return ErrDuplicateVotes
+		}
+	}
+
+	return nil
+}
+
+// ShowResults reports whether a viewer gets to see the tallies, they're kept from anyone who hasn't voted yet
+// so early results don't sway them. The author can always see how their poll is going
+func ShowResults(voted, closed, isAuthor bool) bool {
+	return voted || closed || isAuthor
+}
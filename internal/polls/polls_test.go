@@ -0,0 +1,98 @@
+package polls
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		want    []string
+		wantErr error
+	}{
+		{"two options", []string{"Yes", "No"}, []string{"Yes", "No"}, nil},
+		{"trimmed", []string{" Cats ", "Dogs\n"}, []string{"Cats", "Dogs"}, nil},
+		{"four options", []string{"a", "b", "c", "d"}, []string{"a", "b", "c", "d"}, nil},
+		{"one option", []string{"Yes"}, nil, ErrOptionCount},
+		{"five options", []string{"a", "b", "c", "d", "e"}, nil, ErrOptionCount},
+		{"blank option", []string{"Yes", "  "}, nil, ErrOptionLength},
+		{"long option", []string{"Yes", strings.Repeat("n", MaxOptionLength+1)}, nil, ErrOptionLength},
+		{"duplicate in another case", []string{"Yes", "yes"}, nil, ErrDuplicate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateOptions(tt.options)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateOptions(%q) error = %v, want %v", tt.options, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ValidateOptions(%q) = %q, want %q", tt.options, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		wantErr  error
+	}{
+		{MinDuration, nil},
+		{24 * time.Hour, nil},
+		{MaxDuration, nil},
+		{time.Minute, ErrDuration},
+		{MaxDuration + time.Second, ErrDuration},
+	}
+
+	for _, tt := range tests {
+		err := ValidateDuration(tt.duration)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidateDuration(%v) = %v, want %v", tt.duration, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateBallot(t *testing.T) {
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+	options := []uuid.UUID{first, second, third}
+
+	tests := []struct {
+		name           string
+		picked         []uuid.UUID
+		multipleChoice bool
+		wantErr        error
+	}{
+		{"single choice", []uuid.UUID{second}, false, nil},
+		{"multiple choice", []uuid.UUID{first, third}, true, nil},
+		{"nothing picked", []uuid.UUID{}, true, ErrEmptyBallot},
+		{"two on single choice", []uuid.UUID{first, second}, false, ErrSingleChoice},
+		{"option from another poll", []uuid.UUID{uuid.New()}, false, ErrUnknownOption},
+		{"same option twice", []uuid.UUID{first, first}, true, ErrDuplicateVotes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBallot(tt.picked, options, tt.multipleChoice)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateBallot() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShowResults(t *testing.T) {
+	if ShowResults(false, false, false) {
+		t.Error("results shown to someone who hasn't voted on an open poll")
+	}
+	if !ShowResults(true, false, false) || !ShowResults(false, true, false) || !ShowResults(false, false, true) {
+		t.Error("results hidden from a voter, on a closed poll or from the author")
+	}
+}
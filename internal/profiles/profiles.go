@@ -0,0 +1,121 @@
+package profiles
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MinHandleLength      = 3
+	MaxHandleLength      = 15
+	MaxDisplayNameLength = 50
+	MaxBioLength         = 160
+	MaxLocationLength    = 30
+	MaxURLLength         = 200
+)
+
+var (
+	ErrInvalidHandle  = errors.New("handles must be 3 to 15 letters, numbers or underscores")
+	ErrReservedHandle = errors.New("that handle is reserved")
+)
+
+// handles that would be confused with the site itself or its routes
+var reservedHandles = []string{
+	"admin",
+	"administrator",
+	"api",
+	"chirpy",
+	"help",
+	"me",
+	"moderator",
+	"root",
+	"settings",
+	"support",
+	"system",
+}
+
+// Fields are the parts of a profile the user writes themselves
+type Fields struct {
+	DisplayName string
+	Bio         string
+	Location    string
+	Website     string
+	AvatarURL   string
+}
+
+// NormalizeHandle strips the @ people tend to type in front of a handle
+func NormalizeHandle(handle string) string {
+	return strings.TrimPrefix(strings.TrimSpace(handle), "@")
+}
+
+// ValidateHandle checks a handle someone wants to take, the case they type is kept but uniqueness ignores it
+func ValidateHandle(handle string) error {
+	if len(handle) < MinHandleLength || len(handle) > MaxHandleLength {
+		return ErrInvalidHandle
+	}
+	for _, r := range handle {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return ErrInvalidHandle
+		}
+	}
+	if slices.Contains(reservedHandles, strings.ToLower(handle)) {
+		return ErrReservedHandle
+	}
+
+	return nil
+}
+
+// ValidateFields checks the free text fields fit and that links are absolute http or https URLs
+func ValidateFields(fields Fields) error {
+	limits := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{name: "display_name", value: fields.DisplayName, max: MaxDisplayNameLength},
+		{name: "bio", value: fields.Bio, max: MaxBioLength},
+		{name: "location", value: fields.Location, max: MaxLocationLength},
+		{name: "website", value: fields.Website, max: MaxURLLength},
+		{name: "avatar_url", value: fields.AvatarURL, max: MaxURLLength},
+	}
+	for _, limit := range limits {
+		if utf8.RuneCountInString(limit.value) > limit.max {
+			return fmt.Errorf("%s can be at most %d characters", limit.name, limit.max)
+		}
+	}
+
+	for name, link := range map[string]string{"website": fields.Website, "avatar_url": fields.AvatarURL} {
+		if link == "" {
+			continue
+		}
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("%s must be an absolute http or https URL", name)
+		}
+	}
+
+	return nil
+}
+
+// Mentions pulls the @handles out of a chirp in the order they appear, without duplicates
+func Mentions(body string) []string {
+	mentions := []string{}
+	for _, word := range strings.Fields(body) {
+		if !strings.HasPrefix(word, "@") {
+			continue
+		}
+		handle := strings.TrimRight(word[1:], ".,!?:;'\")")
+		if ValidateHandle(handle) == ErrInvalidHandle {
+			continue
+		}
+		if !slices.ContainsFunc(mentions, func(mention string) bool { return strings.EqualFold(mention, handle) }) {
+			mentions = append(mentions, handle)
+		}
+	}
+
+	return mentions
+}
@@ -0,0 +1,65 @@
+package profiles
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateHandle(t *testing.T) {
+	tests := []struct {
+		handle  string
+		wantErr error
+	}{
+		{handle: "chirper_42"},
+		{handle: "Boot_Dev"},
+		{handle: "ab", wantErr: ErrInvalidHandle},
+		{handle: "this_is_way_too_long", wantErr: ErrInvalidHandle},
+		{handle: "no-dashes", wantErr: ErrInvalidHandle},
+		{handle: "émile", wantErr: ErrInvalidHandle},
+		{handle: "Admin", wantErr: ErrReservedHandle},
+	}
+
+	for _, tc := range tests {
+		err := ValidateHandle(tc.handle)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("ValidateHandle(%q) = %v, want %v", tc.handle, err, tc.wantErr)
+		}
+	}
+}
+
+func TestNormalizeHandle(t *testing.T) {
+	if got := NormalizeHandle(" @chirper "); got != "chirper" {
+		t.Errorf("NormalizeHandle() = %q, want %q", got, "chirper")
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  Fields
+		wantErr bool
+	}{
+		{name: "empty", fields: Fields{}},
+		{name: "valid", fields: Fields{DisplayName: "Chirper", Bio: "I chirp", Website: "https://example.com", AvatarURL: "http://example.com/a.png"}},
+		{name: "bio too long", fields: Fields{Bio: strings.Repeat("a", MaxBioLength+1)}, wantErr: true},
+		{name: "relative website", fields: Fields{Website: "example.com"}, wantErr: true},
+		{name: "javascript avatar", fields: Fields{AvatarURL: "javascript:alert(1)"}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		err := ValidateFields(tc.fields)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: ValidateFields() error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	got := Mentions("hey @alice and @Bob, have you met @alice? cc @x email@example.com @bob")
+	want := []string{"alice", "Bob"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Mentions() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,129 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Message is something published to a topic, ID lets clients pick up where they left off
+type Message struct {
+	Topic string          `json:"topic"`
+	ID    string          `json:"id"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Hub fans messages out to subscribers in this process, publishing never blocks so a subscriber that
+// falls too far behind is dropped and has to reconnect
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+type Subscription struct {
+	hub    *Hub
+	topics map[string]bool
+	ch     chan Message
+	lagged bool
+	closed bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe starts receiving messages for the given topics, buffer is how many messages can queue up before
+// the subscriber counts as lagging
+func (h *Hub) Subscribe(buffer int, topics ...string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{
+		hub:    h,
+		topics: map[string]bool{},
+		ch:     make(chan Message, buffer),
+	}
+	for _, topic := range topics {
+		sub.topics[topic] = true
+	}
+
+	if h.closed {
+		sub.closed = true
+		close(sub.ch)
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+
+	return sub
+}
+
+func (h *Hub) Publish(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if !sub.topics[msg.Topic] {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			sub.lagged = true
+			h.remove(sub)
+		}
+	}
+}
+
+// Close ends every subscription, used when the server shuts down so long lived connections can finish
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.ch)
+	delete(h.subs, sub)
+}
+
+// AddTopic and RemoveTopic change what an open subscription receives
+func (s *Subscription) AddTopic(topic string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.topics[topic] = true
+}
+
+func (s *Subscription) RemoveTopic(topic string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	delete(s.topics, topic)
+}
+
+// Messages is closed once the subscription ends, check Lagged to see if it was because it fell behind
+func (s *Subscription) Messages() <-chan Message {
+	return s.ch
+}
+
+func (s *Subscription) Lagged() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	return s.lagged
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s)
+}
@@ -0,0 +1,76 @@
+package pubsub
+
+import (
+	"testing"
+)
+
+func TestPublishOnlyReachesTopic(t *testing.T) {
+	hub := NewHub()
+	chirps := hub.Subscribe(4, "chirps")
+	other := hub.Subscribe(4, "notifications")
+
+	hub.Publish(Message{Topic: "chirps", ID: "1"})
+
+	select {
+	case msg := <-chirps.Messages():
+		if msg.ID != "1" {
+			t.Errorf("Expected message 1, got %q", msg.ID)
+		}
+	default:
+		t.Error("Expected chirps subscriber to receive the message")
+	}
+	select {
+	case msg := <-other.Messages():
+		t.Errorf("Did not expect notifications subscriber to receive %+v", msg)
+	default:
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(1, "chirps")
+
+	hub.Publish(Message{Topic: "chirps", ID: "1"})
+	hub.Publish(Message{Topic: "chirps", ID: "2"})
+
+	<-sub.Messages()
+	if _, ok := <-sub.Messages(); ok {
+		t.Error("Expected subscription to be closed after falling behind")
+	}
+	if !sub.Lagged() {
+		t.Error("Expected subscription to be marked as lagged")
+	}
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(1, "chirps")
+
+	hub.Close()
+	if _, ok := <-sub.Messages(); ok {
+		t.Error("Expected subscription to be closed with the hub")
+	}
+	if sub.Lagged() {
+		t.Error("Did not expect a closed hub to mark subscriptions as lagged")
+	}
+
+	late := hub.Subscribe(1, "chirps")
+	if _, ok := <-late.Messages(); ok {
+		t.Error("Expected subscribing to a closed hub to return a closed subscription")
+	}
+	sub.Close()
+}
+
+func TestTopicsCanChange(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(4)
+
+	sub.AddTopic("thread:1")
+	hub.Publish(Message{Topic: "thread:1", ID: "1"})
+	sub.RemoveTopic("thread:1")
+	hub.Publish(Message{Topic: "thread:1", ID: "2"})
+
+	if len(sub.Messages()) != 1 {
+		t.Errorf("Expected only the message sent while subscribed, got %d", len(sub.Messages()))
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts events per key in fixed windows, limits are passed per call so each user can be on a different plan
+type Limiter struct {
+	mu        sync.Mutex
+	window    time.Duration
+	buckets   map[string]bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	start time.Time
+	count int
+}
+
+func New(window time.Duration) *Limiter {
+	return &Limiter{
+		window:  window,
+		buckets: map[string]bucket{},
+	}
+}
+
+// Allow records an event for key and reports whether it is within limit for the current window
+func (l *Limiter) Allow(key string, limit int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.buckets[key]
+	if now.Sub(b.start) >= l.window {
+		b = bucket{start: now}
+	}
+	if now.Sub(l.lastPrune) >= l.window {
+		l.prune(now)
+	}
+	if b.count >= limit {
+		return false
+	}
+
+	b.count++
+	l.buckets[key] = b
+
+	return true
+}
+
+// prune drops windows that have ended so idle keys don't pile up forever
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.start) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
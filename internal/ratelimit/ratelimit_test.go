@@ -0,0 +1,26 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := New(time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("user", 3, now) {
+			t.Fatalf("Expected event %d to be allowed", i+1)
+		}
+	}
+	if limiter.Allow("user", 3, now) {
+		t.Error("Expected fourth event in the window to be denied")
+	}
+	if !limiter.Allow("other-user", 3, now) {
+		t.Error("Expected a different key to have its own window")
+	}
+	if !limiter.Allow("user", 3, now.Add(time.Minute)) {
+		t.Error("Expected event in the next window to be allowed")
+	}
+}
@@ -0,0 +1,82 @@
+package subscription
+
+import (
+	"fmt"
+	"time"
+)
+
+// Polka event names that affect a Chirpy Red subscription
+const (
+	EventUpgraded      = "user.upgraded"
+	EventRenewed       = "user.renewed"
+	EventCancelled     = "user.cancelled"
+	EventPaymentFailed = "user.payment_failed"
+	EventRefunded      = "user.refunded"
+)
+
+const (
+	StatusNone      = "none"
+	StatusActive    = "active"
+	StatusCancelled = "cancelled"
+	StatusPastDue   = "past_due"
+	StatusRefunded  = "refunded"
+	StatusExpired   = "expired"
+)
+
+// BillingPeriod is how long one payment keeps a user on Chirpy Red when Polka doesn't tell us the period end
+const BillingPeriod = 30 * 24 * time.Hour
+
+// PaymentGracePeriod keeps a user on Chirpy Red for a little while after a failed payment so Polka can retry the card
+const PaymentGracePeriod = 3 * 24 * time.Hour
+
+type State struct {
+	Status   string
+	RedUntil time.Time
+}
+
+func IsKnownEvent(event string) bool {
+	switch event {
+	case EventUpgraded, EventRenewed, EventCancelled, EventPaymentFailed, EventRefunded:
+		return true
+	}
+	return false
+}
+
+// Apply works out the subscription state after a Polka event, periodEnd is optional and wins over BillingPeriod when set.
+// now should be when the event was received rather than when it's processed, so periods don't drift when it's retried
+func Apply(current State, event string, now, periodEnd time.Time) (State, error) {
+	switch event {
+	case EventUpgraded:
+		if periodEnd.IsZero() {
+			periodEnd = now.Add(BillingPeriod)
+		}
+		return State{Status: StatusActive, RedUntil: periodEnd}, nil
+	case EventRenewed:
+		// a renewal extends whatever time is left, or starts fresh if the subscription already lapsed
+		if periodEnd.IsZero() {
+			start := now
+			if current.RedUntil.After(now) {
+				start = current.RedUntil
+			}
+			periodEnd = start.Add(BillingPeriod)
+		}
+		return State{Status: StatusActive, RedUntil: periodEnd}, nil
+	case EventCancelled:
+		// cancelling stops renewal, the user keeps what they already paid for
+		return State{Status: StatusCancelled, RedUntil: current.RedUntil}, nil
+	case EventPaymentFailed:
+		redUntil := current.RedUntil
+		if graceEnd := now.Add(PaymentGracePeriod); redUntil.Before(graceEnd) {
+			redUntil = graceEnd
+		}
+		return State{Status: StatusPastDue, RedUntil: redUntil}, nil
+	case EventRefunded:
+		return State{Status: StatusRefunded, RedUntil: now}, nil
+	default:
+		return State{}, fmt.Errorf("unknown subscription event %q", event)
+	}
+}
+
+func (s State) IsRed(now time.Time) bool {
+	return s.Status != StatusNone && s.Status != StatusRefunded && s.Status != StatusExpired && s.RedUntil.After(now)
+}
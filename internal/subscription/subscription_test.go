@@ -0,0 +1,94 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	active := State{Status: StatusActive, RedUntil: now.Add(10 * 24 * time.Hour)}
+	lapsed := State{Status: StatusExpired, RedUntil: now.Add(-24 * time.Hour)}
+
+	tests := []struct {
+		name      string
+		current   State
+		event     string
+		periodEnd time.Time
+		expected  State
+		isRed     bool
+	}{
+		{
+			name:     "Upgrade from nothing",
+			current:  State{Status: StatusNone},
+			event:    EventUpgraded,
+			expected: State{Status: StatusActive, RedUntil: now.Add(BillingPeriod)},
+			isRed:    true,
+		},
+		{
+			name:      "Upgrade with explicit period end",
+			current:   State{Status: StatusNone},
+			event:     EventUpgraded,
+			periodEnd: now.Add(time.Hour),
+			expected:  State{Status: StatusActive, RedUntil: now.Add(time.Hour)},
+			isRed:     true,
+		},
+		{
+			name:     "Renewal extends remaining time",
+			current:  active,
+			event:    EventRenewed,
+			expected: State{Status: StatusActive, RedUntil: active.RedUntil.Add(BillingPeriod)},
+			isRed:    true,
+		},
+		{
+			name:     "Renewal after lapse starts from now",
+			current:  lapsed,
+			event:    EventRenewed,
+			expected: State{Status: StatusActive, RedUntil: now.Add(BillingPeriod)},
+			isRed:    true,
+		},
+		{
+			name:     "Cancel keeps paid time",
+			current:  active,
+			event:    EventCancelled,
+			expected: State{Status: StatusCancelled, RedUntil: active.RedUntil},
+			isRed:    true,
+		},
+		{
+			name:     "Payment failure gives a grace period",
+			current:  State{Status: StatusActive, RedUntil: now},
+			event:    EventPaymentFailed,
+			expected: State{Status: StatusPastDue, RedUntil: now.Add(PaymentGracePeriod)},
+			isRed:    true,
+		},
+		{
+			name:     "Refund downgrades immediately",
+			current:  active,
+			event:    EventRefunded,
+			expected: State{Status: StatusRefunded, RedUntil: now},
+			isRed:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := Apply(tt.current, tt.event, now, tt.periodEnd)
+			if err != nil {
+				t.Fatalf("Did not expect error, got: %v", err)
+			}
+			if state.Status != tt.expected.Status || !state.RedUntil.Equal(tt.expected.RedUntil) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, state)
+			}
+			if state.IsRed(now) != tt.isRed {
+				t.Errorf("Expected IsRed %v, got %v", tt.isRed, state.IsRed(now))
+			}
+		})
+	}
+}
+
+func TestApplyUnknownEvent(t *testing.T) {
+	_, err := Apply(State{Status: StatusNone}, "user.exploded", time.Now(), time.Time{})
+	if err == nil {
+		t.Error("Expected error for unknown event, got nil")
+	}
+}
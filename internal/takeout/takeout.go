@@ -0,0 +1,217 @@
+package takeout
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Archive is everything exported for a user, it is written as JSON files for machines plus an HTML index
+// for people
+type Archive struct {
+	GeneratedAt time.Time
+	Profile     Profile
+	Chirps      []Chirp
+	Messages    []Message
+	Likes       []Reaction
+	Rechirps    []Reaction
+	Following   []Follow
+	Followers   []Follow
+	Sessions    []Session
+	Passkeys    []Passkey
+}
+
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Email       string    `json:"email"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	Location    string    `json:"location"`
+	Website     string    `json:"website"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+// Chirp covers drafts, scheduled chirps and deleted chirps that haven't been purged yet as well as published ones
+type Chirp struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Body       string     `json:"body"`
+	ReplyToID  *uuid.UUID `json:"reply_to_id"`
+	QuoteOfID  *uuid.UUID `json:"quote_of"`
+	Visibility string     `json:"visibility"`
+	Status     string     `json:"status"`
+	PublishAt  *time.Time `json:"publish_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+}
+
+// Message is a direct message the user sent or received
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+type Reaction struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Follow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session is a refresh token without the token itself
+type Session struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+type Passkey struct {
+	ID         string     `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Chirpy data export for @{{.Profile.Handle}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; }
+th, td { border: 1px solid #ddd; padding: 0.4rem; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>Chirpy data export for @{{.Profile.Handle}}</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}. The same data is in the data folder as JSON.</p>
+
+<h2>Profile</h2>
+<table>
+<tr><th>ID</th><td>{{.Profile.ID}}</td></tr>
+<tr><th>Joined</th><td>{{.Profile.CreatedAt.Format "2006-01-02"}}</td></tr>
+<tr><th>Email</th><td>{{.Profile.Email}}</td></tr>
+<tr><th>Handle</th><td>@{{.Profile.Handle}}</td></tr>
+<tr><th>Display name</th><td>{{.Profile.DisplayName}}</td></tr>
+<tr><th>Bio</th><td>{{.Profile.Bio}}</td></tr>
+<tr><th>Location</th><td>{{.Profile.Location}}</td></tr>
+<tr><th>Website</th><td>{{.Profile.Website}}</td></tr>
+<tr><th>Avatar</th><td>{{.Profile.AvatarURL}}</td></tr>
+<tr><th>Chirpy Red</th><td>{{if .Profile.IsChirpyRed}}Yes{{else}}No{{end}}</td></tr>
+</table>
+
+<h2>Chirps ({{len .Chirps}})</h2>
+<table>
+<tr><th>Posted</th><th>Chirp</th></tr>
+{{range .Chirps}}<tr><td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td><td>{{.Body}}{{if .ReplyToID}}<br><small>in reply to {{.ReplyToID}}</small>{{end}}{{if .QuoteOfID}}<br><small>quoting {{.QuoteOfID}}</small>{{end}}{{if ne .Status "published"}}<br><small>{{.Status}}</small>{{end}}{{if .DeletedAt}}<br><small>deleted {{.DeletedAt.Format "2006-01-02 15:04"}}</small>{{end}}</td></tr>
+{{end}}</table>
+
+<h2>Direct messages ({{len .Messages}})</h2>
+<table>
+<tr><th>Sent</th><th>Conversation</th><th>From</th><th>Message</th></tr>
+{{range .Messages}}<tr><td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td><td>{{.ConversationID}}</td><td>{{.SenderID}}</td><td>{{.Body}}</td></tr>
+{{end}}</table>
+
+<h2>Likes ({{len .Likes}})</h2>
+<table>
+<tr><th>Liked</th><th>Chirp</th></tr>
+{{range .Likes}}<tr><td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td><td>{{.ChirpID}}</td></tr>
+{{end}}</table>
+
+<h2>Rechirps ({{len .Rechirps}})</h2>
+<table>
+<tr><th>Rechirped</th><th>Chirp</th></tr>
+{{range .Rechirps}}<tr><td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td><td>{{.ChirpID}}</td></tr>
+{{end}}</table>
+
+<h2>Following ({{len .Following}})</h2>
+<table>
+<tr><th>Since</th><th>User</th></tr>
+{{range .Following}}<tr><td>{{.CreatedAt.Format "2006-01-02"}}</td><td>{{.UserID}}</td></tr>
+{{end}}</table>
+
+<h2>Followers ({{len .Followers}})</h2>
+<table>
+<tr><th>Since</th><th>User</th></tr>
+{{range .Followers}}<tr><td>{{.CreatedAt.Format "2006-01-02"}}</td><td>{{.UserID}}</td></tr>
+{{end}}</table>
+
+<h2>Sessions ({{len .Sessions}})</h2>
+<table>
+<tr><th>Started</th><th>Expires</th><th>Revoked</th></tr>
+{{range .Sessions}}<tr><td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td><td>{{.ExpiresAt.Format "2006-01-02 15:04"}}</td><td>{{if .RevokedAt}}{{.RevokedAt.Format "2006-01-02 15:04"}}{{end}}</td></tr>
+{{end}}</table>
+
+<h2>Passkeys ({{len .Passkeys}})</h2>
+<table>
+<tr><th>Added</th><th>Last used</th><th>ID</th></tr>
+{{range .Passkeys}}<tr><td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td><td>{{if .LastUsedAt}}{{.LastUsedAt.Format "2006-01-02 15:04"}}{{end}}</td><td>{{.ID}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// Write builds the ZIP archive into w
+func Write(w io.Writer, archive Archive) error {
+	zipWriter := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{name: "data/profile.json", data: archive.Profile},
+		{name: "data/chirps.json", data: nonNil(archive.Chirps)},
+		{name: "data/messages.json", data: nonNil(archive.Messages)},
+		{name: "data/likes.json", data: nonNil(archive.Likes)},
+		{name: "data/rechirps.json", data: nonNil(archive.Rechirps)},
+		{name: "data/following.json", data: nonNil(archive.Following)},
+		{name: "data/followers.json", data: nonNil(archive.Followers)},
+		{name: "data/sessions.json", data: nonNil(archive.Sessions)},
+		{name: "data/passkeys.json", data: nonNil(archive.Passkeys)},
+	}
+	for _, file := range files {
+		fileWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: archive.GeneratedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(fileWriter)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(file.data)
+		if err != nil {
+			return err
+		}
+	}
+
+	indexWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "index.html", Method: zip.Deflate, Modified: archive.GeneratedAt})
+	if err != nil {
+		return err
+	}
+	err = indexTemplate.Execute(indexWriter, archive)
+	if err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+// empty sections are written as [] rather than null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,81 @@
+package takeout
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWrite(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	archive := Archive{
+		GeneratedAt: now,
+		Profile:     Profile{ID: uuid.New(), CreatedAt: now, Email: "test@test.com", Handle: "chirper"},
+		Chirps: []Chirp{
+			{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: "<script>alert(1)</script>", Status: "published"},
+			{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: "not yet", Status: "draft"},
+		},
+		Messages: []Message{
+			{ID: uuid.New(), ConversationID: uuid.New(), CreatedAt: now, SenderID: uuid.New(), Body: "<b>hi</b>"},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, archive)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Error reading archive: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Error opening %s: %v", file.Name, err)
+		}
+		contents, _ := io.ReadAll(rc)
+		rc.Close()
+		files[file.Name] = string(contents)
+	}
+
+	for _, name := range []string{"index.html", "data/profile.json", "data/chirps.json", "data/messages.json", "data/likes.json", "data/sessions.json", "data/passkeys.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in archive", name)
+		}
+	}
+
+	chirps := []Chirp{}
+	err = json.Unmarshal([]byte(files["data/chirps.json"]), &chirps)
+	if err != nil || len(chirps) != 2 || chirps[0].Body != archive.Chirps[0].Body || chirps[1].Status != "draft" {
+		t.Errorf("Expected chirps.json to round trip, got %v (%v)", chirps, err)
+	}
+	messages := []Message{}
+	err = json.Unmarshal([]byte(files["data/messages.json"]), &messages)
+	if err != nil || len(messages) != 1 || messages[0].Body != archive.Messages[0].Body {
+		t.Errorf("Expected messages.json to round trip, got %v (%v)", messages, err)
+	}
+	if strings.TrimSpace(files["data/likes.json"]) != "[]" {
+		t.Errorf("Expected empty likes to be [], got %q", files["data/likes.json"])
+	}
+
+	index := files["index.html"]
+	if strings.Contains(index, "<script>") || strings.Contains(index, "<b>hi") {
+		t.Error("Expected chirp and message bodies to be escaped in index.html")
+	}
+	if !strings.Contains(index, "<small>draft</small>") {
+		t.Error("Expected index.html to mark drafts")
+	}
+	if !strings.Contains(index, "@chirper") {
+		t.Error("Expected index.html to show the handle")
+	}
+}
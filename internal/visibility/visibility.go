@@ -0,0 +1,69 @@
+package visibility
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// who a chirp is shown to, the default is Public
+const (
+	Public    = "public"
+	Unlisted  = "unlisted"
+	Followers = "followers"
+	Mentioned = "mentioned"
+)
+
+// IsValid reports whether visibility is one a chirp can be posted with
+func IsValid(visibility string) bool {
+	switch visibility {
+	case Public, Unlisted, Followers, Mentioned:
+		return true
+	}
+
+	return false
+}
+
+// Audience is everything needed to decide who can see a chirp
+type Audience struct {
+	AuthorID   uuid.UUID
+	Visibility string
+	// chirps from protected accounts are only ever shown to approved followers
+	Protected bool
+	Mentioned []uuid.UUID
+}
+
+// CanView reports whether a viewer can open a chirp, viewerID is uuid.Nil for someone who isn't logged in and
+// following is whether they're an approved follower of the author. The database queries listing chirps apply
+// the same rules so the two have to be kept in step
+func CanView(audience Audience, viewerID uuid.UUID, following bool) bool {
+	if viewerID != uuid.Nil && viewerID == audience.AuthorID {
+		return true
+	}
+	if audience.Protected && !following {
+		return false
+	}
+
+	switch audience.Visibility {
+	case Public, Unlisted:
+		return true
+	case Followers:
+		return following
+	case Mentioned:
+		return viewerID != uuid.Nil && slices.Contains(audience.Mentioned, viewerID)
+	}
+
+	return false
+}
+
+// Listed reports whether a chirp shows up in listings that aren't about its author or thread, unlisted chirps
+// can still be opened directly and seen by followers
+func Listed(visibility string) bool {
+	return visibility == Public
+}
+
+// Shareable reports whether a chirp can be rechirped or quoted, sharing puts it in front of people the author
+// didn't pick so only chirps anyone could open qualify
+func Shareable(audience Audience) bool {
+	return !audience.Protected && (audience.Visibility == Public || audience.Visibility == Unlisted)
+}
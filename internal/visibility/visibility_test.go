@@ -0,0 +1,73 @@
+package visibility
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCanView(t *testing.T) {
+	author := uuid.New()
+	follower := uuid.New()
+	mentioned := uuid.New()
+	stranger := uuid.New()
+
+	tests := []struct {
+		name      string
+		audience  Audience
+		viewerID  uuid.UUID
+		following bool
+		want      bool
+	}{
+		{"public to anyone", Audience{AuthorID: author, Visibility: Public}, uuid.Nil, false, true},
+		{"unlisted by link", Audience{AuthorID: author, Visibility: Unlisted}, stranger, false, true},
+		{"followers to a follower", Audience{AuthorID: author, Visibility: Followers}, follower, true, true},
+		{"followers to a stranger", Audience{AuthorID: author, Visibility: Followers}, stranger, false, false},
+		{"followers to the author", Audience{AuthorID: author, Visibility: Followers}, author, false, true},
+		{"mentioned to the mentioned", Audience{AuthorID: author, Visibility: Mentioned, Mentioned: []uuid.UUID{mentioned}}, mentioned, false, true},
+		{"mentioned to a follower", Audience{AuthorID: author, Visibility: Mentioned, Mentioned: []uuid.UUID{mentioned}}, follower, true, false},
+		{"mentioned logged out", Audience{AuthorID: author, Visibility: Mentioned, Mentioned: []uuid.UUID{uuid.Nil}}, uuid.Nil, false, false},
+		{"protected public to a stranger", Audience{AuthorID: author, Visibility: Public, Protected: true}, stranger, false, false},
+		{"protected public to a follower", Audience{AuthorID: author, Visibility: Public, Protected: true}, follower, true, true},
+		{"protected mentioned to a non-follower", Audience{AuthorID: author, Visibility: Mentioned, Protected: true, Mentioned: []uuid.UUID{mentioned}}, mentioned, false, false},
+		{"protected to the author", Audience{AuthorID: author, Visibility: Public, Protected: true}, author, false, true},
+		{"unknown visibility", Audience{AuthorID: author, Visibility: "secret"}, stranger, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CanView(tt.audience, tt.viewerID, tt.following)
+			if got != tt.want {
+				t.Errorf("CanView() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListed(t *testing.T) {
+	if !Listed(Public) {
+		t.Errorf("Expected public chirps to be listed")
+	}
+	for _, visibility := range []string{Unlisted, Followers, Mentioned} {
+		if Listed(visibility) {
+			t.Errorf("Expected %s chirps not to be listed", visibility)
+		}
+	}
+}
+
+func TestShareable(t *testing.T) {
+	author := uuid.New()
+	for _, visibility := range []string{Public, Unlisted} {
+		if !Shareable(Audience{AuthorID: author, Visibility: visibility}) {
+			t.Errorf("Expected %s chirps to be shareable", visibility)
+		}
+		if Shareable(Audience{AuthorID: author, Visibility: visibility, Protected: true}) {
+			t.Errorf("Expected %s chirps from protected accounts not to be shareable", visibility)
+		}
+	}
+	for _, visibility := range []string{Followers, Mentioned} {
+		if Shareable(Audience{AuthorID: author, Visibility: visibility}) {
+			t.Errorf("Expected %s chirps not to be shareable", visibility)
+		}
+	}
+}
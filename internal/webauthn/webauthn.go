@@ -0,0 +1,307 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	CeremonyRegistration = "webauthn.create"
+	CeremonyAssertion    = "webauthn.get"
+)
+
+// authenticator data flags, see https://www.w3.org/TR/webauthn-2/#sctn-authenticator-data
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+)
+
+// COSE algorithm identifier for ECDSA w/ SHA-256, the only algorithm we advertise
+const AlgES256 = -7
+
+var (
+	ErrChallengeMismatch = errors.New("webauthn: challenge does not match")
+	ErrOriginMismatch    = errors.New("webauthn: origin does not match")
+	ErrSignCount         = errors.New("webauthn: sign count did not increase, authenticator may be cloned")
+)
+
+type RelyingParty struct {
+	ID     string
+	Name   string
+	Origin string
+}
+
+type ClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// Credential is what gets stored server side after a successful registration
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+func NewChallenge() (string, error) {
+	challenge := make([]byte, 32)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(challenge), nil
+}
+
+func ParseClientData(clientDataJSON []byte) (ClientData, error) {
+	clientData := ClientData{}
+	err := json.Unmarshal(clientDataJSON, &clientData)
+	if err != nil {
+		return ClientData{}, fmt.Errorf("webauthn: invalid client data: %w", err)
+	}
+
+	return clientData, nil
+}
+
+// VerifyRegistration checks an attestation response from navigator.credentials.create and returns the new credential
+func (rp RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte) (Credential, error) {
+	err := rp.verifyClientData(clientDataJSON, CeremonyRegistration, challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return Credential{}, err
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return Credential{}, errors.New("webauthn: attestation object is not a map")
+	}
+	format, _ := attestation["fmt"].(string)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	attStmt, _ := attestation["attStmt"].(map[interface{}]interface{})
+	if rawAuthData == nil || attStmt == nil {
+		return Credential{}, errors.New("webauthn: attestation object is missing fields")
+	}
+
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if authData.flags&flagAttestedCredData == 0 {
+		return Credential{}, errors.New("webauthn: attestation has no credential data")
+	}
+
+	publicKey, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	err = verifyAttestationStatement(format, attStmt, rawAuthData, clientDataHash[:], publicKey)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		ID:        authData.credentialID,
+		PublicKey: authData.publicKey,
+		SignCount: authData.signCount,
+	}, nil
+}
+
+// VerifyAssertion checks a response from navigator.credentials.get against a stored credential and returns its new sign count
+func (rp RelyingParty) VerifyAssertion(challenge string, credential Credential, clientDataJSON, rawAuthData, signature []byte) (uint32, error) {
+	err := rp.verifyClientData(clientDataJSON, CeremonyAssertion, challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+
+	publicKey, err := parseCOSEKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, rawAuthData...), clientDataHash[:]...))
+	if !ecdsa.VerifyASN1(publicKey, digest[:], signature) {
+		return 0, errors.New("webauthn: invalid assertion signature")
+	}
+
+	// authenticators that don't implement a counter always report zero
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ErrSignCount
+	}
+
+	return authData.signCount, nil
+}
+
+func (rp RelyingParty) verifyClientData(clientDataJSON []byte, ceremony, challenge string) error {
+	clientData, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return err
+	}
+	if clientData.Type != ceremony {
+		return fmt.Errorf("webauthn: unexpected client data type %q", clientData.Type)
+	}
+	if clientData.Challenge != challenge {
+		return ErrChallengeMismatch
+	}
+	if clientData.Origin != rp.Origin {
+		return ErrOriginMismatch
+	}
+
+	return nil
+}
+
+func (rp RelyingParty) verifyAuthenticatorData(rawAuthData []byte) (authenticatorData, error) {
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return authenticatorData{}, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return authenticatorData{}, errors.New("webauthn: relying party ID hash does not match")
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return authenticatorData{}, errors.New("webauthn: user was not present")
+	}
+
+	return authData, nil
+}
+
+func parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	// rpIdHash (32) + flags (1) + signCount (4)
+	const minLength = 37
+	if len(raw) < minLength {
+		return authenticatorData{}, errors.New("webauthn: authenticator data too short")
+	}
+
+	authData := authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if authData.flags&flagAttestedCredData == 0 {
+		return authData, nil
+	}
+
+	// aaguid (16) + credentialIdLength (2)
+	rest := raw[minLength:]
+	if len(rest) < 18 {
+		return authenticatorData{}, errors.New("webauthn: attested credential data too short")
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return authenticatorData{}, errors.New("webauthn: credential ID truncated")
+	}
+	authData.credentialID = rest[:idLength]
+
+	_, keyLength, err := decodeCBOR(rest[idLength:])
+	if err != nil {
+		return authenticatorData{}, err
+	}
+	authData.publicKey = rest[idLength : idLength+keyLength]
+
+	return authData, nil
+}
+
+// parseCOSEKey only understands EC2 P-256 keys, which is what every platform authenticator offers for ES256
+func parseCOSEKey(raw []byte) (*ecdsa.PublicKey, error) {
+	decoded, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("webauthn: public key is not a COSE map")
+	}
+
+	keyType, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+	curve, _ := key[int64(-1)].(int64)
+	x, _ := key[int64(-2)].([]byte)
+	y, _ := key[int64(-3)].([]byte)
+	if keyType != 2 || alg != AlgES256 || curve != 1 {
+		return nil, errors.New("webauthn: unsupported public key algorithm")
+	}
+	if len(x) != 32 || len(y) != 32 {
+		return nil, errors.New("webauthn: invalid public key coordinates")
+	}
+
+	publicKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, errors.New("webauthn: public key is not on curve")
+	}
+
+	return publicKey, nil
+}
+
+// verifyAttestationStatement accepts "none" and "packed" attestation, we don't validate certificate chains since
+// we only care that the credential works, not which vendor made the authenticator
+func verifyAttestationStatement(format string, attStmt map[interface{}]interface{}, rawAuthData, clientDataHash []byte, credentialKey *ecdsa.PublicKey) error {
+	switch format {
+	case "none":
+		return nil
+	case "packed":
+		alg, _ := attStmt["alg"].(int64)
+		signature, _ := attStmt["sig"].([]byte)
+		if alg != AlgES256 || signature == nil {
+			return errors.New("webauthn: unsupported packed attestation")
+		}
+
+		signingKey := credentialKey
+		if certs, ok := attStmt["x5c"].([]interface{}); ok && len(certs) > 0 {
+			rawCert, _ := certs[0].([]byte)
+			cert, err := x509.ParseCertificate(rawCert)
+			if err != nil {
+				return fmt.Errorf("webauthn: invalid attestation certificate: %w", err)
+			}
+			certKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+			if !ok {
+				return errors.New("webauthn: unsupported attestation certificate key")
+			}
+			signingKey = certKey
+		}
+
+		digest := sha256.Sum256(append(append([]byte{}, rawAuthData...), clientDataHash...))
+		if !ecdsa.VerifyASN1(signingKey, digest[:], signature) {
+			return errors.New("webauthn: invalid attestation signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("webauthn: unsupported attestation format %q", format)
+	}
+}
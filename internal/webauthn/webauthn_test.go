@@ -0,0 +1,250 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+// softwareAuthenticator plays the part of a security key so the ceremonies can be tested end to end
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+
+	return &softwareAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (a *softwareAuthenticator) clientData(ceremony, challenge, origin string) []byte {
+	clientDataJSON, _ := json.Marshal(ClientData{
+		Type:      ceremony,
+		Challenge: challenge,
+		Origin:    origin,
+	})
+	return clientDataJSON
+}
+
+func (a *softwareAuthenticator) authData(rpID string, flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authData := append([]byte{}, rpIDHash[:]...)
+	authData = append(authData, flags)
+	authData = binary.BigEndian.AppendUint32(authData, a.signCount)
+	if attested {
+		authData = append(authData, make([]byte, 16)...)
+		authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+		authData = append(authData, a.credentialID...)
+		authData = append(authData, a.coseKey()...)
+	}
+	return authData
+}
+
+func (a *softwareAuthenticator) coseKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+	return encodeCBOR(map[interface{}]interface{}{
+		int64(1):  int64(2),
+		int64(3):  int64(AlgES256),
+		int64(-1): int64(1),
+		int64(-2): x,
+		int64(-3): y,
+	})
+}
+
+func (a *softwareAuthenticator) sign(authData, clientDataJSON []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, _ := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	return signature
+}
+
+func (a *softwareAuthenticator) create(rp RelyingParty, challenge, format string) (clientDataJSON, attestationObject []byte) {
+	clientDataJSON = a.clientData(CeremonyRegistration, challenge, rp.Origin)
+	authData := a.authData(rp.ID, flagUserPresent|flagUserVerified|flagAttestedCredData, true)
+
+	attStmt := map[interface{}]interface{}{}
+	if format == "packed" {
+		attStmt["alg"] = int64(AlgES256)
+		attStmt["sig"] = a.sign(authData, clientDataJSON)
+	}
+
+	attestationObject = encodeCBOR(map[interface{}]interface{}{
+		"fmt":      format,
+		"attStmt":  attStmt,
+		"authData": authData,
+	})
+	return clientDataJSON, attestationObject
+}
+
+func (a *softwareAuthenticator) get(rp RelyingParty, challenge string) (clientDataJSON, authData, signature []byte) {
+	a.signCount++
+	clientDataJSON = a.clientData(CeremonyAssertion, challenge, rp.Origin)
+	authData = a.authData(rp.ID, flagUserPresent|flagUserVerified, false)
+	return clientDataJSON, authData, a.sign(authData, clientDataJSON)
+}
+
+// encodeCBOR covers just enough of CBOR to build attestation objects and COSE keys
+func encodeCBOR(value interface{}) []byte {
+	header := func(majorType byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{majorType<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{majorType<<5 | 24, byte(n)}
+		default:
+			return binary.BigEndian.AppendUint16([]byte{majorType<<5 | 25}, uint16(n))
+		}
+	}
+
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return header(1, uint64(-1-v))
+		}
+		return header(0, uint64(v))
+	case []byte:
+		return append(header(2, uint64(len(v))), v...)
+	case string:
+		return append(header(3, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		keys := make([][]byte, 0, len(v))
+		encoded := map[string][]byte{}
+		for key, item := range v {
+			encodedKey := encodeCBOR(key)
+			keys = append(keys, encodedKey)
+			encoded[string(encodedKey)] = encodeCBOR(item)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+
+		out := header(5, uint64(len(v)))
+		for _, key := range keys {
+			out = append(out, key...)
+			out = append(out, encoded[string(key)]...)
+		}
+		return out
+	}
+	return nil
+}
+
+func testRelyingParty() RelyingParty {
+	return RelyingParty{
+		ID:     "localhost",
+		Name:   "Chirpy",
+		Origin: "http://localhost:8080",
+	}
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	for _, format := range []string{"none", "packed"} {
+		t.Run(format, func(t *testing.T) {
+			rp := testRelyingParty()
+			authenticator := newSoftwareAuthenticator(t)
+
+			challenge, err := NewChallenge()
+			if err != nil {
+				t.Fatalf("Error making challenge: %v", err)
+			}
+
+			clientDataJSON, attestationObject := authenticator.create(rp, challenge, format)
+			credential, err := rp.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+			if err != nil {
+				t.Fatalf("Did not expect registration error, got: %v", err)
+			}
+			if string(credential.ID) != string(authenticator.credentialID) {
+				t.Errorf("Expected credential ID %x, got %x", authenticator.credentialID, credential.ID)
+			}
+
+			challenge, _ = NewChallenge()
+			clientDataJSON, authData, signature := authenticator.get(rp, challenge)
+			signCount, err := rp.VerifyAssertion(challenge, credential, clientDataJSON, authData, signature)
+			if err != nil {
+				t.Fatalf("Did not expect assertion error, got: %v", err)
+			}
+			if signCount != 1 {
+				t.Errorf("Expected sign count 1, got %d", signCount)
+			}
+		})
+	}
+}
+
+func TestAssertionFailures(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := newSoftwareAuthenticator(t)
+
+	challenge, _ := NewChallenge()
+	clientDataJSON, attestationObject := authenticator.create(rp, challenge, "none")
+	credential, err := rp.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		t.Fatalf("Did not expect registration error, got: %v", err)
+	}
+
+	t.Run("Wrong challenge", func(t *testing.T) {
+		clientDataJSON, authData, signature := authenticator.get(rp, "not-the-challenge")
+		_, err := rp.VerifyAssertion(challenge, credential, clientDataJSON, authData, signature)
+		if !errors.Is(err, ErrChallengeMismatch) {
+			t.Errorf("Expected challenge mismatch, got: %v", err)
+		}
+	})
+
+	t.Run("Wrong origin", func(t *testing.T) {
+		evil := rp
+		evil.Origin = "https://evil.example"
+		clientDataJSON, authData, signature := authenticator.get(evil, challenge)
+		_, err := rp.VerifyAssertion(challenge, credential, clientDataJSON, authData, signature)
+		if !errors.Is(err, ErrOriginMismatch) {
+			t.Errorf("Expected origin mismatch, got: %v", err)
+		}
+	})
+
+	t.Run("Tampered signature", func(t *testing.T) {
+		clientDataJSON, authData, signature := authenticator.get(rp, challenge)
+		authData[len(authData)-1]++
+		_, err := rp.VerifyAssertion(challenge, credential, clientDataJSON, authData, signature)
+		if err == nil {
+			t.Error("Expected error for tampered authenticator data, got nil")
+		}
+	})
+
+	t.Run("Sign count rollback", func(t *testing.T) {
+		stored := credential
+		stored.SignCount = 100
+		clientDataJSON, authData, signature := authenticator.get(rp, challenge)
+		_, err := rp.VerifyAssertion(challenge, stored, clientDataJSON, authData, signature)
+		if !errors.Is(err, ErrSignCount) {
+			t.Errorf("Expected sign count error, got: %v", err)
+		}
+	})
+
+	t.Run("Other authenticator", func(t *testing.T) {
+		other := newSoftwareAuthenticator(t)
+		clientDataJSON, authData, signature := other.get(rp, challenge)
+		_, err := rp.VerifyAssertion(challenge, credential, clientDataJSON, authData, signature)
+		if err == nil {
+			t.Error("Expected error for signature from another key, got nil")
+		}
+	})
+}
+
+func TestDecodeCBORRejectsTruncatedInput(t *testing.T) {
+	encoded := encodeCBOR(map[interface{}]interface{}{"fmt": "none"})
+	_, _, err := decodeCBOR(encoded[:len(encoded)-1])
+	if err == nil {
+		t.Error("Expected error decoding truncated CBOR, got nil")
+	}
+}
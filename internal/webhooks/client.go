@@ -0,0 +1,88 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidURL       = errors.New("webhook URL must be an absolute http or https URL")
+	ErrForbiddenAddress = errors.New("webhook URL must point at a public address")
+
+	// carrier-grade NAT range, not covered by IsPrivate but just as internal
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+)
+
+// NewClient returns the client deliveries are sent with. Every connection is checked as it's dialed, after DNS
+// has been resolved, so a hostname can't be pointed at an internal address once the webhook is registered.
+// Redirects aren't followed, the 3XX response just counts as a failed attempt
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicAddr(addrPort.Addr()) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// a proxy would be dialed instead of the integrator and defeat the check above
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// ValidateURL checks a webhook URL when it's registered so mistakes are caught straight away, NewClient still
+// checks every connection since what a hostname resolves to can change afterwards
+func ValidateURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	webhookURL, err := url.Parse(rawURL)
+	if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Hostname() == "" {
+		return nil, ErrInvalidURL
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", webhookURL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return nil, ErrForbiddenAddress
+		}
+	}
+
+	return webhookURL, nil
+}
+
+// IsPublicAddr reports whether webhooks may be sent to an address, anything loopback, private, link-local (which
+// includes cloud metadata endpoints), multicast or unspecified is refused
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
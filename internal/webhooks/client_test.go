@@ -0,0 +1,78 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		got := IsPublicAddr(netip.MustParseAddr(tt.addr))
+		if got != tt.want {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{"ftp://example.com/hook", ErrInvalidURL},
+		{"/just/a/path", ErrInvalidURL},
+		{"http://127.0.0.1:8080/admin/reset", ErrForbiddenAddress},
+		{"http://169.254.169.254/latest/meta-data/", ErrForbiddenAddress},
+		{"http://[::1]/hook", ErrForbiddenAddress},
+		{"https://93.184.216.34/hook", nil},
+	}
+
+	for _, tt := range tests {
+		_, err := ValidateURL(context.Background(), tt.url)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ValidateURL(%q) = %v, want %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewClientRefusesInternalAddresses(t *testing.T) {
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	_, err := Send(context.Background(), NewClient(time.Second), Delivery{URL: server.URL, Payload: []byte(`{}`)})
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Expected ErrForbiddenAddress, got %v", err)
+	}
+	if reached {
+		t.Error("Expected the loopback server not to be reached")
+	}
+}
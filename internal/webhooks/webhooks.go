@@ -0,0 +1,88 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is moved to the dead letter state
+	MaxAttempts = 8
+
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+
+	// at most this much of a response is read so the connection can be reused, none of it is kept
+	maxDrainedResponse = 4096
+)
+
+type Delivery struct {
+	ID        string
+	EventType string
+	URL       string
+	Secret    string
+	Payload   []byte
+}
+
+type Result struct {
+	StatusCode int
+	Duration   time.Duration
+}
+
+// Backoff returns how long to wait before retrying after the given number of failed attempts,
+// doubling each time with up to 10% jitter so a recovering integrator isn't hit by every retry at once
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	jitter := time.Duration(rand.Int64N(int64(delay) / 10))
+	return delay + jitter
+}
+
+// Send POSTs a signed payload to an integrator, any non 2XX response is treated as a failure. Only the status
+// code is reported back, response bodies are thrown away so webhooks can't be used to read other servers
+func Send(ctx context.Context, client *http.Client, delivery Delivery) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set("Chirpy-Event", delivery.EventType)
+	req.Header.Set("Chirpy-Delivery", delivery.ID)
+	req.Header.Set("Chirpy-Signature", auth.MakeWebhookSignature(delivery.Secret, time.Now(), delivery.Payload))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{Duration: time.Since(start)}, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedResponse))
+	result := Result{
+		StatusCode: resp.StatusCode,
+		Duration:   time.Since(start),
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, fmt.Errorf("webhook endpoint responded with %d", resp.StatusCode)
+	}
+
+	return result, nil
+}
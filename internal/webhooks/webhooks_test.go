@@ -0,0 +1,74 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+)
+
+func TestSendSignsPayload(t *testing.T) {
+	payload := []byte(`{"type":"chirp.created"}`)
+	secret := "integrator-secret"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		err := auth.ValidateWebhookSignature(req.Header.Get("Chirpy-Signature"), body, secret, time.Minute)
+		if err != nil {
+			t.Errorf("Signature did not validate: %v", err)
+		}
+		if req.Header.Get("Chirpy-Event") != "chirp.created" {
+			t.Errorf("Expected event header chirp.created, got %q", req.Header.Get("Chirpy-Event"))
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	result, err := Send(context.Background(), server.Client(), Delivery{
+		ID:        "delivery-1",
+		EventType: "chirp.created",
+		URL:       server.URL,
+		Secret:    secret,
+		Payload:   payload,
+	})
+	if err != nil {
+		t.Fatalf("Did not expect error, got: %v", err)
+	}
+	if result.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", http.StatusAccepted, result.StatusCode)
+	}
+}
+
+func TestSendFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	result, err := Send(context.Background(), server.Client(), Delivery{URL: server.URL, Payload: []byte(`{}`)})
+	if err == nil {
+		t.Error("Expected error for 503 response, got nil")
+	}
+	if result.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected failed status code to be logged, got %+v", result)
+	}
+}
+
+func TestBackoffGrowsAndCaps(t *testing.T) {
+	previous := time.Duration(0)
+	for attempts := 1; attempts <= 5; attempts++ {
+		delay := Backoff(attempts)
+		if delay <= previous {
+			t.Errorf("Expected backoff to grow, attempt %d gave %v after %v", attempts, delay, previous)
+		}
+		previous = delay
+	}
+
+	if delay := Backoff(50); delay > maxBackoff+maxBackoff/10 {
+		t.Errorf("Expected backoff to be capped, got %v", delay)
+	}
+}
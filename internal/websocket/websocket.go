@@ -0,0 +1,329 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// opcodes from RFC 6455
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// close codes, 4000 and up are free for applications to use
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseTryAgainLater   = 1013
+)
+
+const (
+	acceptGUID   = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	writeTimeout = 10 * time.Second
+)
+
+var (
+	ErrNotWebSocket    = errors.New("websocket: request is not a websocket upgrade")
+	ErrUnmaskedFrame   = errors.New("websocket: client frames must be masked")
+	ErrMessageTooBig   = errors.New("websocket: message exceeds the read limit")
+	ErrInvalidFrame    = errors.New("websocket: invalid frame")
+	errControlTooLarge = errors.New("websocket: control frames can't be fragmented or exceed 125 bytes")
+)
+
+// CloseError is returned from ReadMessage once the peer sends a close frame
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with %d %s", e.Code, e.Text)
+}
+
+// Conn is the server side of a websocket connection, reads have to come from a single goroutine while
+// writes are safe from several
+type Conn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	readLimit int64
+	onPong    func()
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+// Upgrade completes the opening handshake and takes over the connection from net/http, a non-empty protocol is
+// sent back as the chosen subprotocol and should be one the client offered
+func Upgrade(w http.ResponseWriter, req *http.Request, protocol string) (*Conn, error) {
+	if req.Method != http.MethodGet ||
+		!headerContains(req.Header, "Connection", "upgrade") ||
+		!headerContains(req.Header, "Upgrade", "websocket") {
+		return nil, ErrNotWebSocket
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, ErrNotWebSocket
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, ErrNotWebSocket
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("websocket: response does not support hijacking")
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n"
+	if protocol != "" {
+		response += "Sec-WebSocket-Protocol: " + protocol + "\r\n"
+	}
+	response += "\r\n"
+	_, err = conn.Write([]byte(response))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{
+		conn:      conn,
+		reader:    buffered.Reader,
+		readLimit: 1 << 20,
+		onPong:    func() {},
+	}, nil
+}
+
+// Subprotocols lists the subprotocols a client offered in its Sec-WebSocket-Protocol header
+func Subprotocols(req *http.Request) []string {
+	protocols := []string{}
+	for _, field := range req.Header.Values("Sec-WebSocket-Protocol") {
+		for _, part := range strings.Split(field, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				protocols = append(protocols, part)
+			}
+		}
+	}
+
+	return protocols
+}
+
+// AcceptKey is the Sec-WebSocket-Accept value for a client's Sec-WebSocket-Key
+func AcceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetPongHandler is called from ReadMessage whenever a pong arrives
+func (c *Conn) SetPongHandler(handler func()) {
+	c.onPong = handler
+}
+
+// ReadMessage returns the next text or binary message, pings are answered and pongs handled along the way
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	messageType := 0
+	message := []byte{}
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			err = c.WriteMessage(PongMessage, payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			c.onPong()
+			continue
+		case CloseMessage:
+			closeErr := &CloseError{Code: CloseNormal}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+			}
+			c.WriteClose(closeErr.Code, "")
+			return 0, nil, closeErr
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, ErrInvalidFrame
+			}
+			messageType = opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, ErrInvalidFrame
+			}
+		default:
+			return 0, nil, ErrInvalidFrame
+		}
+
+		if int64(len(message)+len(payload)) > c.readLimit {
+			c.WriteClose(CloseMessageTooBig, "")
+			return 0, nil, ErrMessageTooBig
+		}
+		message = append(message, payload...)
+
+		if fin {
+			return messageType, message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(c.reader, header)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, ErrInvalidFrame
+	}
+	opcode := int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	if !masked {
+		c.WriteClose(CloseProtocolError, "")
+		return false, 0, nil, ErrUnmaskedFrame
+	}
+	if opcode >= CloseMessage && (!fin || length > 125) {
+		c.WriteClose(CloseProtocolError, "")
+		return false, 0, nil, errControlTooLarge
+	}
+
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		_, err = io.ReadFull(c.reader, extended)
+		length = int64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		_, err = io.ReadFull(c.reader, extended)
+		length = int64(binary.BigEndian.Uint64(extended))
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if length > c.readLimit || length < 0 {
+		c.WriteClose(CloseMessageTooBig, "")
+		return false, 0, nil, ErrMessageTooBig
+	}
+
+	mask := make([]byte, 4)
+	_, err = io.ReadFull(c.reader, mask)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends a single unfragmented frame, a client that doesn't take it within the write timeout
+// gets the write failed instead of holding up the sender
+func (c *Conn) WriteMessage(opcode int, data []byte) error {
+	return c.writeFrame(opcode, data, time.Now().Add(writeTimeout))
+}
+
+// WriteClose starts the closing handshake, only the first call sends anything
+func (c *Conn) WriteClose(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+
+	return c.writeFrame(CloseMessage, payload, time.Now().Add(time.Second))
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) writeFrame(opcode int, data []byte, deadline time.Time) error {
+	frame := []byte{0x80 | byte(opcode)}
+	switch {
+	case len(data) <= 125:
+		frame = append(frame, byte(len(data)))
+	case len(data) <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(data)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(data)))
+	}
+	frame = append(frame, data...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// nothing may follow a close frame
+	if c.closeSent {
+		return nil
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	c.conn.SetWriteDeadline(deadline)
+	_, err := c.conn.Write(frame)
+
+	return err
+}
+
+func headerContains(header http.Header, name, value string) bool {
+	for _, field := range header.Values(name) {
+		for _, part := range strings.Split(field, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
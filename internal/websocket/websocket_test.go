@@ -0,0 +1,198 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// dial does the client half of the handshake against a test server
+func dial(t *testing.T, server *httptest.Server) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Could not dial: %v", err)
+	}
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	request := "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	conn.Write([]byte(request))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Could not read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept key %q", resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	return conn, reader
+}
+
+func writeClientFrame(conn net.Conn, fin bool, opcode int, payload []byte) {
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	if len(payload) <= 125 {
+		frame = append(frame, 0x80|byte(len(payload)))
+	} else {
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	conn.Write(frame)
+}
+
+func readServerFrame(t *testing.T, reader *bufio.Reader) (int, []byte) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		t.Fatalf("Could not read frame: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Error("Server frames must not be masked")
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		extended := make([]byte, 2)
+		io.ReadFull(reader, extended)
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+	payload := make([]byte, length)
+	io.ReadFull(reader, payload)
+
+	return int(header[0] & 0x0f), payload
+}
+
+func echoServer(t *testing.T, readErr chan<- error) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := Upgrade(w, req, "")
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		conn.SetReadLimit(1024)
+
+		for {
+			opcode, message, err := conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			conn.WriteMessage(opcode, message)
+		}
+	}))
+}
+
+func TestEchoesFragmentedMessage(t *testing.T) {
+	readErr := make(chan error, 1)
+	server := echoServer(t, readErr)
+	defer server.Close()
+
+	conn, reader := dial(t, server)
+	defer conn.Close()
+
+	writeClientFrame(conn, false, TextMessage, []byte("hello "))
+	writeClientFrame(conn, true, PingMessage, []byte("are you there"))
+	writeClientFrame(conn, true, continuationFrame, []byte("world"))
+
+	opcode, payload := readServerFrame(t, reader)
+	if opcode != PongMessage || string(payload) != "are you there" {
+		t.Errorf("Expected pong echoing the ping, got %d %q", opcode, payload)
+	}
+	opcode, payload = readServerFrame(t, reader)
+	if opcode != TextMessage || string(payload) != "hello world" {
+		t.Errorf("Expected reassembled text message, got %d %q", opcode, payload)
+	}
+
+	writeClientFrame(conn, true, CloseMessage, []byte{0x03, 0xe8})
+	opcode, _ = readServerFrame(t, reader)
+	if opcode != CloseMessage {
+		t.Errorf("Expected close frame in reply, got %d", opcode)
+	}
+
+	closeErr := &CloseError{}
+	if err := <-readErr; !errors.As(err, &closeErr) || closeErr.Code != CloseNormal {
+		t.Errorf("Expected normal close error, got %v", err)
+	}
+}
+
+func TestRejectsOversizedMessage(t *testing.T) {
+	readErr := make(chan error, 1)
+	server := echoServer(t, readErr)
+	defer server.Close()
+
+	conn, reader := dial(t, server)
+	defer conn.Close()
+
+	writeClientFrame(conn, true, TextMessage, make([]byte, 2048))
+
+	opcode, payload := readServerFrame(t, reader)
+	if opcode != CloseMessage || binary.BigEndian.Uint16(payload) != CloseMessageTooBig {
+		t.Errorf("Expected close with %d, got %d %v", CloseMessageTooBig, opcode, payload)
+	}
+	if err := <-readErr; !errors.Is(err, ErrMessageTooBig) {
+		t.Errorf("Expected ErrMessageTooBig, got %v", err)
+	}
+}
+
+func TestUpgradeRejectsPlainRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err := Upgrade(httptest.NewRecorder(), req, "")
+	if !errors.Is(err, ErrNotWebSocket) {
+		t.Errorf("Expected ErrNotWebSocket, got %v", err)
+	}
+}
+
+func TestUpgradeSendsChosenSubprotocol(t *testing.T) {
+	offered := make(chan []string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		offered <- Subprotocols(req)
+		conn, err := Upgrade(w, req, "chat")
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Could not dial: %v", err)
+	}
+	defer conn.Close()
+
+	request := "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Protocol: chat, token.abc\r\n\r\n"
+	conn.Write([]byte(request))
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Could not read handshake response: %v", err)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != "chat" {
+		t.Errorf("Expected chat to be chosen, got %q", got)
+	}
+	if got := <-offered; len(got) != 2 || got[0] != "chat" || got[1] != "token.abc" {
+		t.Errorf("Expected both offered subprotocols, got %v", got)
+	}
+}
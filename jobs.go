@@ -0,0 +1,779 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/outbox"
+	"github.com/Khazz0r/chirpy/internal/pubsub"
+	"github.com/Khazz0r/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	subscriptionExpiryInterval = 10 * time.Minute
+	webhookDeliveryInterval    = 5 * time.Second
+	webhookDeliveryBatchSize   = 20
+	outboxRelayInterval        = time.Second
+	outboxRelayBatchSize       = 100
+	outboxRetention            = 7 * 24 * time.Hour
+	outboxRelayLease           = 30 * time.Second
+	streamChannel              = "chirpy_stream"
+	streamListenerPingInterval = 90 * time.Second
+	accountDeletionInterval    = time.Hour
+	dataExportInterval         = 10 * time.Second
+	chirpImportInterval        = 5 * time.Second
+	chirpImportBatchSize       = 100
+	deletedChirpPurgeInterval  = time.Hour
+	scheduledChirpInterval     = 10 * time.Second
+	scheduledChirpRetryDelay   = time.Minute
+	scheduledChirpMaxAttempts  = 5
+	expiredChirpReapInterval   = time.Minute
+	expiredChirpReapBatchSize  = 500
+	pollCloseInterval          = 30 * time.Second
+	pollCloseBatchSize         = 100
+)
+
+// statuses a webhook delivery moves through, dead deliveries stay put until someone redelivers them
+const (
+	deliveryStatusPending   = "pending"
+	deliveryStatusSucceeded = "succeeded"
+	deliveryStatusDead      = "dead"
+)
+
+// background job that takes Chirpy Red away from users whose paid time has run out without a renewal
+func (cfg *apiConfig) runSubscriptionExpiry(ctx context.Context) {
+	ticker := time.NewTicker(subscriptionExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		downgraded, err := cfg.db.DowngradeLapsedUsers(ctx)
+		if err != nil {
+			log.Printf("Error downgrading lapsed Chirpy Red users: %v", err)
+		} else if len(downgraded) > 0 {
+			log.Printf("Downgraded %d lapsed Chirpy Red users", len(downgraded))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// background job that permanently deletes accounts whose grace period after deactivation has passed, their
+// chirps and everything else go with them through the foreign keys
+func (cfg *apiConfig) runAccountDeletion(ctx context.Context) {
+	ticker := time.NewTicker(accountDeletionInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := cfg.deleteDeactivatedUsers(ctx)
+		if err != nil {
+			log.Printf("Error deleting deactivated users: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Deleted %d deactivated users", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// background job that publishes scheduled chirps once they're due, each one is claimed and published in its
+// own transaction so several servers can run this without a chirp ever going out twice
+func (cfg *apiConfig) runScheduledChirps(ctx context.Context) {
+	ticker := time.NewTicker(scheduledChirpInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			published, err := cfg.publishDueChirp(ctx)
+			if err != nil {
+				log.Printf("Error publishing scheduled chirp: %v", err)
+				break
+			}
+			if !published {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// helper that publishes the next due scheduled chirp, it reports false when there isn't one. A chirp that fails
+// to publish is put off with a doubling delay so the ones due after it still go out
+func (cfg *apiConfig) publishDueChirp(ctx context.Context) (bool, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	chirp, err := qtx.ClaimDueScheduledChirp(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = publishChirp(ctx, qtx, chirp)
+	if err != nil {
+		log.Printf("Error publishing scheduled chirp %s: %v", chirp.ID, err)
+		tx.Rollback()
+
+		err = cfg.db.MarkScheduledChirpFailed(ctx, database.MarkScheduledChirpFailedParams{
+			RetryAt:     time.Now().UTC().Add(scheduledChirpRetryDelay << chirp.PublishAttempts),
+			MaxAttempts: scheduledChirpMaxAttempts,
+			ID:          chirp.ID,
+		})
+		return err == nil, err
+	}
+
+	return true, tx.Commit()
+}
+
+// background job that deletes chirps that have expired, they're already hidden from every read so this only
+// has to keep up rather than be on time
+func (cfg *apiConfig) runExpiredChirpReaper(ctx context.Context) {
+	ticker := time.NewTicker(expiredChirpReapInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			reaped, err := cfg.reapExpiredChirps(ctx)
+			if err != nil {
+				log.Printf("Error deleting expired chirps: %v", err)
+				break
+			}
+			if reaped < expiredChirpReapBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// helper that deletes a batch of expired chirps and lets integrators know they're gone
+func (cfg *apiConfig) reapExpiredChirps(ctx context.Context) (int, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	expired, err := qtx.DeleteExpiredChirps(ctx, expiredChirpReapBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, chirp := range expired {
+		// integrators already heard about chirps that were deleted before they expired
+		if chirp.DeletedAt.Valid {
+			continue
+		}
+		err = recordChirpEvent(ctx, qtx, EventChirpExpired, chirp)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(expired), tx.Commit()
+}
+
+// background job that closes polls once they've run out and tells their authors, polls already stop taking
+// votes on time so this only decides when the notification goes out
+func (cfg *apiConfig) runPollCloser(ctx context.Context) {
+	ticker := time.NewTicker(pollCloseInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			closed, err := cfg.closeDuePolls(ctx)
+			if err != nil {
+				log.Printf("Error closing polls: %v", err)
+				break
+			}
+			if closed < pollCloseBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// helper that closes a batch of polls that have run out, notifying each author in the same transaction so
+// nobody is told twice
+func (cfg *apiConfig) closeDuePolls(ctx context.Context) (int, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	closed, err := qtx.CloseDuePolls(ctx, pollCloseBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, poll := range closed {
+		// a chirp that's been deleted in the meantime has nothing left to show its author
+		chirp, err := qtx.GetChirpByID(ctx, poll.ChirpID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		err = notifySystem(ctx, qtx, chirp.UserID, notificationPollClosed, uuid.NullUUID{UUID: chirp.ID, Valid: true}, "poll:"+chirp.ID.String())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(closed), tx.Commit()
+}
+
+// background job that permanently removes chirps once they've been deleted for longer than the retention period
+func (cfg *apiConfig) runDeletedChirpPurge(ctx context.Context) {
+	ticker := time.NewTicker(deletedChirpPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := cfg.db.PurgeDeletedChirps(ctx, sql.NullTime{Time: time.Now().UTC().Add(-deletedChirpRetention), Valid: true})
+		if err != nil {
+			log.Printf("Error purging deleted chirps: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted chirps", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) deleteDeactivatedUsers(ctx context.Context) (int, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	deletedIDs, err := qtx.DeleteDeactivatedUsers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, userID := range deletedIDs {
+		err = recordEvent(ctx, qtx, aggregateUser, userID, EventUserDeleted, struct {
+			ID uuid.UUID `json:"id"`
+		}{
+			ID: userID,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deletedIDs), tx.Commit()
+}
+
+// background job that builds requested data exports one at a time, an export stuck in processing for a while
+// is picked up again in case the server building it went away
+func (cfg *apiConfig) runDataExports(ctx context.Context) {
+	ticker := time.NewTicker(dataExportInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		for {
+			export, err := cfg.db.ClaimDataExport(ctx)
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				log.Printf("Error claiming data export: %v", err)
+				break
+			}
+			cfg.processDataExport(ctx, export)
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			_, err := cfg.db.DeleteExpiredDataExports(ctx)
+			if err != nil {
+				log.Printf("Error cleaning up expired data exports: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) processDataExport(ctx context.Context, export database.DataExport) {
+	err := cfg.completeDataExport(ctx, export)
+	if err == nil {
+		return
+	}
+
+	log.Printf("Error building data export %s: %v", export.ID, err)
+	err = cfg.db.FailDataExport(ctx, database.FailDataExportParams{
+		ID:    export.ID,
+		Error: sql.NullString{String: "Unable to build the archive, request a new export to try again", Valid: true},
+	})
+	if err != nil {
+		log.Printf("Error marking data export %s as failed: %v", export.ID, err)
+	}
+}
+
+// helper that stores the finished archive and tells the user about it in one go, so a user is never
+// notified about an export that isn't there
+func (cfg *apiConfig) completeDataExport(ctx context.Context, export database.DataExport) error {
+	archive, err := cfg.buildDataExport(ctx, export.UserID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.SaveDataExportArchive(ctx, database.SaveDataExportArchiveParams{
+		ExportID: export.ID,
+		Archive:  archive,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = qtx.CompleteDataExport(ctx, database.CompleteDataExportParams{
+		ID:        export.ID,
+		SizeBytes: int64(len(archive)),
+		ExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(dataExportRetention), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = notifySystem(ctx, qtx, export.UserID, notificationExportReady, uuid.NullUUID{}, "export:"+export.ID.String())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// background job that turns queued archive posts into chirps a batch at a time, every batch renews the claim
+// on its import so another server only picks it up if this one went away
+func (cfg *apiConfig) runChirpImports(ctx context.Context) {
+	ticker := time.NewTicker(chirpImportInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			chirpImport, err := cfg.db.ClaimChirpImport(ctx)
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				log.Printf("Error claiming chirp import: %v", err)
+				break
+			}
+			cfg.processChirpImport(ctx, chirpImport)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) processChirpImport(ctx context.Context, chirpImport database.ChirpImport) {
+	plan, err := cfg.planForUser(ctx, chirpImport.UserID)
+	if err != nil {
+		log.Printf("Error looking up plan for chirp import %s: %v", chirpImport.ID, err)
+		return
+	}
+
+	for {
+		imported, err := cfg.importChirpBatch(ctx, chirpImport, plan.MaxChirpLength)
+		if err != nil {
+			log.Printf("Error importing chirps for import %s: %v", chirpImport.ID, err)
+			err = cfg.db.FailChirpImport(ctx, database.FailChirpImportParams{
+				ID:    chirpImport.ID,
+				Error: sql.NullString{String: "Import stopped partway through, posts not yet imported can be brought over by importing the archive again", Valid: true},
+			})
+			if err != nil {
+				log.Printf("Error marking chirp import %s as failed: %v", chirpImport.ID, err)
+			}
+			return
+		}
+		if imported == 0 {
+			break
+		}
+	}
+
+	err = cfg.db.CompleteChirpImport(ctx, chirpImport.ID)
+	if err != nil {
+		log.Printf("Error marking chirp import %s as completed: %v", chirpImport.ID, err)
+	}
+}
+
+// helper that works through the next batch of pending posts in an import and reports how many it went through.
+// posts are taken oldest first, so a reply to an earlier post in the archive becomes a reply to its chirp
+func (cfg *apiConfig) importChirpBatch(ctx context.Context, chirpImport database.ChirpImport, maxChirpLength int) (int, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.db.WithTx(tx)
+	items, err := qtx.GetPendingChirpImportItems(ctx, database.GetPendingChirpImportItemsParams{
+		ImportID: chirpImport.ID,
+		Limit:    chirpImportBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range items {
+		result := database.MarkChirpImportItemParams{ID: item.ID}
+
+		// posts already brought over by an earlier import of the same archive are left alone
+		_, err = qtx.GetImportedChirpID(ctx, database.GetImportedChirpIDParams{
+			UserID:     chirpImport.UserID,
+			Source:     chirpImport.Source,
+			ExternalID: item.ExternalID,
+		})
+		if err == nil {
+			result.Status = importItemSkipped
+			result.Error = sql.NullString{String: "post was already imported", Valid: true}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+
+		chirpBody, validateErr := validateChirp(item.Body, maxChirpLength)
+		switch {
+		case result.Status != "":
+		case item.Body == "":
+			result.Status = importItemSkipped
+			result.Error = sql.NullString{String: "post has no text", Valid: true}
+		case validateErr != nil:
+			result.Status = importItemFailed
+			result.Error = sql.NullString{String: validateErr.Error(), Valid: true}
+		default:
+			replyToID := uuid.NullUUID{}
+			if item.ReplyToExternalID.Valid {
+				replyToID, err = qtx.GetImportedChirpID(ctx, database.GetImportedChirpIDParams{
+					UserID:     chirpImport.UserID,
+					Source:     chirpImport.Source,
+					ExternalID: item.ReplyToExternalID.String,
+				})
+				if err != nil && !errors.Is(err, sql.ErrNoRows) {
+					return 0, err
+				}
+			}
+
+			chirp, err := qtx.CreateImportedChirp(ctx, database.CreateImportedChirpParams{
+				CreatedAt: item.PostedAt,
+				UpdatedAt: item.PostedAt,
+				Body:      chirpBody,
+				UserID:    chirpImport.UserID,
+				ReplyToID: replyToID,
+			})
+			if err != nil {
+				return 0, err
+			}
+
+			// integrators hear about imported chirps like any other, followers aren't notified about old posts
+			err = recordChirpEvent(ctx, qtx, EventChirpCreated, chirp)
+			if err != nil {
+				return 0, err
+			}
+
+			result.Status = importItemImported
+			result.ChirpID = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+		}
+
+		err = qtx.MarkChirpImportItem(ctx, result)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = qtx.TouchChirpImport(ctx, chirpImport.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(items), tx.Commit()
+}
+
+// background job that sends queued outbound webhooks, claiming deliveries leases them for a few minutes
+// so several servers can run this at once without sending the same delivery twice
+func (cfg *apiConfig) runWebhookDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(webhookDeliveryInterval)
+	defer ticker.Stop()
+
+	for {
+		deliveries, err := cfg.db.ClaimDueWebhookDeliveries(ctx, webhookDeliveryBatchSize)
+		if err != nil {
+			log.Printf("Error claiming webhook deliveries: %v", err)
+		}
+		for _, delivery := range deliveries {
+			cfg.deliverWebhook(ctx, delivery)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) deliverWebhook(ctx context.Context, delivery database.WebhookDelivery) {
+	webhook, err := cfg.db.GetWebhookSubscriptionByID(ctx, delivery.SubscriptionID)
+	if err != nil {
+		log.Printf("Error loading webhook %s for delivery %s: %v", delivery.SubscriptionID, delivery.ID, err)
+		return
+	}
+
+	result, sendErr := webhooks.Send(ctx, cfg.webhookClient, webhooks.Delivery{
+		ID:        delivery.ID.String(),
+		EventType: delivery.EventType,
+		URL:       webhook.Url,
+		Secret:    webhook.Secret,
+		Payload:   delivery.Payload,
+	})
+
+	statusCode := sql.NullInt32{Int32: int32(result.StatusCode), Valid: result.StatusCode != 0}
+	errorMessage := sql.NullString{}
+	if sendErr != nil {
+		errorMessage = sql.NullString{String: sendErr.Error(), Valid: true}
+	}
+
+	err = cfg.db.CreateWebhookDeliveryAttempt(ctx, database.CreateWebhookDeliveryAttemptParams{
+		DeliveryID: delivery.ID,
+		StatusCode: statusCode,
+		Error:      errorMessage,
+		DurationMs: int32(result.Duration.Milliseconds()),
+	})
+	if err != nil {
+		log.Printf("Error logging attempt for webhook delivery %s: %v", delivery.ID, err)
+	}
+
+	if sendErr == nil {
+		err = cfg.db.MarkWebhookDeliverySucceeded(ctx, database.MarkWebhookDeliverySucceededParams{
+			ID:             delivery.ID,
+			LastStatusCode: statusCode,
+		})
+		if err != nil {
+			log.Printf("Error marking webhook delivery %s as succeeded: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	attempts := int(delivery.Attempts) + 1
+	status := deliveryStatusPending
+	if attempts >= webhooks.MaxAttempts {
+		status = deliveryStatusDead
+	}
+
+	err = cfg.db.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{
+		ID:             delivery.ID,
+		Status:         status,
+		NextAttemptAt:  time.Now().UTC().Add(webhooks.Backoff(attempts)),
+		LastStatusCode: statusCode,
+		LastError:      errorMessage,
+	})
+	if err != nil {
+		log.Printf("Error marking webhook delivery %s as failed: %v", delivery.ID, err)
+	}
+}
+
+// background job that publishes outbox events to the configured sinks, an event is marked published only after
+// every sink accepted it so delivery is at-least-once and consumers should dedupe on the event ID
+func (cfg *apiConfig) runOutboxRelay(ctx context.Context) {
+	ticker := time.NewTicker(outboxRelayInterval)
+	defer ticker.Stop()
+
+	// identifies this server's relay when taking and renewing the lease
+	holder := uuid.New()
+	lastCleanup := time.Time{}
+	for {
+		err := cfg.relayOutbox(ctx, holder)
+		if err != nil {
+			log.Printf("Error relaying outbox events: %v", err)
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			cutoff := sql.NullTime{Time: time.Now().UTC().Add(-outboxRetention), Valid: true}
+			_, err = cfg.db.DeletePublishedOutboxEvents(ctx, cutoff)
+			if err != nil {
+				log.Printf("Error cleaning up published outbox events: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// helper that relays one batch of outbox events, only the server holding the relay lease publishes so events
+// keep the order they were written in. No transaction is held while publishing, a slow sink only slows the relay
+func (cfg *apiConfig) relayOutbox(ctx context.Context, holder uuid.UUID) error {
+	acquired, err := cfg.db.AcquireOutboxRelayLease(ctx, database.AcquireOutboxRelayLeaseParams{
+		Holder:    holder,
+		ExpiresAt: time.Now().UTC().Add(outboxRelayLease),
+	})
+	if err != nil || acquired == 0 {
+		return err
+	}
+
+	events, err := cfg.db.GetUnpublishedOutboxEvents(ctx, outboxRelayBatchSize)
+	if err != nil {
+		return err
+	}
+
+	messages := []outbox.Message{}
+	for _, event := range events {
+		messages = append(messages, outbox.Message{
+			Sequence:      event.ID,
+			ID:            event.EventID.String(),
+			AggregateType: event.AggregateType,
+			AggregateID:   event.AggregateID.String(),
+			Type:          event.EventType,
+			Payload:       event.Payload,
+		})
+	}
+
+	attempts := map[int64]int32{}
+	for _, event := range events {
+		attempts[event.ID] = event.Attempts
+	}
+
+	// publishing stops well before the lease runs out so another server can't take over mid batch
+	publishCtx, cancel := context.WithTimeout(ctx, outboxRelayLease/2)
+	defer cancel()
+
+	for _, result := range outbox.Dispatch(publishCtx, cfg.outboxSink, messages) {
+		switch {
+		case result.Err == nil:
+			err = cfg.db.MarkOutboxEventPublished(ctx, result.Sequence)
+		case errors.Is(result.Err, outbox.ErrHeldBack), publishCtx.Err() != nil:
+			// running out of time isn't the event's fault, it's picked up again next batch
+			continue
+		default:
+			// once an event is given up on, the ones after it for the same aggregate are let through
+			failed := int(attempts[result.Sequence]) + 1
+			deadAt := sql.NullTime{}
+			if failed >= outbox.MaxAttempts {
+				deadAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+				log.Printf("Giving up on outbox event %d after %d attempts: %v", result.Sequence, failed, result.Err)
+			} else {
+				log.Printf("Error publishing outbox event %d: %v", result.Sequence, result.Err)
+			}
+			err = cfg.db.MarkOutboxEventFailed(ctx, database.MarkOutboxEventFailedParams{
+				ID:            result.Sequence,
+				LastError:     sql.NullString{String: result.Err.Error(), Valid: true},
+				NextAttemptAt: time.Now().UTC().Add(outbox.RetryDelay(failed)),
+				DeadAt:        deadAt,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// background job that listens for stream messages sent by any server and passes them to this server's hub,
+// which is what lets a chirp posted on one server reach clients connected to another
+func (cfg *apiConfig) runStreamListener(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Stream listener connection problem: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	err := listener.Listen(streamChannel)
+	if err != nil {
+		log.Printf("Error listening on %s: %v", streamChannel, err)
+		return
+	}
+
+	ticker := time.NewTicker(streamListenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			listener.Ping()
+		case notification := <-listener.Notify:
+			// nil means the connection was re-established, anything sent in between is caught up by clients
+			// reconnecting with Last-Event-ID
+			if notification == nil {
+				continue
+			}
+
+			msg := pubsub.Message{}
+			err = json.Unmarshal([]byte(notification.Extra), &msg)
+			if err != nil {
+				log.Printf("Error decoding stream message: %v", err)
+				continue
+			}
+			cfg.hub.Publish(msg)
+		}
+	}
+}
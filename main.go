@@ -1,13 +1,25 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/entitlements"
+	"github.com/Khazz0r/chirpy/internal/mailer"
+	"github.com/Khazz0r/chirpy/internal/outbox"
+	"github.com/Khazz0r/chirpy/internal/pubsub"
+	"github.com/Khazz0r/chirpy/internal/ratelimit"
+	"github.com/Khazz0r/chirpy/internal/webauthn"
+	"github.com/Khazz0r/chirpy/internal/webhooks"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -15,9 +27,19 @@ import (
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	dbConn         *sql.DB
 	platform       string
 	jwtSecret      string
 	polkaKey       string
+	webauthn       webauthn.RelyingParty
+	plans          entitlements.Plans
+	chirpLimiter   *ratelimit.Limiter
+	webhookClient  *http.Client
+	outboxSink     outbox.Sink
+	hub            *pubsub.Hub
+	mailer         mailer.Sender
+	deletionGrace  time.Duration
+	websockets     sync.WaitGroup
 }
 
 func main() {
@@ -42,6 +64,51 @@ func main() {
 	if polkaKey == "" {
 		log.Fatal("Polka API key must be set")
 	}
+	// passkeys are bound to the site they were made on, so these default to the local dev server
+	webauthnRPID := os.Getenv("WEBAUTHN_RP_ID")
+	if webauthnRPID == "" {
+		webauthnRPID = "localhost"
+	}
+	webauthnOrigin := os.Getenv("WEBAUTHN_ORIGIN")
+	if webauthnOrigin == "" {
+		webauthnOrigin = "http://localhost:8080"
+	}
+
+	// plans can be tuned without a deploy by pointing PLANS_CONFIG at a JSON file
+	plans := entitlements.DefaultPlans()
+	if plansConfig := os.Getenv("PLANS_CONFIG"); plansConfig != "" {
+		plans, err = entitlements.LoadPlans(plansConfig)
+		if err != nil {
+			log.Fatalf("error loading plans config: %v", err)
+		}
+	}
+
+	// NATS and Kafka only receive events when configured, the in-process bus always runs
+	natsURL := os.Getenv("NATS_URL")
+	kafkaRESTURL := os.Getenv("KAFKA_REST_URL")
+	kafkaTopic := os.Getenv("KAFKA_TOPIC")
+	if kafkaTopic == "" {
+		kafkaTopic = "chirpy-events"
+	}
+
+	// deactivated accounts can be restored by logging in until this runs out
+	deletionGrace := 30 * 24 * time.Hour
+	if graceStr := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); graceStr != "" {
+		deletionGrace, err = time.ParseDuration(graceStr)
+		if err != nil || deletionGrace < 0 {
+			log.Fatalf("ACCOUNT_DELETION_GRACE_PERIOD must be a duration like 720h: %v", err)
+		}
+	}
+
+	// emails are only logged unless an SMTP server is configured
+	var mailSender mailer.Sender = mailer.LogSender{}
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		mailFrom := os.Getenv("MAIL_FROM")
+		if mailFrom == "" {
+			log.Fatal("MAIL_FROM must be set when SMTP_ADDR is")
+		}
+		mailSender = mailer.NewSMTPSender(smtpAddr, mailFrom, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	}
 
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -53,10 +120,33 @@ func main() {
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
 		db:             dbQueries,
+		dbConn:         db,
 		platform:       platform,
 		jwtSecret:      jwtSecret,
 		polkaKey:       polkaKey,
+		webauthn: webauthn.RelyingParty{
+			ID:     webauthnRPID,
+			Name:   "Chirpy",
+			Origin: webauthnOrigin,
+		},
+		plans:         plans,
+		chirpLimiter:  ratelimit.New(time.Minute),
+		webhookClient: webhooks.NewClient(10 * time.Second),
+		hub:           pubsub.NewHub(),
+		mailer:        mailSender,
+		deletionGrace: deletionGrace,
+	}
+
+	bus := outbox.NewBus()
+	bus.Subscribe("*", apiCfg.enqueueWebhookDeliveries)
+	sinks := outbox.Fanout{bus}
+	if natsURL != "" {
+		sinks = append(sinks, outbox.NewNATSSink(natsURL, "chirpy"))
 	}
+	if kafkaRESTURL != "" {
+		sinks = append(sinks, outbox.NewKafkaRESTSink(kafkaRESTURL, kafkaTopic, &http.Client{Timeout: 10 * time.Second}))
+	}
+	apiCfg.outboxSink = sinks
 
 	mux := http.NewServeMux()
 	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
@@ -68,23 +158,160 @@ func main() {
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevokeToken)
 
 	mux.HandleFunc("POST /api/users", apiCfg.handlerCreateUser)
-	mux.HandleFunc("PUT /api/users", apiCfg.handlerUpdateProfile)
+	mux.HandleFunc("PATCH /api/users/me", apiCfg.handlerPatchUser)
+	mux.HandleFunc("DELETE /api/users/me", apiCfg.handlerDeleteOwnAccount)
+	mux.HandleFunc("POST /api/users/email/confirm", apiCfg.handlerConfirmEmailChange)
+	mux.HandleFunc("GET /api/users/me/subscription", apiCfg.handlerGetSubscription)
+	mux.HandleFunc("GET /api/users/{handle}", apiCfg.handlerGetProfile)
+	mux.HandleFunc("PUT /api/users/me/profile", apiCfg.handlerUpdateProfileFields)
+	mux.HandleFunc("PUT /api/users/me/handle", apiCfg.handlerChangeHandle)
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.handlerFollowUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.handlerUnfollowUser)
+	mux.HandleFunc("GET /api/users/me/drafts", apiCfg.handlerGetDrafts)
+	mux.HandleFunc("PUT /api/users/me/drafts/{chirpID}", apiCfg.handlerUpdateDraft)
+	mux.HandleFunc("DELETE /api/users/me/drafts/{chirpID}", apiCfg.handlerDeleteDraft)
+	mux.HandleFunc("PUT /api/users/me/protected", apiCfg.handlerUpdateProtected)
+	mux.HandleFunc("GET /api/users/me/follow_requests", apiCfg.handlerGetFollowRequests)
+	mux.HandleFunc("POST /api/users/me/follow_requests/{userID}/approve", apiCfg.handlerApproveFollowRequest)
+	mux.HandleFunc("DELETE /api/users/me/follow_requests/{userID}", apiCfg.handlerDenyFollowRequest)
+	mux.HandleFunc("POST /api/users/{userID}/block", apiCfg.handlerBlockUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.handlerUnblockUser)
+	mux.HandleFunc("POST /api/users/{userID}/mute", apiCfg.handlerMuteUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", apiCfg.handlerUnmuteUser)
+	mux.HandleFunc("GET /api/users/me/blocks", apiCfg.handlerGetBlocks)
+	mux.HandleFunc("GET /api/users/me/mutes", apiCfg.handlerGetMutes)
+	mux.HandleFunc("GET /api/users/me/muted_keywords", apiCfg.handlerGetMutedKeywords)
+	mux.HandleFunc("POST /api/users/me/muted_keywords", apiCfg.handlerCreateMutedKeyword)
+	mux.HandleFunc("DELETE /api/users/me/muted_keywords/{keywordID}", apiCfg.handlerDeleteMutedKeyword)
+
+	mux.HandleFunc("PUT /api/users/me/dms", apiCfg.handlerUpdateDMSettings)
+
+	mux.HandleFunc("POST /api/users/me/export", apiCfg.handlerRequestDataExport)
+	mux.HandleFunc("GET /api/users/me/exports", apiCfg.handlerGetDataExports)
+	mux.HandleFunc("GET /api/users/me/exports/{exportID}", apiCfg.handlerGetDataExport)
+	mux.HandleFunc("GET /api/exports/{exportID}/download", apiCfg.handlerDownloadDataExport)
+
+	mux.HandleFunc("POST /api/users/me/import", apiCfg.handlerCreateChirpImport)
+	mux.HandleFunc("GET /api/users/me/imports", apiCfg.handlerGetChirpImports)
+	mux.HandleFunc("GET /api/users/me/imports/{importID}", apiCfg.handlerGetChirpImport)
+	mux.HandleFunc("GET /api/users/me/imports/{importID}/items", apiCfg.handlerGetChirpImportItems)
+
+	mux.HandleFunc("POST /api/conversations", apiCfg.handlerCreateConversation)
+	mux.HandleFunc("GET /api/conversations", apiCfg.handlerGetConversations)
+	mux.HandleFunc("DELETE /api/conversations/{conversationID}", apiCfg.handlerDeleteConversation)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.handlerSendMessage)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.handlerGetMessages)
+	mux.HandleFunc("DELETE /api/conversations/{conversationID}/messages/{messageID}", apiCfg.handlerDeleteMessage)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.handlerMarkConversationRead)
+
+	mux.HandleFunc("GET /api/notifications", apiCfg.handlerGetNotifications)
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.handlerMarkAllNotificationsRead)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", apiCfg.handlerMarkNotificationRead)
+	mux.HandleFunc("GET /api/notifications/preferences", apiCfg.handlerGetNotificationPreferences)
+	mux.HandleFunc("PUT /api/notifications/preferences", apiCfg.handlerUpdateNotificationPreferences)
+
+	mux.HandleFunc("POST /api/reports", apiCfg.handlerCreateReport)
+	mux.HandleFunc("GET /api/moderation/reports", apiCfg.handlerGetReportQueue)
+	mux.HandleFunc("GET /api/moderation/reports/{reportID}", apiCfg.handlerGetReport)
+	mux.HandleFunc("POST /api/moderation/reports/{reportID}/claim", apiCfg.handlerClaimReport)
+	mux.HandleFunc("DELETE /api/moderation/reports/{reportID}/claim", apiCfg.handlerReleaseReport)
+	mux.HandleFunc("POST /api/moderation/reports/{reportID}/actions", apiCfg.handlerCreateReportAction)
+	mux.HandleFunc("POST /api/moderation/reports/{reportID}/resolve", apiCfg.handlerResolveReport)
+	mux.HandleFunc("POST /api/moderation/users/{userID}/suspend", apiCfg.handlerSuspendUser)
+	mux.HandleFunc("DELETE /api/moderation/users/{userID}/suspend", apiCfg.handlerLiftSuspension)
+	mux.HandleFunc("POST /api/moderation/users/{userID}/ban", apiCfg.handlerBanUser)
+	mux.HandleFunc("DELETE /api/moderation/users/{userID}/ban", apiCfg.handlerUnbanUser)
+
 	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
 
+	mux.HandleFunc("POST /api/passkeys/register/begin", apiCfg.handlerBeginPasskeyRegistration)
+	mux.HandleFunc("POST /api/passkeys/register/finish", apiCfg.handlerFinishPasskeyRegistration)
+	mux.HandleFunc("POST /api/passkeys/login/begin", apiCfg.handlerBeginPasskeyLogin)
+	mux.HandleFunc("POST /api/passkeys/login/finish", apiCfg.handlerFinishPasskeyLogin)
+
 	mux.HandleFunc("POST /api/chirps", apiCfg.handlerCreateChirp)
 	mux.HandleFunc("GET /api/chirps", apiCfg.handlerGetAllChirps)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerGetChirp)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.handlerEditChirp)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", apiCfg.handlerRestoreChirp)
+	mux.HandleFunc("GET /api/users/me/deleted_chirps", apiCfg.handlerGetDeletedChirps)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.handlerLikeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.handlerUnlikeChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.handlerRechirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.handlerUndoRechirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/votes", apiCfg.handlerVotePoll)
+
+	mux.HandleFunc("GET /api/stream/chirps", apiCfg.handlerStreamChirps)
+	mux.HandleFunc("GET /api/ws", apiCfg.handlerWebSocket)
+
+	mux.HandleFunc("POST /api/webhooks", apiCfg.handlerCreateWebhook)
+	mux.HandleFunc("GET /api/webhooks", apiCfg.handlerGetWebhooks)
+	mux.HandleFunc("DELETE /api/webhooks/{webhookID}", apiCfg.handlerDeleteWebhook)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", apiCfg.handlerGetWebhookDeliveries)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries/{deliveryID}", apiCfg.handlerGetWebhookDelivery)
+	mux.HandleFunc("POST /api/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", apiCfg.handlerRedeliverWebhook)
 
 	mux.HandleFunc("POST /admin/reset", apiCfg.handlerDeleteAllUsers)
 	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerNumOfRequests)
+	mux.HandleFunc("PUT /admin/users/{userID}/role", apiCfg.handlerSetUserRole)
+
+	mux.HandleFunc("GET /admin/audit", apiCfg.handlerGetAuditLog)
+	mux.HandleFunc("GET /admin/audit/export", apiCfg.handlerExportAuditLog)
+	mux.HandleFunc("GET /admin/audit/verify", apiCfg.handlerVerifyAuditLog)
+
+	mux.HandleFunc("GET /admin/webhooks/events", apiCfg.handlerGetWebhookEvents)
+	mux.HandleFunc("POST /admin/webhooks/events/{eventID}/replay", apiCfg.handlerReplayWebhookEvent)
 
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerUpgradeUser)
 
+	// background jobs stop when the server is asked to shut down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go apiCfg.runSubscriptionExpiry(ctx)
+	go apiCfg.runWebhookDeliveries(ctx)
+	go apiCfg.runOutboxRelay(ctx)
+	go apiCfg.runAccountDeletion(ctx)
+	go apiCfg.runDataExports(ctx)
+	go apiCfg.runChirpImports(ctx)
+	go apiCfg.runDeletedChirpPurge(ctx)
+	go apiCfg.runScheduledChirps(ctx)
+	go apiCfg.runExpiredChirpReaper(ctx)
+	go apiCfg.runPollCloser(ctx)
+	go apiCfg.runStreamListener(ctx, dbURL)
+
 	server := http.Server{
-		Handler: mux,
+		Handler: middlewareRequestID(apiCfg.middlewareAccountStanding(mux)),
 		Addr:    ":8080",
 	}
 
-	server.ListenAndServe()
+	// Shutdown waits for open connections but won't end streams on its own, closing the hub does that
+	server.RegisterOnShutdown(apiCfg.hub.Close)
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+
+		// websockets are hijacked from the server so Shutdown doesn't wait for them to drain
+		drained := make(chan struct{})
+		go func() {
+			apiCfg.websockets.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-shutdownCtx.Done():
+		}
+	}()
+
+	err = server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatalf("error running server: %v", err)
+	}
+	<-shutdownDone
 }
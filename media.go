@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/entitlements"
+	"github.com/google/uuid"
+)
+
+const maxMediaURLLength = 2048
+
+// errMediaNotAllowed is returned when a chirp has more media than the author's plan allows
+var errMediaNotAllowed = errors.New("too many media for plan")
+
+// helper that checks the media a chirp is being created with, each one is a link to an image or video hosted
+// elsewhere and how many a chirp can have depends on the author's plan
+func validateChirpMedia(media []string, plan entitlements.Plan) error {
+	if len(media) == 0 {
+		return nil
+	}
+	if !plan.Can(entitlements.CapabilityAttachMedia) || len(media) > plan.MaxMediaPerChirp {
+		return errMediaNotAllowed
+	}
+
+	for _, mediaURL := range media {
+		parsed, err := url.Parse(mediaURL)
+		if err != nil || len(mediaURL) > maxMediaURLLength || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.New("Media must be absolute http or https URLs")
+		}
+	}
+
+	return nil
+}
+
+// helper that saves a chirp's media in the order it was given, should be given the transaction creating the chirp
+func addChirpMedia(ctx context.Context, db *database.Queries, chirpID uuid.UUID, media []string) error {
+	for i, mediaURL := range media {
+		err := db.AddChirpMedia(ctx, database.AddChirpMediaParams{
+			ChirpID:  chirpID,
+			Position: int32(i),
+			Url:      mediaURL,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// helper that fills in the media of a page of chirps with one query
+func attachMedia(ctx context.Context, db *database.Queries, chirps []Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	media, err := db.GetMediaByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	mediaByChirp := map[uuid.UUID][]string{}
+	for _, medium := range media {
+		mediaByChirp[medium.ChirpID] = append(mediaByChirp[medium.ChirpID], medium.Url)
+	}
+	for i := range chirps {
+		chirps[i].Media = mediaByChirp[chirps[i].ID]
+	}
+
+	return nil
+}
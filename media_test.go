@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Khazz0r/chirpy/internal/entitlements"
+)
+
+func TestValidateChirpMedia(t *testing.T) {
+	plans := entitlements.DefaultPlans()
+	free := plans.ForUser(false)
+	red := plans.ForUser(true)
+	noMedia := entitlements.Plan{Name: "text_only", MaxChirpLength: 140, ChirpsPerMinute: 10}
+
+	tests := []struct {
+		name    string
+		media   []string
+		plan    entitlements.Plan
+		wantErr error
+		invalid bool
+	}{
+		{name: "no media", plan: noMedia},
+		{name: "one on free", media: []string{"https://example.com/a.png"}, plan: free},
+		{name: "two on free", media: []string{"https://example.com/a.png", "https://example.com/b.png"}, plan: free, wantErr: errMediaNotAllowed},
+		{name: "four on red", media: []string{"https://a.example/1", "https://a.example/2", "http://a.example/3", "https://a.example/4"}, plan: red},
+		{name: "five on red", media: []string{"https://a.example/1", "https://a.example/2", "https://a.example/3", "https://a.example/4", "https://a.example/5"}, plan: red, wantErr: errMediaNotAllowed},
+		{name: "plan without media", media: []string{"https://example.com/a.png"}, plan: noMedia, wantErr: errMediaNotAllowed},
+		{name: "relative URL", media: []string{"/a.png"}, plan: red, invalid: true},
+		{name: "other scheme", media: []string{"javascript:alert(1)"}, plan: red, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateChirpMedia(tt.media, tt.plan)
+			if tt.invalid {
+				if err == nil || errors.Is(err, errMediaNotAllowed) {
+					t.Errorf("validateChirpMedia() = %v, want an invalid URL error", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateChirpMedia() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
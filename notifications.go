@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/keywords"
+	"github.com/google/uuid"
+)
+
+// notification types, each one can be switched off in a user's preferences
+const (
+	notificationFollow         = "follow"
+	notificationFollowRequest  = "follow_request"
+	notificationFollowAccepted = "follow_accepted"
+	notificationReply          = "reply"
+	notificationMention        = "mention"
+	notificationLike           = "like"
+	notificationRechirp        = "rechirp"
+	notificationQuote          = "quote"
+)
+
+// notifications Chirpy sends itself, they aren't from another user so they can't be switched off
+const (
+	notificationExportReady       = "export_ready"
+	notificationReportResolved    = "report_resolved"
+	notificationModerationWarning = "moderation_warning"
+	notificationPollClosed        = "poll_closed"
+)
+
+var notificationTypes = []string{
+	notificationFollow,
+	notificationFollowRequest,
+	notificationFollowAccepted,
+	notificationReply,
+	notificationMention,
+	notificationLike,
+	notificationRechirp,
+	notificationQuote,
+}
+
+type Notification struct {
+	ID         uuid.UUID   `json:"id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	Type       string      `json:"type"`
+	ChirpID    *uuid.UUID  `json:"chirp_id"`
+	ActorIDs   []uuid.UUID `json:"actor_ids"`
+	ActorCount int64       `json:"actor_count"`
+	Summary    string      `json:"summary"`
+	Read       bool        `json:"read"`
+}
+
+func isNotificationType(notificationType string) bool {
+	return slices.Contains(notificationTypes, notificationType)
+}
+
+// helper that tells a user about something another user did, while unread everything with the same group key
+// collapses into one notification, should be given the transaction's queries like recordEvent
+func notifyUser(ctx context.Context, db *database.Queries, recipientID, actorID uuid.UUID, notificationType string, chirpID uuid.NullUUID, groupKey string) error {
+	if recipientID == actorID {
+		return nil
+	}
+
+	// blocks either way and the recipient's mutes drop the notification entirely
+	suppressed, err := db.IsNotificationSuppressed(ctx, database.IsNotificationSuppressedParams{
+		RecipientID: recipientID,
+		ActorID:     actorID,
+	})
+	if err != nil || suppressed {
+		return err
+	}
+
+	// replies and mentions are the actor's own chirp, so the recipient's muted keywords apply to it
+	if chirpID.Valid && (notificationType == notificationReply || notificationType == notificationMention) {
+		chirp, err := db.GetChirpByID(ctx, chirpID.UUID)
+		if err != nil {
+			return err
+		}
+		mutedKeywords, err := db.GetActiveMutedKeywords(ctx, recipientID)
+		if err != nil {
+			return err
+		}
+		muted := []string{}
+		for _, mutedKeyword := range mutedKeywords {
+			muted = append(muted, mutedKeyword.Keyword)
+		}
+		if keywords.Match(chirp.Body, muted) {
+			return nil
+		}
+	}
+
+	enabled, err := db.IsNotificationEnabled(ctx, database.IsNotificationEnabledParams{
+		UserID: recipientID,
+		Type:   notificationType,
+	})
+	if err != nil || !enabled {
+		return err
+	}
+
+	notification, err := db.UpsertNotification(ctx, database.UpsertNotificationParams{
+		UserID:   recipientID,
+		Type:     notificationType,
+		GroupKey: groupKey,
+		ChirpID:  chirpID,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = db.AddNotificationActor(ctx, database.AddNotificationActorParams{
+		NotificationID: notification.ID,
+		ActorID:        actorID,
+	})
+	if err != nil {
+		return err
+	}
+
+	structuredNotification := Notification{
+		ID:        notification.ID,
+		CreatedAt: notification.CreatedAt,
+		UpdatedAt: notification.UpdatedAt,
+		Type:      notification.Type,
+		ActorIDs:  []uuid.UUID{actorID},
+	}
+	if notification.ChirpID.Valid {
+		structuredNotification.ChirpID = &notification.ChirpID.UUID
+	}
+
+	return publishToStream(ctx, db, notificationsTopic(recipientID), notification.ID.String(), structuredNotification)
+}
+
+// helper for notifications Chirpy sends about the user's own account rather than someone else's actions,
+// should be given the transaction's queries like recordEvent
+func notifySystem(ctx context.Context, db *database.Queries, recipientID uuid.UUID, notificationType string, chirpID uuid.NullUUID, groupKey string) error {
+	notification, err := db.UpsertNotification(ctx, database.UpsertNotificationParams{
+		UserID:   recipientID,
+		Type:     notificationType,
+		GroupKey: groupKey,
+		ChirpID:  chirpID,
+	})
+	if err != nil {
+		return err
+	}
+
+	structuredNotification := Notification{
+		ID:        notification.ID,
+		CreatedAt: notification.CreatedAt,
+		UpdatedAt: notification.UpdatedAt,
+		Type:      notification.Type,
+		ActorIDs:  []uuid.UUID{},
+		Summary:   notificationSummary(notification.Type, 0),
+	}
+	if notification.ChirpID.Valid {
+		structuredNotification.ChirpID = &notification.ChirpID.UUID
+	}
+
+	return publishToStream(ctx, db, notificationsTopic(recipientID), notification.ID.String(), structuredNotification)
+}
+
+// helper that describes a notification, collapsed ones read like "12 people liked your chirp"
+func notificationSummary(notificationType string, actorCount int64) string {
+	who := "Someone"
+	if actorCount > 1 {
+		who = fmt.Sprintf("%d people", actorCount)
+	}
+
+	switch notificationType {
+	case notificationFollow:
+		return who + " followed you"
+	case notificationFollowRequest:
+		return who + " asked to follow you"
+	case notificationFollowAccepted:
+		return who + " approved your follow request"
+	case notificationReply:
+		return who + " replied to your chirp"
+	case notificationMention:
+		return who + " mentioned you"
+	case notificationLike:
+		return who + " liked your chirp"
+	case notificationRechirp:
+		return who + " rechirped your chirp"
+	case notificationQuote:
+		return who + " quoted your chirp"
+	case notificationExportReady:
+		return "Your data export is ready to download"
+	case notificationReportResolved:
+		return "A moderator reviewed your report"
+	case notificationModerationWarning:
+		return "A moderator warned you about your content"
+	case notificationPollClosed:
+		return "Your poll has closed, see the results"
+	default:
+		return who + " interacted with you"
+	}
+}
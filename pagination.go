@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// cursors point just past the last item on a page of results sorted newest first, they're opaque to clients
+func encodeCursor(sortedAt time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sortedAt.Format(time.RFC3339Nano) + "," + id.String()))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+
+	timePart, idPart, found := strings.Cut(string(decoded), ",")
+	if !found {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+
+	sortedAt, err := time.Parse(time.RFC3339Nano, timePart)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	id, err := uuid.Parse(idPart)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+
+	return sortedAt, id, nil
+}
+
+// helper for the cursor of a first page, it sits after the newest possible item
+func firstPageCursor() (time.Time, uuid.UUID) {
+	return time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC), uuid.Max
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// QuotedChirp is the compact copy of a quoted chirp embedded in the quote, when the viewer can't see it any
+// more (deleted, expired, hidden from them or behind a block) only its ID is kept and unavailable is set
+type QuotedChirp struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	Body        string     `json:"body,omitempty"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	Unavailable bool       `json:"unavailable,omitempty"`
+}
+
+// helper that embeds quoted chirps as the viewer gets to see them and counts how often each chirp has been
+// rechirped and quoted, uuid.Nil is someone who isn't logged in
+func attachQuotes(ctx context.Context, db *database.Queries, viewerID uuid.UUID, chirps []Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := []uuid.UUID{}
+	quotedIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+		if chirp.QuoteOf != nil {
+			quotedIDs = append(quotedIDs, *chirp.QuoteOf)
+		}
+	}
+
+	counts, err := db.GetChirpShareCounts(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+	countsByChirp := map[uuid.UUID]database.GetChirpShareCountsRow{}
+	for _, count := range counts {
+		countsByChirp[count.ID] = count
+	}
+
+	visibleQuoted := map[uuid.UUID]database.Chirp{}
+	if len(quotedIDs) > 0 {
+		quoted, err := db.GetVisibleChirpsByIDs(ctx, database.GetVisibleChirpsByIDsParams{
+			Ids:      quotedIDs,
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, chirp := range quoted {
+			visibleQuoted[chirp.ID] = chirp
+		}
+	}
+
+	for i := range chirps {
+		chirps[i].RechirpCount = countsByChirp[chirps[i].ID].RechirpCount
+		chirps[i].QuoteCount = countsByChirp[chirps[i].ID].QuoteCount
+
+		if chirps[i].QuoteOf == nil {
+			continue
+		}
+		quoted, ok := visibleQuoted[*chirps[i].QuoteOf]
+		if !ok {
+			chirps[i].Quoted = &QuotedChirp{
+				ID:          *chirps[i].QuoteOf,
+				Unavailable: true,
+			}
+			continue
+		}
+		chirps[i].Quoted = &QuotedChirp{
+			ID:        quoted.ID,
+			CreatedAt: &quoted.CreatedAt,
+			Body:      quoted.Body,
+			UserID:    &quoted.UserID,
+		}
+	}
+
+	return nil
+}
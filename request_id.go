@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+type requestInfoKey struct{}
+
+// requestInfo is what's known about the request a change came from, it ends up in the audit log
+type requestInfo struct {
+	ID string
+	IP string
+}
+
+// request IDs handed to us by a proxy are kept so logs line up, as long as they look like an ID
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// middleware that gives every request an ID, sent back in X-Request-ID, and remembers who it came from
+func middlewareRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requestID := req.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ip, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			ip = req.RemoteAddr
+		}
+
+		ctx := context.WithValue(req.Context(), requestInfoKey{}, requestInfo{ID: requestID, IP: ip})
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// helper that gets the request info back out of a context, background jobs don't have any
+func requestInfoFromContext(ctx context.Context) requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(requestInfo)
+	return info
+}
@@ -0,0 +1,43 @@
+-- name: LockAuditLog :exec
+SELECT pg_advisory_xact_lock(hashtext('audit_log'));
+
+-- name: GetLastAuditHash :one
+SELECT hash FROM audit_log
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: CreateAuditEntry :one
+INSERT INTO audit_log (created_at, actor_type, actor_id, action, target_type, target_id, before, after, ip, request_id, prev_hash, hash)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12
+)
+RETURNING *;
+
+-- name: GetAuditLog :many
+SELECT * FROM audit_log
+WHERE (sqlc.arg(actor_id)::text = '' OR audit_log.actor_id = sqlc.arg(actor_id)::text)
+AND (sqlc.arg(action)::text = '' OR audit_log.action = sqlc.arg(action)::text)
+AND (sqlc.arg(target_type)::text = '' OR audit_log.target_type = sqlc.arg(target_type)::text)
+AND (sqlc.arg(target_id)::text = '' OR audit_log.target_id = sqlc.arg(target_id)::text)
+AND audit_log.created_at >= sqlc.arg(since)::timestamp
+AND audit_log.created_at < sqlc.arg(until)::timestamp
+AND audit_log.id < sqlc.arg(before_id)::bigint
+ORDER BY audit_log.id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetAuditLogAfter :many
+SELECT * FROM audit_log
+WHERE audit_log.id > $1
+ORDER BY audit_log.id
+LIMIT $2;
@@ -0,0 +1,96 @@
+-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: GetBlockedIDs :many
+SELECT blocked_id FROM blocks
+WHERE blocks.blocker_id = $1
+ORDER BY created_at DESC;
+
+-- name: LockUserPair :exec
+-- serializes blocking against following between two users, whichever commits first the other sees it
+SELECT pg_advisory_xact_lock(hashtext(
+    'user_pair:' || LEAST(sqlc.arg(user_id)::uuid, sqlc.arg(other_user_id)::uuid)::text
+    || ':' || GREATEST(sqlc.arg(user_id)::uuid, sqlc.arg(other_user_id)::uuid)::text
+));
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = sqlc.arg(user_id)::uuid AND blocked_id = sqlc.arg(other_user_id)::uuid)
+    OR (blocker_id = sqlc.arg(other_user_id)::uuid AND blocked_id = sqlc.arg(user_id)::uuid)
+)::boolean AS blocked;
+
+-- name: GetBlockRelatedIDs :many
+SELECT blocked_id AS user_id FROM blocks WHERE blocks.blocker_id = sqlc.arg(user_id)::uuid
+UNION
+SELECT blocker_id AS user_id FROM blocks WHERE blocks.blocked_id = sqlc.arg(user_id)::uuid;
+
+-- name: RemoveFollowsBetween :exec
+WITH removed_requests AS (
+    DELETE FROM follow_requests
+    WHERE (follower_id = sqlc.arg(user_id)::uuid AND followee_id = sqlc.arg(other_user_id)::uuid)
+    OR (follower_id = sqlc.arg(other_user_id)::uuid AND followee_id = sqlc.arg(user_id)::uuid)
+)
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg(user_id)::uuid AND followee_id = sqlc.arg(other_user_id)::uuid)
+OR (follower_id = sqlc.arg(other_user_id)::uuid AND followee_id = sqlc.arg(user_id)::uuid);
+
+-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutedIDs :many
+SELECT muted_id FROM mutes
+WHERE mutes.muter_id = $1
+ORDER BY created_at DESC;
+
+-- name: IsNotificationSuppressed :one
+SELECT (
+    EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocker_id = sqlc.arg(recipient_id)::uuid AND blocked_id = sqlc.arg(actor_id)::uuid)
+        OR (blocker_id = sqlc.arg(actor_id)::uuid AND blocked_id = sqlc.arg(recipient_id)::uuid)
+    )
+    OR EXISTS (SELECT 1 FROM mutes WHERE muter_id = sqlc.arg(recipient_id)::uuid AND muted_id = sqlc.arg(actor_id)::uuid)
+)::boolean AS suppressed;
+
+-- name: UpsertMutedKeyword :one
+INSERT INTO muted_keywords (id, created_at, user_id, keyword, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, keyword) DO UPDATE
+SET expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: GetActiveMutedKeywords :many
+SELECT * FROM muted_keywords
+WHERE muted_keywords.user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at;
+
+-- name: DeleteMutedKeyword :exec
+DELETE FROM muted_keywords
+WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,107 @@
+-- name: CreateChirpImport :one
+INSERT INTO chirp_imports (id, created_at, updated_at, user_id, source, status)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    'pending'
+)
+RETURNING *;
+
+-- name: CreateChirpImportItem :exec
+INSERT INTO chirp_import_items (id, import_id, position, external_id, reply_to_external_id, posted_at, body, status, error)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+);
+
+-- name: GetActiveChirpImport :one
+SELECT * FROM chirp_imports
+WHERE chirp_imports.user_id = $1 AND status IN ('pending', 'processing')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetChirpImportByID :one
+SELECT * FROM chirp_imports
+WHERE chirp_imports.id = $1;
+
+-- name: GetChirpImportsByUserID :many
+SELECT * FROM chirp_imports
+WHERE chirp_imports.user_id = $1
+ORDER BY created_at DESC
+LIMIT 20;
+
+-- name: ClaimChirpImport :one
+UPDATE chirp_imports
+SET status = 'processing', updated_at = NOW()
+WHERE id = (
+    SELECT pending.id FROM chirp_imports AS pending
+    WHERE pending.status = 'pending'
+    OR (pending.status = 'processing' AND pending.updated_at < NOW() - INTERVAL '10 minutes')
+    ORDER BY pending.created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: TouchChirpImport :exec
+UPDATE chirp_imports
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: CompleteChirpImport :exec
+UPDATE chirp_imports
+SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailChirpImport :exec
+UPDATE chirp_imports
+SET status = 'failed', error = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetPendingChirpImportItems :many
+SELECT * FROM chirp_import_items
+WHERE chirp_import_items.import_id = $1 AND status = 'pending'
+ORDER BY position
+LIMIT $2;
+
+-- name: GetChirpImportItems :many
+SELECT * FROM chirp_import_items
+WHERE chirp_import_items.import_id = sqlc.arg(import_id)::uuid
+AND (sqlc.arg(status)::text = '' OR chirp_import_items.status = sqlc.arg(status)::text)
+AND chirp_import_items.position > sqlc.arg(after_position)::int
+ORDER BY position
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: MarkChirpImportItem :exec
+UPDATE chirp_import_items
+SET status = $2, error = $3, chirp_id = $4
+WHERE id = $1;
+
+-- name: GetImportedChirpID :one
+SELECT chirp_import_items.chirp_id FROM chirp_import_items
+JOIN chirp_imports ON chirp_imports.id = chirp_import_items.import_id
+WHERE chirp_imports.user_id = sqlc.arg(user_id)::uuid
+AND chirp_imports.source = sqlc.arg(source)::text
+AND chirp_import_items.external_id = sqlc.arg(external_id)::text
+AND chirp_import_items.status = 'imported'
+LIMIT 1;
+
+-- name: GetChirpImportProgress :one
+SELECT
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE status = 'pending') AS pending,
+    COUNT(*) FILTER (WHERE status = 'imported') AS imported,
+    COUNT(*) FILTER (WHERE status = 'skipped') AS skipped,
+    COUNT(*) FILTER (WHERE status = 'failed') AS failed
+FROM chirp_import_items
+WHERE chirp_import_items.import_id = $1;
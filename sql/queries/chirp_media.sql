@@ -0,0 +1,12 @@
+-- name: AddChirpMedia :exec
+INSERT INTO chirp_media (chirp_id, position, url)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: GetMediaByChirpIDs :many
+SELECT * FROM chirp_media
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
@@ -1,26 +1,229 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id, visibility, status, publish_at, expires_in, expires_at, quote_of_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: CreateImportedChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
 )
 RETURNING *;
 
 -- name: GetAllChirps :many
-SELECT * FROM chirps
-ORDER BY created_at;
+-- only public chirps are listed here, the rest are found through their author, a thread or the timeline
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.deactivated_at IS NULL AND chirps.status = 'published'
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND (sqlc.arg(include_deleted)::boolean OR chirps.deleted_at IS NULL)
+AND (chirps.visibility = 'public' OR chirps.user_id = sqlc.arg(viewer_id)::uuid)
+AND (chirps.user_id = sqlc.arg(viewer_id)::uuid OR (
+    (NOT users.is_protected OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg(viewer_id)::uuid AND follows.followee_id = chirps.user_id))
+    AND (chirps.visibility IN ('public', 'unlisted')
+        OR (chirps.visibility = 'followers' AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg(viewer_id)::uuid AND follows.followee_id = chirps.user_id))
+        OR (chirps.visibility = 'mentioned' AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg(viewer_id)::uuid)))
+))
+AND (sqlc.arg(include_restricted)::boolean OR (users.banned_at IS NULL AND (users.suspended_until IS NULL OR users.suspended_until <= NOW())))
+ORDER BY chirps.created_at;
 
 -- name: GetChirpsByAuthorID :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = sqlc.arg(user_id)::uuid AND users.deactivated_at IS NULL AND chirps.status = 'published'
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND (sqlc.arg(include_deleted)::boolean OR chirps.deleted_at IS NULL)
+AND (chirps.user_id = sqlc.arg(viewer_id)::uuid OR (
+    (NOT users.is_protected OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg(viewer_id)::uuid AND follows.followee_id = chirps.user_id))
+    AND (chirps.visibility IN ('public', 'unlisted')
+        OR (chirps.visibility = 'followers' AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg(viewer_id)::uuid AND follows.followee_id = chirps.user_id))
+        OR (chirps.visibility = 'mentioned' AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg(viewer_id)::uuid)))
+))
+AND (sqlc.arg(include_restricted)::boolean OR (users.banned_at IS NULL AND (users.suspended_until IS NULL OR users.suspended_until <= NOW())));
+
+-- every chirp still stored for a user whatever state it's in, for their data export
+-- name: GetAllChirpsByAuthorID :many
 SELECT * FROM chirps
-WHERE chirps.user_id = $1;
+WHERE chirps.user_id = $1
+ORDER BY chirps.created_at;
 
 -- name: GetChirpByID :one
-SELECT * FROM chirps
-WHERE chirps.id = $1;
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND users.deactivated_at IS NULL AND chirps.status = 'published' AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW());
+
+-- name: GetChirpByIDIncludingDeleted :one
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND users.deactivated_at IS NULL AND chirps.status = 'published'
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW());
 
 -- name: DeleteChirp :exec
+UPDATE chirps
+SET deleted_at = NOW(), deleted_by = $3
+WHERE chirps.id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, deleted_by = NULL
+WHERE chirps.id = sqlc.arg(id)::uuid AND user_id = sqlc.arg(user_id)::uuid
+AND deleted_by = user_id AND deleted_at > sqlc.arg(deleted_after)::timestamp
+AND (expires_at IS NULL OR expires_at > NOW())
+RETURNING *;
+
+-- name: GetRestorableChirps :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)::uuid AND deleted_by = user_id AND deleted_at > sqlc.arg(deleted_after)::timestamp
+AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY deleted_at DESC;
+
+-- name: PurgeDeletedChirps :execrows
 DELETE FROM chirps
-WHERE chirps.id = $1 AND user_id = $2;
+WHERE deleted_at < $1;
+
+-- name: GetChirpsAfter :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE (chirps.created_at, chirps.id) > (sqlc.arg(created_at)::timestamp, sqlc.arg(id)::uuid)
+AND users.deactivated_at IS NULL AND chirps.status = 'published' AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND (chirps.user_id = sqlc.arg(viewer_id)::uuid OR (
+    (NOT users.is_protected OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg(viewer_id)::uuid AND follows.followee_id = chirps.user_id))
+    AND (chirps.visibility IN ('public', 'unlisted')
+        OR (chirps.visibility = 'followers' AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg(viewer_id)::uuid AND follows.followee_id = chirps.user_id))
+        OR (chirps.visibility = 'mentioned' AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg(viewer_id)::uuid)))
+))
+ORDER BY chirps.created_at, chirps.id
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: NotifyStream :exec
+SELECT pg_notify('chirpy_stream', sqlc.arg(payload)::text);
+
+-- name: AddChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetChirpMentionIDs :many
+SELECT user_id FROM chirp_mentions
+WHERE chirp_mentions.chirp_id = $1;
+
+-- name: GetUnpublishedChirps :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)::uuid AND status <> 'published'
+AND (sqlc.arg(status)::text = '' OR status = sqlc.arg(status)::text)
+ORDER BY COALESCE(publish_at, updated_at);
+
+-- name: EditChirp :one
+-- only the body of a published chirp can be changed, and only while it's inside its author's edit window
+UPDATE chirps
+SET body = sqlc.arg(body)::text, updated_at = NOW()
+WHERE id = sqlc.arg(id)::uuid AND user_id = sqlc.arg(user_id)::uuid AND status = 'published' AND deleted_at IS NULL
+AND created_at >= sqlc.arg(published_after)::timestamp
+RETURNING *;
+
+-- name: UpdateUnpublishedChirp :one
+-- a chirp published from here takes the time it went out as its creation time so it lands at the top of
+-- timelines and streams
+UPDATE chirps
+SET body = $3, visibility = $4, status = $5, publish_at = $6, expires_in = $7, expires_at = $8, updated_at = NOW(),
+    created_at = CASE WHEN $5 = 'published' THEN NOW() ELSE created_at END,
+    publish_attempts = 0, publish_retry_at = NULL
+WHERE id = $1 AND user_id = $2 AND status <> 'published'
+RETURNING *;
+
+-- name: DeleteUnpublishedChirp :execrows
+DELETE FROM chirps
+WHERE id = $1 AND user_id = $2 AND status <> 'published';
+
+-- name: ClaimDueScheduledChirp :one
+-- like UpdateUnpublishedChirp a chirp goes out with a fresh creation time and starts counting down to when it
+-- expires. The row lock is held until the publishing transaction commits, so other servers skip over it and a chirp
+-- is only ever published once. Chirps from suspended and banned users wait until they're allowed to post again
+UPDATE chirps
+SET status = 'published', created_at = NOW(), updated_at = NOW(), expires_at = NOW() + make_interval(secs => expires_in)
+WHERE id = (
+    SELECT due.id FROM chirps AS due
+    JOIN users ON users.id = due.user_id
+    WHERE due.status = 'scheduled' AND due.publish_at <= NOW()
+    AND (due.publish_retry_at IS NULL OR due.publish_retry_at <= NOW())
+    AND users.deactivated_at IS NULL AND users.banned_at IS NULL
+    AND (users.suspended_until IS NULL OR users.suspended_until <= NOW())
+    ORDER BY due.publish_at
+    LIMIT 1
+    FOR UPDATE OF due SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkScheduledChirpFailed :exec
+-- run outside the publishing transaction since that's rolled back, the chirp goes back to being a draft once it's
+-- used up its attempts so its author can see it didn't go out
+UPDATE chirps
+SET publish_attempts = publish_attempts + 1, publish_retry_at = sqlc.arg(retry_at)::timestamp,
+    status = CASE WHEN publish_attempts + 1 >= sqlc.arg(max_attempts)::int THEN 'draft' ELSE status END
+WHERE id = sqlc.arg(id)::uuid AND status = 'scheduled';
+
+-- name: DeleteExpiredChirps :many
+-- expired chirps are already hidden from every read, this just clears them out. Locked rows are left for
+-- whichever server got to them first
+DELETE FROM chirps
+WHERE id IN (
+    SELECT expired.id FROM chirps AS expired
+    WHERE expired.expires_at <= NOW()
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: GetVisibleChirpsByIDs :many
+-- the chirps out of ids the viewer is allowed to see, used for quoted chirps so anything deleted, expired,
+-- hidden from them or behind a block is simply missing
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = ANY(sqlc.arg(ids)::uuid[])
+AND users.deactivated_at IS NULL AND chirps.status = 'published' AND chirps.deleted_at IS NULL
+AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())
+AND users.banned_at IS NULL AND (users.suspended_until IS NULL OR users.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg(viewer_id)::uuid AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id)::uuid)
+)
+AND (chirps.user_id = sqlc.arg(viewer_id)::uuid OR (
+    (NOT users.is_protected OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg(viewer_id)::uuid AND follows.followee_id = chirps.user_id))
+    AND (chirps.visibility IN ('public', 'unlisted')
+        OR (chirps.visibility = 'followers' AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg(viewer_id)::uuid AND follows.followee_id = chirps.user_id))
+        OR (chirps.visibility = 'mentioned' AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg(viewer_id)::uuid)))
+));
+
+-- name: GetChirpShareCounts :many
+-- quotes are chirps of their own so only the ones still up are counted, rechirps go with their chirp
+SELECT chirps.id,
+    (SELECT COUNT(*) FROM rechirps WHERE rechirps.chirp_id = chirps.id) AS rechirp_count,
+    (SELECT COUNT(*) FROM chirps AS quotes
+        WHERE quotes.quote_of_id = chirps.id AND quotes.status = 'published' AND quotes.deleted_at IS NULL
+        AND (quotes.expires_at IS NULL OR quotes.expires_at > NOW())) AS quote_count
+FROM chirps
+WHERE chirps.id = ANY(sqlc.arg(chirp_ids)::uuid[]);
@@ -0,0 +1,67 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id, status)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    'pending'
+)
+RETURNING *;
+
+-- name: GetActiveDataExport :one
+SELECT * FROM data_exports
+WHERE data_exports.user_id = $1 AND status IN ('pending', 'processing')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetDataExportByID :one
+SELECT * FROM data_exports
+WHERE data_exports.id = $1;
+
+-- name: GetDataExportsByUserID :many
+SELECT * FROM data_exports
+WHERE data_exports.user_id = $1
+ORDER BY created_at DESC
+LIMIT 20;
+
+-- name: ClaimDataExport :one
+UPDATE data_exports
+SET status = 'processing', updated_at = NOW()
+WHERE id = (
+    SELECT pending.id FROM data_exports AS pending
+    WHERE pending.status = 'pending'
+    OR (pending.status = 'processing' AND pending.updated_at < NOW() - INTERVAL '10 minutes')
+    ORDER BY pending.created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: SaveDataExportArchive :exec
+INSERT INTO data_export_archives (export_id, archive)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT (export_id) DO UPDATE
+SET archive = EXCLUDED.archive;
+
+-- name: CompleteDataExport :one
+UPDATE data_exports
+SET status = 'ready', size_bytes = $2, completed_at = NOW(), expires_at = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed', error = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetDataExportArchive :one
+SELECT archive FROM data_export_archives
+WHERE data_export_archives.export_id = $1;
+
+-- name: DeleteExpiredDataExports :execrows
+DELETE FROM data_exports
+WHERE expires_at <= NOW();
@@ -0,0 +1,133 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, is_group)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+);
+
+-- name: GetConversationByID :one
+SELECT * FROM conversations
+WHERE conversations.id = $1;
+
+-- name: GetDirectConversation :one
+SELECT conversations.* FROM conversations
+JOIN conversation_members AS mine ON mine.conversation_id = conversations.id AND mine.user_id = sqlc.arg(user_id)::uuid
+JOIN conversation_members AS theirs ON theirs.conversation_id = conversations.id AND theirs.user_id = sqlc.arg(other_user_id)::uuid
+WHERE NOT conversations.is_group
+LIMIT 1;
+
+-- name: LockDirectConversation :exec
+SELECT pg_advisory_xact_lock(hashtext(
+    'direct_conversation:' || LEAST(sqlc.arg(user_id)::uuid, sqlc.arg(other_user_id)::uuid)::text
+    || ':' || GREATEST(sqlc.arg(user_id)::uuid, sqlc.arg(other_user_id)::uuid)::text
+));
+
+-- name: GetConversationMembers :many
+SELECT * FROM conversation_members
+WHERE conversation_members.conversation_id = $1
+ORDER BY joined_at;
+
+-- name: GetConversationsForUser :many
+SELECT conversations.*,
+    (
+        SELECT COUNT(*) FROM messages
+        WHERE messages.conversation_id = conversations.id
+            AND messages.sender_id <> conversation_members.user_id
+            AND messages.created_at > GREATEST(conversation_members.last_read_at, conversation_members.cleared_at, 'epoch'::timestamp)
+            AND NOT EXISTS (
+                SELECT 1 FROM message_deletions
+                WHERE message_deletions.message_id = messages.id AND message_deletions.user_id = conversation_members.user_id
+            )
+    ) AS unread_count
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = $1
+    AND (conversation_members.cleared_at IS NULL OR conversations.updated_at > conversation_members.cleared_at)
+ORDER BY conversations.updated_at DESC;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: GetMessageByID :one
+SELECT * FROM messages
+WHERE messages.id = $1;
+
+-- name: GetMessages :many
+SELECT messages.* FROM messages
+JOIN conversation_members ON conversation_members.conversation_id = messages.conversation_id
+    AND conversation_members.user_id = sqlc.arg(user_id)::uuid
+WHERE messages.conversation_id = sqlc.arg(conversation_id)::uuid
+    AND (conversation_members.cleared_at IS NULL OR messages.created_at > conversation_members.cleared_at)
+    AND NOT EXISTS (
+        SELECT 1 FROM message_deletions
+        WHERE message_deletions.message_id = messages.id AND message_deletions.user_id = conversation_members.user_id
+    )
+    AND (messages.created_at, messages.id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY messages.created_at DESC, messages.id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: ClearConversation :exec
+UPDATE conversation_members
+SET cleared_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: DeleteMessageForUser :exec
+INSERT INTO message_deletions (message_id, user_id, deleted_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: IsMutualFollow :one
+SELECT (
+    EXISTS (SELECT 1 FROM follows WHERE follower_id = sqlc.arg(user_id)::uuid AND followee_id = sqlc.arg(other_user_id)::uuid)
+    AND EXISTS (SELECT 1 FROM follows WHERE follower_id = sqlc.arg(other_user_id)::uuid AND followee_id = sqlc.arg(user_id)::uuid)
+)::boolean AS mutual;
+
+-- every message a user sent plus the ones they can still see in their conversations, for their data export
+-- name: GetMessagesForExport :many
+SELECT messages.* FROM messages
+WHERE messages.sender_id = $1
+    OR EXISTS (
+        SELECT 1 FROM conversation_members
+        WHERE conversation_members.conversation_id = messages.conversation_id
+            AND conversation_members.user_id = $1
+            AND (conversation_members.cleared_at IS NULL OR messages.created_at > conversation_members.cleared_at)
+            AND NOT EXISTS (
+                SELECT 1 FROM message_deletions
+                WHERE message_deletions.message_id = messages.id AND message_deletions.user_id = $1
+            )
+    )
+ORDER BY messages.conversation_id, messages.created_at, messages.id;
@@ -0,0 +1,21 @@
+-- name: UpsertEmailChangeRequest :one
+INSERT INTO email_change_requests (user_id, created_at, new_email, token_hash, expires_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id) DO UPDATE
+SET created_at = EXCLUDED.created_at, new_email = EXCLUDED.new_email, token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: GetEmailChangeRequestByUserID :one
+SELECT * FROM email_change_requests
+WHERE email_change_requests.user_id = $1 AND expires_at > NOW();
+
+-- name: ConsumeEmailChangeRequest :one
+DELETE FROM email_change_requests
+WHERE token_hash = $1 AND expires_at > NOW()
+RETURNING *;
@@ -0,0 +1,50 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follows.follower_id = $1;
+
+-- name: GetFollowing :many
+SELECT * FROM follows
+WHERE follows.follower_id = $1
+ORDER BY created_at;
+
+-- name: GetFollowers :many
+SELECT * FROM follows
+WHERE follows.followee_id = $1
+ORDER BY created_at;
+
+-- name: IsFollowing :one
+SELECT EXISTS (
+    SELECT 1 FROM follows
+    WHERE follows.follower_id = $1 AND follows.followee_id = $2
+) AS following;
+
+-- name: CreateFollowRequest :execrows
+INSERT INTO follow_requests (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowRequests :many
+SELECT * FROM follow_requests
+WHERE follow_requests.followee_id = $1
+ORDER BY created_at;
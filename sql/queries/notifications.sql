@@ -0,0 +1,74 @@
+-- name: UpsertNotification :one
+INSERT INTO notifications (id, created_at, updated_at, user_id, type, group_key, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, group_key) WHERE read_at IS NULL
+DO UPDATE SET updated_at = NOW()
+RETURNING *;
+
+-- name: AddNotificationActor :exec
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (notification_id, actor_id)
+DO UPDATE SET created_at = NOW();
+
+-- name: GetNotifications :many
+SELECT notifications.*,
+    (SELECT COUNT(*) FROM notification_actors WHERE notification_actors.notification_id = notifications.id) AS actor_count,
+    ARRAY(
+        SELECT recent.actor_id FROM notification_actors AS recent
+        WHERE recent.notification_id = notifications.id
+        ORDER BY recent.created_at DESC
+        LIMIT 3
+    )::uuid[] AS recent_actor_ids
+FROM notifications
+WHERE notifications.user_id = sqlc.arg(user_id)::uuid
+    AND (notifications.updated_at, notifications.id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY notifications.updated_at DESC, notifications.id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE notifications.user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE id = $1 AND user_id = $2 AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE notification_preferences.user_id = $1;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (user_id, type)
+DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW();
+
+-- name: IsNotificationEnabled :one
+SELECT COALESCE(
+    (SELECT enabled FROM notification_preferences WHERE notification_preferences.user_id = $1 AND notification_preferences.type = $2),
+    true
+)::boolean AS enabled;
@@ -0,0 +1,46 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox (created_at, event_id, aggregate_type, aggregate_id, event_type, payload)
+VALUES (
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: AcquireOutboxRelayLease :execrows
+UPDATE outbox_relay_lease
+SET holder = $1, expires_at = $2
+WHERE id = 1 AND (holder = $1 OR expires_at < NOW());
+
+-- events whose turn it is, anything after an event of the same aggregate that's waiting to be retried waits too
+-- so they still go out in order
+-- name: GetUnpublishedOutboxEvents :many
+SELECT * FROM outbox
+WHERE outbox.published_at IS NULL AND outbox.dead_at IS NULL AND outbox.next_attempt_at <= NOW()
+    AND NOT EXISTS (
+        SELECT 1 FROM outbox AS earlier
+        WHERE earlier.aggregate_type = outbox.aggregate_type
+            AND earlier.aggregate_id = outbox.aggregate_id
+            AND earlier.id < outbox.id
+            AND earlier.published_at IS NULL
+            AND earlier.dead_at IS NULL
+            AND earlier.next_attempt_at > NOW()
+    )
+ORDER BY outbox.id
+LIMIT $1;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET published_at = NOW(), last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3, dead_at = $4
+WHERE id = $1;
+
+-- name: DeletePublishedOutboxEvents :execrows
+DELETE FROM outbox
+WHERE published_at < $1 OR dead_at < $1;
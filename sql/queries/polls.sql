@@ -0,0 +1,83 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, multiple_choice, duration)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: AddPollOption :exec
+INSERT INTO poll_options (id, chirp_id, position, text)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3
+);
+
+-- name: StartPoll :exec
+-- a poll starts running when its chirp is published
+UPDATE polls
+SET closes_at = NOW() + make_interval(secs => duration)
+WHERE chirp_id = $1 AND closes_at IS NULL;
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1;
+
+-- name: GetPollOptions :many
+SELECT * FROM poll_options
+WHERE chirp_id = $1
+ORDER BY position;
+
+-- name: GetPollsByChirpIDs :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollTallies :many
+SELECT poll_options.id, poll_options.chirp_id, poll_options.position, poll_options.text, COUNT(poll_votes.user_id) AS votes
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.option_id = poll_options.id
+WHERE poll_options.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY poll_options.id
+ORDER BY poll_options.chirp_id, poll_options.position;
+
+-- name: GetPollVoterCounts :many
+SELECT chirp_id, COUNT(*) AS voters FROM poll_ballots
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY chirp_id;
+
+-- name: GetUserPollVotes :many
+SELECT chirp_id, option_id FROM poll_votes
+WHERE user_id = sqlc.arg(user_id)::uuid AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: CreatePollBallot :execrows
+INSERT INTO poll_ballots (chirp_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: AddPollVote :exec
+INSERT INTO poll_votes (option_id, chirp_id, user_id)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: CloseDuePolls :many
+-- polls that have run out are marked closed so their authors are only told once, whichever server gets to
+-- one first takes it
+UPDATE polls
+SET closed_at = NOW()
+WHERE chirp_id IN (
+    SELECT due.chirp_id FROM polls AS due
+    WHERE due.closes_at <= NOW() AND due.closed_at IS NULL
+    ORDER BY due.closes_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
@@ -0,0 +1,69 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, target_type, target_id, target_user_id, reason, details, content, status)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    'open'
+)
+ON CONFLICT (reporter_id, target_type, target_id) WHERE status IN ('open', 'claimed') DO NOTHING
+RETURNING *;
+
+-- name: GetOpenReportByReporter :one
+SELECT * FROM reports
+WHERE reports.reporter_id = $1 AND target_type = $2 AND target_id = $3 AND status IN ('open', 'claimed');
+
+-- name: GetReportByID :one
+SELECT * FROM reports
+WHERE reports.id = $1;
+
+-- name: GetReportQueue :many
+SELECT * FROM reports
+WHERE reports.status = $1
+ORDER BY created_at
+LIMIT 50;
+
+-- name: ClaimReport :one
+UPDATE reports
+SET status = 'claimed', claimed_by = sqlc.arg(moderator_id)::uuid, claimed_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg(id)::uuid
+AND (status = 'open' OR (status = 'claimed' AND claimed_by = sqlc.arg(moderator_id)::uuid))
+RETURNING *;
+
+-- name: ReleaseReport :one
+UPDATE reports
+SET status = 'open', claimed_by = NULL, claimed_at = NULL, updated_at = NOW()
+WHERE id = sqlc.arg(id)::uuid AND status = 'claimed' AND claimed_by = sqlc.arg(moderator_id)::uuid
+RETURNING *;
+
+-- name: ResolveReport :one
+UPDATE reports
+SET status = sqlc.arg(status)::text, resolved_by = sqlc.arg(moderator_id)::uuid, resolved_at = NOW(),
+    resolution_note = sqlc.arg(resolution_note)::text, updated_at = NOW()
+WHERE id = sqlc.arg(id)::uuid AND status = 'claimed' AND claimed_by = sqlc.arg(moderator_id)::uuid
+RETURNING *;
+
+-- name: CreateReportAction :one
+INSERT INTO report_actions (id, created_at, report_id, moderator_id, action, note, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetReportActions :many
+SELECT * FROM report_actions
+WHERE report_actions.report_id = $1
+ORDER BY created_at;
@@ -0,0 +1,30 @@
+-- name: GetSubscriptionByUserID :one
+SELECT * FROM subscriptions
+WHERE subscriptions.user_id = $1;
+
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions (user_id, created_at, updated_at, status, red_until, last_event_id)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id) DO UPDATE
+SET status = EXCLUDED.status, red_until = EXCLUDED.red_until, last_event_id = EXCLUDED.last_event_id, updated_at = NOW()
+RETURNING *;
+
+-- name: DowngradeLapsedUsers :many
+WITH lapsed AS (
+    UPDATE subscriptions
+    SET status = 'expired', updated_at = NOW()
+    WHERE red_until <= NOW() AND status IN ('active', 'cancelled', 'past_due')
+    RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = false, updated_at = NOW()
+FROM lapsed
+WHERE users.id = lapsed.user_id
+RETURNING users.id;
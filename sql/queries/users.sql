@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -16,13 +17,158 @@ DELETE FROM users;
 SELECT * FROM users
 WHERE users.email = $1;
 
--- name: UpdateUser :exec
-UPDATE users
-SET email = $1, hashed_password = $2
-WHERE id = $3;
+-- name: GetUserByIDForUpdate :one
+SELECT * FROM users
+WHERE users.id = $1
+FOR UPDATE;
 
 -- name: UpgradeUser :one
 UPDATE users
 SET is_chirpy_red = true
 WHERe id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE users.id = $1;
+
+-- name: SetUserChirpyRed :exec
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserProtected :one
+UPDATE users
+SET is_protected = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetDefaultChirpExpiry :one
+UPDATE users
+SET default_chirp_expires_in = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserDMsOpen :exec
+UPDATE users
+SET dms_open = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(users.handle) = LOWER(sqlc.arg(handle)::text);
+
+-- name: GetUserProfileCounts :one
+SELECT
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = sqlc.arg(user_id)::uuid) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = sqlc.arg(user_id)::uuid) AS following_count,
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = sqlc.arg(user_id)::uuid AND chirps.status = 'published' AND chirps.deleted_at IS NULL
+        AND (chirps.expires_at IS NULL OR chirps.expires_at > NOW())) AS chirp_count;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET display_name = $2, bio = $3, location = $4, website = $5, avatar_url = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserHandle :one
+UPDATE users
+SET handle = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ReserveHandle :exec
+INSERT INTO handle_reservations (handle, user_id, created_at, reserved_until)
+VALUES (
+    LOWER(sqlc.arg(handle)::text),
+    sqlc.arg(user_id)::uuid,
+    NOW(),
+    sqlc.arg(reserved_until)::timestamp
+)
+ON CONFLICT (handle) DO UPDATE
+SET user_id = EXCLUDED.user_id, created_at = EXCLUDED.created_at, reserved_until = EXCLUDED.reserved_until;
+
+-- name: GetHandleReservation :one
+SELECT * FROM handle_reservations
+WHERE handle_reservations.handle = LOWER(sqlc.arg(handle)::text) AND reserved_until > NOW();
+
+-- name: ReleaseHandleReservation :exec
+DELETE FROM handle_reservations
+WHERE handle = LOWER(sqlc.arg(handle)::text) AND user_id = sqlc.arg(user_id)::uuid;
+
+-- name: SetUserEmail :one
+UPDATE users
+SET email = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserPassword :one
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeactivateUser :one
+UPDATE users
+SET deactivated_at = NOW(), delete_after = sqlc.arg(delete_after)::timestamp, updated_at = NOW()
+WHERE id = sqlc.arg(id)::uuid
+RETURNING *;
+
+-- name: ReactivateUser :one
+UPDATE users
+SET deactivated_at = NULL, delete_after = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteDeactivatedUsers :many
+DELETE FROM users
+WHERE delete_after <= NOW()
+RETURNING id;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_until = $2, suspension_reason = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: BanUser :one
+UPDATE users
+SET banned_at = NOW(), ban_reason = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: LiftSuspension :one
+UPDATE users
+SET suspended_until = NULL, suspension_reason = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnbanUser :one
+UPDATE users
+SET banned_at = NULL, ban_reason = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RecordBannedEmail :exec
+INSERT INTO banned_emails (email, created_at, user_id, reason)
+SELECT lower(users.email), NOW(), users.id, users.ban_reason
+FROM users
+WHERE users.id = $1
+ON CONFLICT (email) DO UPDATE
+SET user_id = EXCLUDED.user_id, reason = EXCLUDED.reason;
+
+-- name: DeleteBannedEmails :exec
+DELETE FROM banned_emails
+WHERE banned_emails.user_id = $1;
+
+-- name: IsEmailBanned :one
+SELECT EXISTS (
+    SELECT 1 FROM banned_emails
+    WHERE banned_emails.email = lower($1)
+) AS banned;
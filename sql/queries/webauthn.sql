@@ -0,0 +1,42 @@
+-- name: CreateWebAuthnChallenge :exec
+INSERT INTO webauthn_challenges (challenge, created_at, ceremony, user_id, expires_at)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    NOW() + INTERVAL '5 minutes'
+);
+
+-- name: ConsumeWebAuthnChallenge :one
+DELETE FROM webauthn_challenges
+WHERE challenge = $1 AND ceremony = $2 AND expires_at > NOW()
+RETURNING *;
+
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (id, created_at, updated_at, user_id, public_key, sign_count)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetWebAuthnCredentialByID :one
+SELECT * FROM webauthn_credentials
+WHERE webauthn_credentials.id = $1;
+
+-- name: GetWebAuthnCredentialsByUserID :many
+SELECT * FROM webauthn_credentials
+WHERE webauthn_credentials.user_id = $1
+ORDER BY created_at;
+
+-- name: UpdateWebAuthnSignCount :execrows
+-- the counter only moves forward, so of two assertions carrying the same count only the first one gets through.
+-- Authenticators that don't keep a counter always send 0
+UPDATE webauthn_credentials
+SET sign_count = $2, last_used_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0));
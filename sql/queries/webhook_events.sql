@@ -0,0 +1,34 @@
+-- name: RecordWebhookEvent :one
+INSERT INTO webhook_events (id, created_at, updated_at, source, event, payload, signature)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (id) DO UPDATE
+SET deliveries = webhook_events.deliveries + 1, updated_at = NOW()
+RETURNING *;
+
+-- name: GetWebhookEventByID :one
+SELECT * FROM webhook_events
+WHERE webhook_events.id = $1;
+
+-- name: GetWebhookEvents :many
+SELECT * FROM webhook_events
+ORDER BY created_at DESC
+LIMIT $1;
+
+-- name: MarkWebhookEventFailed :exec
+UPDATE webhook_events
+SET last_error = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ClaimWebhookEvent :one
+UPDATE webhook_events
+SET processed_at = NOW(), last_error = NULL, updated_at = NOW()
+WHERE id = $1 AND processed_at IS NULL
+RETURNING *;
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE webauthn_credentials (
+    id BYTEA PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE webauthn_challenges (
+    challenge TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    ceremony TEXT NOT NULL,
+    user_id UUID DEFAULT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE webauthn_challenges;
+DROP TABLE webauthn_credentials;
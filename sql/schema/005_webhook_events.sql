@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE webhook_events (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    source TEXT NOT NULL,
+    event TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    signature TEXT NOT NULL,
+    deliveries INTEGER NOT NULL DEFAULT 1,
+    processed_at TIMESTAMP DEFAULT NULL,
+    last_error TEXT DEFAULT NULL
+);
+
+-- +goose Down
+DROP TABLE webhook_events;
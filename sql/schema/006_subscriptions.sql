@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE subscriptions (
+    user_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL,
+    red_until TIMESTAMP NOT NULL,
+    last_event_id TEXT NOT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX subscriptions_red_until_idx ON subscriptions (red_until) WHERE status <> 'expired';
+
+-- +goose Down
+DROP TABLE subscriptions;
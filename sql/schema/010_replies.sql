@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN reply_to_id UUID DEFAULT NULL REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_reply_to_idx ON chirps (reply_to_id);
+
+-- +goose Down
+DROP INDEX chirps_reply_to_idx;
+ALTER TABLE chirps DROP COLUMN reply_to_id;
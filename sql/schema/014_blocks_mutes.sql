@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE TABLE blocks (
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX blocks_blocked_idx ON blocks (blocked_id);
+
+CREATE TABLE mutes (
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    FOREIGN KEY (muter_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (muted_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE muted_keywords (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    keyword TEXT NOT NULL,
+    expires_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX muted_keywords_user_keyword_idx ON muted_keywords (user_id, keyword);
+
+-- +goose Down
+DROP TABLE muted_keywords;
+DROP TABLE mutes;
+DROP TABLE blocks;
@@ -0,0 +1,39 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle TEXT,
+ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN location TEXT NOT NULL DEFAULT '',
+ADD COLUMN website TEXT NOT NULL DEFAULT '',
+ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';
+
+-- existing users get a placeholder handle they can change later
+UPDATE users
+SET handle = 'user_' || SUBSTRING(REPLACE(id::text, '-', '') FROM 1 FOR 10);
+
+ALTER TABLE users
+ALTER COLUMN handle SET NOT NULL;
+
+CREATE UNIQUE INDEX users_handle_idx ON users (LOWER(handle));
+
+-- handles someone has moved away from stay theirs for a while so nobody can pick them up and impersonate them
+CREATE TABLE handle_reservations (
+    handle TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    reserved_until TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE handle_reservations;
+DROP INDEX users_handle_idx;
+ALTER TABLE users
+DROP COLUMN avatar_url,
+DROP COLUMN website,
+DROP COLUMN location,
+DROP COLUMN bio,
+DROP COLUMN display_name,
+DROP COLUMN handle;
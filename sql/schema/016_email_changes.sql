@@ -0,0 +1,15 @@
+-- +goose Up
+-- a new email only replaces the old one once the link sent to it is followed
+CREATE TABLE email_change_requests (
+    user_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    new_email TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE email_change_requests;
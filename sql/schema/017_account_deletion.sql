@@ -0,0 +1,13 @@
+-- +goose Up
+-- deactivated accounts are hidden straight away and only deleted for good once delete_after passes
+ALTER TABLE users
+ADD COLUMN deactivated_at TIMESTAMP DEFAULT NULL,
+ADD COLUMN delete_after TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX users_delete_after_idx ON users (delete_after) WHERE delete_after IS NOT NULL;
+
+-- +goose Down
+DROP INDEX users_delete_after_idx;
+ALTER TABLE users
+DROP COLUMN delete_after,
+DROP COLUMN deactivated_at;
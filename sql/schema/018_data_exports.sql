@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE data_exports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    status TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    error TEXT DEFAULT NULL,
+    completed_at TIMESTAMP DEFAULT NULL,
+    expires_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX data_exports_user_idx ON data_exports (user_id, created_at DESC);
+CREATE INDEX data_exports_pending_idx ON data_exports (created_at) WHERE status IN ('pending', 'processing');
+
+-- archives live apart from their export so listing exports doesn't load them
+CREATE TABLE data_export_archives (
+    export_id UUID PRIMARY KEY,
+    archive BYTEA NOT NULL,
+    FOREIGN KEY (export_id)
+        REFERENCES data_exports(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE data_export_archives;
+DROP TABLE data_exports;
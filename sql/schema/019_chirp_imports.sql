@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE chirp_imports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    source TEXT NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT DEFAULT NULL,
+    completed_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_imports_user_idx ON chirp_imports (user_id, created_at DESC);
+CREATE INDEX chirp_imports_pending_idx ON chirp_imports (created_at) WHERE status IN ('pending', 'processing');
+
+-- one row per post in the archive, which is also where its result is reported
+CREATE TABLE chirp_import_items (
+    id UUID PRIMARY KEY,
+    import_id UUID NOT NULL,
+    position INTEGER NOT NULL,
+    external_id TEXT NOT NULL,
+    reply_to_external_id TEXT DEFAULT NULL,
+    posted_at TIMESTAMP NOT NULL,
+    body TEXT NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT DEFAULT NULL,
+    chirp_id UUID DEFAULT NULL,
+    UNIQUE (import_id, position),
+    FOREIGN KEY (import_id)
+        REFERENCES chirp_imports(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX chirp_import_items_imported_idx ON chirp_import_items (external_id) WHERE status = 'imported';
+
+-- +goose Down
+DROP TABLE chirp_import_items;
+DROP TABLE chirp_imports;
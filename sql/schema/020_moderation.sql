@@ -0,0 +1,72 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user',
+ADD COLUMN suspended_until TIMESTAMP DEFAULT NULL,
+ADD COLUMN suspension_reason TEXT DEFAULT NULL,
+ADD COLUMN banned_at TIMESTAMP DEFAULT NULL,
+ADD COLUMN ban_reason TEXT DEFAULT NULL;
+
+-- target_id points at the chirp, user or message reported, content keeps a copy of what was reported in case
+-- it's deleted before a moderator gets to it
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    reporter_id UUID NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id UUID NOT NULL,
+    target_user_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    claimed_by UUID DEFAULT NULL,
+    claimed_at TIMESTAMP DEFAULT NULL,
+    resolved_by UUID DEFAULT NULL,
+    resolved_at TIMESTAMP DEFAULT NULL,
+    resolution_note TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (reporter_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (target_user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (claimed_by)
+        REFERENCES users(id)
+        ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by)
+        REFERENCES users(id)
+        ON DELETE SET NULL
+);
+
+-- reporting the same thing again while the first report is still open is a no-op
+CREATE UNIQUE INDEX reports_open_idx ON reports (reporter_id, target_type, target_id) WHERE status IN ('open', 'claimed');
+CREATE INDEX reports_queue_idx ON reports (status, created_at);
+
+CREATE TABLE report_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    report_id UUID NOT NULL,
+    moderator_id UUID DEFAULT NULL,
+    action TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (report_id)
+        REFERENCES reports(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (moderator_id)
+        REFERENCES users(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX report_actions_report_idx ON report_actions (report_id, created_at);
+
+-- +goose Down
+DROP TABLE report_actions;
+DROP TABLE reports;
+ALTER TABLE users
+DROP COLUMN ban_reason,
+DROP COLUMN banned_at,
+DROP COLUMN suspension_reason,
+DROP COLUMN suspended_until,
+DROP COLUMN role;
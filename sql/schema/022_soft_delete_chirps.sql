@@ -0,0 +1,14 @@
+-- +goose Up
+-- deleted chirps are kept until the purge job removes them so they can be restored and moderators can still
+-- see them, deleted_by tells an author's own deletion apart from a moderator's
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP DEFAULT NULL,
+ADD COLUMN deleted_by UUID DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_deleted_at_idx;
+ALTER TABLE chirps
+DROP COLUMN deleted_at,
+DROP COLUMN deleted_by;
@@ -0,0 +1,42 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public';
+
+-- everyone a chirp mentioned when it was posted, mentioned-only chirps are shown to them even if they change
+-- handle or someone else takes the old one
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    PRIMARY KEY (chirp_id, user_id),
+    FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_mentions_user_idx ON chirp_mentions (user_id);
+
+-- protected accounts approve their followers, until then a follow waits here
+ALTER TABLE users ADD COLUMN is_protected BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE follow_requests (
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    FOREIGN KEY (follower_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (followee_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX follow_requests_followee_idx ON follow_requests (followee_id);
+
+-- +goose Down
+DROP TABLE follow_requests;
+ALTER TABLE users DROP COLUMN is_protected;
+DROP TABLE chirp_mentions;
+ALTER TABLE chirps DROP COLUMN visibility;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- drafts and scheduled chirps live alongside published ones but only their author sees them, scheduled ones
+-- are published by a background job once publish_at passes
+ALTER TABLE chirps
+ADD COLUMN status TEXT NOT NULL DEFAULT 'published',
+ADD COLUMN publish_at TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX chirps_publish_at_idx ON chirps (publish_at) WHERE status = 'scheduled';
+CREATE INDEX chirps_unpublished_user_idx ON chirps (user_id) WHERE status <> 'published';
+
+-- +goose Down
+DROP INDEX chirps_unpublished_user_idx;
+DROP INDEX chirps_publish_at_idx;
+ALTER TABLE chirps
+DROP COLUMN status,
+DROP COLUMN publish_at;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- expires_in is how long a chirp lasts once it's published, expires_at is only set then so drafts and
+-- scheduled chirps don't start counting down early
+ALTER TABLE chirps
+ADD COLUMN expires_in INTEGER DEFAULT NULL,
+ADD COLUMN expires_at TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX chirps_expires_at_idx ON chirps (expires_at) WHERE expires_at IS NOT NULL;
+
+-- how long a user's chirps last when they don't say otherwise, NULL keeps them forever
+ALTER TABLE users ADD COLUMN default_chirp_expires_in INTEGER DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN default_chirp_expires_in;
+DROP INDEX chirps_expires_at_idx;
+ALTER TABLE chirps
+DROP COLUMN expires_in,
+DROP COLUMN expires_at;
@@ -0,0 +1,61 @@
+-- +goose Up
+-- a chirp has at most one poll. closes_at is only set once the chirp is published so drafts don't start
+-- counting down early, closed_at is set once the author has been told it's over
+CREATE TABLE polls (
+    chirp_id UUID PRIMARY KEY,
+    multiple_choice BOOLEAN NOT NULL DEFAULT false,
+    duration INTEGER NOT NULL,
+    closes_at TIMESTAMP DEFAULT NULL,
+    closed_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX polls_closes_at_idx ON polls (closes_at) WHERE closed_at IS NULL;
+
+CREATE TABLE poll_options (
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL,
+    position INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    UNIQUE (chirp_id, position),
+    FOREIGN KEY (chirp_id)
+        REFERENCES polls(chirp_id)
+        ON DELETE CASCADE
+);
+
+-- a ballot is someone's one vote on a poll, on multiple choice polls it can pick several options
+CREATE TABLE poll_ballots (
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, user_id),
+    FOREIGN KEY (chirp_id)
+        REFERENCES polls(chirp_id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE poll_votes (
+    option_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    PRIMARY KEY (option_id, user_id),
+    FOREIGN KEY (option_id)
+        REFERENCES poll_options(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id, user_id)
+        REFERENCES poll_ballots(chirp_id, user_id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX poll_votes_user_idx ON poll_votes (user_id, chirp_id);
+
+-- +goose Down
+DROP TABLE poll_votes;
+DROP TABLE poll_ballots;
+DROP TABLE poll_options;
+DROP TABLE polls;
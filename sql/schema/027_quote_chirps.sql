@@ -0,0 +1,10 @@
+-- +goose Up
+-- a quote is a chirp of its own that embeds the one it's commenting on, it's kept if the quoted chirp is
+-- purged but then has nothing left to show
+ALTER TABLE chirps ADD COLUMN quote_of_id UUID DEFAULT NULL REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_quote_of_idx ON chirps (quote_of_id) WHERE quote_of_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_quote_of_idx;
+ALTER TABLE chirps DROP COLUMN quote_of_id;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE webhook_delivery_attempts DROP COLUMN response;
+
+-- +goose Down
+ALTER TABLE webhook_delivery_attempts ADD COLUMN response TEXT NOT NULL DEFAULT '';
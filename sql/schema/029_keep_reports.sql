@@ -0,0 +1,17 @@
+-- +goose Up
+-- reports and what moderators did about them outlive the reported account, only the link to it is cleared
+ALTER TABLE reports
+ALTER COLUMN target_user_id DROP NOT NULL,
+DROP CONSTRAINT reports_target_user_id_fkey,
+ADD CONSTRAINT reports_target_user_id_fkey FOREIGN KEY (target_user_id)
+    REFERENCES users(id)
+    ON DELETE SET NULL;
+
+-- +goose Down
+DELETE FROM reports WHERE target_user_id IS NULL;
+ALTER TABLE reports
+ALTER COLUMN target_user_id SET NOT NULL,
+DROP CONSTRAINT reports_target_user_id_fkey,
+ADD CONSTRAINT reports_target_user_id_fkey FOREIGN KEY (target_user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- emails of banned accounts, kept apart from users so a ban outlives the account being deleted. user_id is
+-- plain data rather than a foreign key for the same reason
+CREATE TABLE banned_emails (
+    email TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    reason TEXT DEFAULT NULL
+);
+
+CREATE INDEX banned_emails_user_idx ON banned_emails (user_id);
+
+INSERT INTO banned_emails (email, created_at, user_id, reason)
+SELECT lower(users.email), users.banned_at, users.id, users.ban_reason
+FROM users
+WHERE users.banned_at IS NOT NULL
+ON CONFLICT (email) DO NOTHING;
+
+-- +goose Down
+DROP TABLE banned_emails;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- JSONB reformats what it stores, the payload is kept as the exact bytes Polka signed instead. Events stored
+-- before this can't be turned back into their original bytes
+ALTER TABLE webhook_events
+ALTER COLUMN payload TYPE BYTEA USING convert_to(payload::text, 'UTF8');
+
+-- +goose Down
+ALTER TABLE webhook_events
+ALTER COLUMN payload TYPE JSONB USING convert_from(payload, 'UTF8')::jsonb;
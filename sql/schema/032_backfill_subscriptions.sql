@@ -0,0 +1,11 @@
+-- +goose Up
+-- users upgraded before subscriptions were tracked get one billing period from now, so they lapse like
+-- everyone else if Polka never renews them
+INSERT INTO subscriptions (user_id, created_at, updated_at, status, red_until, last_event_id)
+SELECT users.id, NOW(), NOW(), 'active', NOW() + INTERVAL '30 days', ''
+FROM users
+WHERE users.is_chirpy_red = true
+ON CONFLICT (user_id) DO NOTHING;
+
+-- +goose Down
+DELETE FROM subscriptions WHERE last_event_id = '';
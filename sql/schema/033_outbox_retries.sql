@@ -0,0 +1,30 @@
+-- +goose Up
+-- events that keep failing back off and are given up on after too many attempts instead of being retried
+-- every second forever
+ALTER TABLE outbox
+ADD COLUMN next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+ADD COLUMN dead_at TIMESTAMP DEFAULT NULL;
+
+DROP INDEX outbox_unpublished_idx;
+CREATE INDEX outbox_unpublished_idx ON outbox (id) WHERE published_at IS NULL AND dead_at IS NULL;
+CREATE INDEX outbox_aggregate_idx ON outbox (aggregate_type, aggregate_id, id) WHERE published_at IS NULL AND dead_at IS NULL;
+
+-- whoever holds the lease is the only server relaying, it's renewed every batch and taken over once it runs out.
+-- Unlike a transaction scoped lock it doesn't keep a transaction open while events are being published
+CREATE TABLE outbox_relay_lease (
+    id INTEGER PRIMARY KEY,
+    holder UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO outbox_relay_lease (id, holder, expires_at)
+VALUES (1, gen_random_uuid(), '1970-01-01');
+
+-- +goose Down
+DROP TABLE outbox_relay_lease;
+DROP INDEX outbox_aggregate_idx;
+DROP INDEX outbox_unpublished_idx;
+CREATE INDEX outbox_unpublished_idx ON outbox (id) WHERE published_at IS NULL;
+ALTER TABLE outbox
+DROP COLUMN dead_at,
+DROP COLUMN next_attempt_at;
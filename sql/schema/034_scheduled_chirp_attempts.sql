@@ -0,0 +1,11 @@
+-- +goose Up
+-- a scheduled chirp that fails to publish is retried later instead of holding up every chirp due after it, and
+-- goes back to being a draft after too many attempts
+ALTER TABLE chirps
+ADD COLUMN publish_attempts INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN publish_retry_at TIMESTAMP DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN publish_attempts,
+DROP COLUMN publish_retry_at;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- media attached to a chirp by URL, in the order it was given. How many a chirp can have depends on its author's plan
+CREATE TABLE chirp_media (
+    chirp_id UUID NOT NULL,
+    position INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    PRIMARY KEY (chirp_id, position),
+    FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE chirp_media;
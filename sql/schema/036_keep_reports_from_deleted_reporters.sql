@@ -0,0 +1,18 @@
+-- +goose Up
+-- like 029 for the reported account, a report and what moderators did about it are kept once the reporter's
+-- account is deleted, only the link to them is cleared
+ALTER TABLE reports
+ALTER COLUMN reporter_id DROP NOT NULL,
+DROP CONSTRAINT reports_reporter_id_fkey,
+ADD CONSTRAINT reports_reporter_id_fkey FOREIGN KEY (reporter_id)
+    REFERENCES users(id)
+    ON DELETE SET NULL;
+
+-- +goose Down
+DELETE FROM reports WHERE reporter_id IS NULL;
+ALTER TABLE reports
+ALTER COLUMN reporter_id SET NOT NULL,
+DROP CONSTRAINT reports_reporter_id_fkey,
+ADD CONSTRAINT reports_reporter_id_fkey FOREIGN KEY (reporter_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE;
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// writes a suspended or banned user can still make, so they can always leave and take their data with them
+var writesAllowedWhileRestricted = map[string]bool{
+	"DELETE /api/users/me":      true,
+	"POST /api/users/me/export": true,
+}
+
+// writes a deactivated user can still make, logging in is how an account is brought back before it's deleted
+var writesAllowedWhileDeactivated = map[string]bool{
+	"POST /api/login":                 true,
+	"POST /api/passkeys/login/begin":  true,
+	"POST /api/passkeys/login/finish": true,
+	"DELETE /api/users/me":            true,
+}
+
+// helper that explains why an account can't be used right now, it's empty for accounts in good standing
+func accountRestriction(user database.User, now time.Time) string {
+	if user.BannedAt.Valid {
+		message := "This account has been banned"
+		if user.BanReason.Valid {
+			message += ": " + user.BanReason.String
+		}
+		return message
+	}
+
+	if user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(now) {
+		message := "This account is suspended until " + user.SuspendedUntil.Time.Format(time.RFC3339)
+		if user.SuspensionReason.Valid {
+			message += ": " + user.SuspensionReason.String
+		}
+		return message
+	}
+
+	return ""
+}
+
+// helper that reports whether the viewer is staff and can see content hidden from everyone else
+func (cfg *apiConfig) isStaff(ctx context.Context, userID uuid.UUID) (bool, error) {
+	if userID == uuid.Nil {
+		return false, nil
+	}
+
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return user.Role == roleModerator || user.Role == roleAdmin, nil
+}
+
+// helper that bans a user and logs them out everywhere, should be given the transaction's queries like recordEvent.
+// Their email is remembered separately so deleting the account doesn't free it up to sign up again
+func banUser(ctx context.Context, db *database.Queries, userID uuid.UUID, reason string) (database.User, error) {
+	user, err := db.BanUser(ctx, database.BanUserParams{
+		ID:        userID,
+		BanReason: sql.NullString{String: reason, Valid: reason != ""},
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	err = db.RecordBannedEmail(ctx, userID)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return user, db.RevokeUserRefreshTokens(ctx, userID)
+}
+
+// middleware that turns away writes from suspended, banned and deactivated users, access tokens can't be revoked
+// so this is what stops one handed out before the suspension or deactivation from still working
+func (cfg *apiConfig) middlewareAccountStanding(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		// requests without an access token are left to the handler, it knows whether it needs one
+		token, err := auth.GetBearerToken(req.Header)
+		if err != nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+		userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+		if err != nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		route := req.Method + " " + req.URL.Path
+		if writesAllowedWhileRestricted[route] && writesAllowedWhileDeactivated[route] {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		user, err := cfg.db.GetUserByID(req.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Not authorized", err)
+			return
+		}
+		if user.DeactivatedAt.Valid && !writesAllowedWhileDeactivated[route] {
+			respondWithError(w, http.StatusForbidden, "This account has been deactivated, log in again to restore it", nil)
+			return
+		}
+		if restriction := accountRestriction(user, time.Now()); restriction != "" && !writesAllowedWhileRestricted[route] {
+			respondWithError(w, http.StatusForbidden, restriction, nil)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Khazz0r/chirpy/internal/auth"
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// users the fake driver below hands back, keyed by ID
+var standingTestUsers = map[string]database.User{}
+
+func init() {
+	sql.Register("standing-test", standingTestDriver{})
+}
+
+// standingTestDriver answers every query with the user whose ID is the first argument, which is all
+// GetUserByID needs. Columns come back in the same order as the fields of database.User
+type standingTestDriver struct{}
+
+func (standingTestDriver) Open(name string) (driver.Conn, error) { return standingTestConn{}, nil }
+
+type standingTestConn struct{}
+
+func (standingTestConn) Prepare(query string) (driver.Stmt, error) { return standingTestStmt{}, nil }
+func (standingTestConn) Close() error                              { return nil }
+func (standingTestConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type standingTestStmt struct{}
+
+func (standingTestStmt) Close() error  { return nil }
+func (standingTestStmt) NumInput() int { return -1 }
+func (standingTestStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (standingTestStmt) Query(args []driver.Value) (driver.Rows, error) {
+	id, _ := args[0].(string)
+	user, ok := standingTestUsers[id]
+	return &standingTestRows{user: user, found: ok}, nil
+}
+
+type standingTestRows struct {
+	user  database.User
+	found bool
+}
+
+func (r *standingTestRows) Columns() []string {
+	return make([]string, reflect.TypeOf(r.user).NumField())
+}
+
+func (r *standingTestRows) Close() error { return nil }
+
+func (r *standingTestRows) Next(dest []driver.Value) error {
+	if !r.found {
+		return io.EOF
+	}
+	r.found = false
+
+	fields := reflect.ValueOf(r.user)
+	for i := range dest {
+		field := fields.Field(i).Interface()
+		if valuer, ok := field.(driver.Valuer); ok {
+			value, err := valuer.Value()
+			if err != nil {
+				return err
+			}
+			dest[i] = value
+			continue
+		}
+		value, err := driver.DefaultParameterConverter.ConvertValue(field)
+		if err != nil {
+			return err
+		}
+		dest[i] = value
+	}
+	return nil
+}
+
+func TestAccountRestriction(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	later := sql.NullTime{Time: now.Add(time.Hour), Valid: true}
+	earlier := sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+
+	tests := []struct {
+		name string
+		user database.User
+		want string
+	}{
+		{"good standing", database.User{}, ""},
+		{"banned", database.User{BannedAt: earlier}, "This account has been banned"},
+		{
+			"banned with reason",
+			database.User{BannedAt: earlier, BanReason: sql.NullString{String: "spam", Valid: true}},
+			"This account has been banned: spam",
+		},
+		{
+			"suspended",
+			database.User{SuspendedUntil: later},
+			"This account is suspended until 2025-05-01T13:00:00Z",
+		},
+		{
+			"suspended with reason",
+			database.User{SuspendedUntil: later, SuspensionReason: sql.NullString{String: "cool off", Valid: true}},
+			"This account is suspended until 2025-05-01T13:00:00Z: cool off",
+		},
+		{"suspension over", database.User{SuspendedUntil: earlier}, ""},
+		{"ban outweighs suspension", database.User{BannedAt: earlier, SuspendedUntil: later}, "This account has been banned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := accountRestriction(tt.user, now)
+			if got != tt.want {
+				t.Errorf("accountRestriction() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareAccountStanding(t *testing.T) {
+	const secret = "standing-test-secret"
+
+	db, err := sql.Open("standing-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cfg := &apiConfig{db: database.New(db), jwtSecret: secret}
+
+	good := database.User{ID: uuid.New()}
+	banned := database.User{ID: uuid.New(), BannedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	suspended := database.User{ID: uuid.New(), SuspendedUntil: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}}
+	unsuspended := database.User{ID: uuid.New(), SuspendedUntil: sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}}
+	deactivated := database.User{ID: uuid.New(), DeactivatedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	standingTestUsers = map[string]database.User{}
+	for _, user := range []database.User{good, banned, suspended, unsuspended, deactivated} {
+		standingTestUsers[user.ID.String()] = user
+	}
+
+	tokenFor := func(userID uuid.UUID) string {
+		token, err := auth.MakeJWTToken(userID, secret, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"reads are always allowed", http.MethodGet, "/api/chirps", tokenFor(banned.ID), http.StatusOK},
+		{"no token is left to the handler", http.MethodPost, "/api/chirps", "", http.StatusOK},
+		{"bad token is left to the handler", http.MethodPost, "/api/chirps", "Bearer nope", http.StatusOK},
+		{"good standing", http.MethodPost, "/api/chirps", tokenFor(good.ID), http.StatusOK},
+		{"suspension over", http.MethodPost, "/api/chirps", tokenFor(unsuspended.ID), http.StatusOK},
+		{"banned", http.MethodPost, "/api/chirps", tokenFor(banned.ID), http.StatusForbidden},
+		{"suspended", http.MethodDelete, "/api/chirps/123", tokenFor(suspended.ID), http.StatusForbidden},
+		{"suspended user can export", http.MethodPost, "/api/users/me/export", tokenFor(suspended.ID), http.StatusOK},
+		{"banned user can delete their account", http.MethodDelete, "/api/users/me", tokenFor(banned.ID), http.StatusOK},
+		{"unknown user", http.MethodPost, "/api/chirps", tokenFor(uuid.New()), http.StatusUnauthorized},
+		{"deactivated", http.MethodPost, "/api/chirps", tokenFor(deactivated.ID), http.StatusForbidden},
+		{"deactivated user can't export", http.MethodPost, "/api/users/me/export", tokenFor(deactivated.ID), http.StatusForbidden},
+		{"deactivated user can log back in", http.MethodPost, "/api/login", tokenFor(deactivated.ID), http.StatusOK},
+		{"deactivated user can delete again", http.MethodDelete, "/api/users/me", tokenFor(deactivated.ID), http.StatusOK},
+	}
+
+	handler := cfg.middlewareAccountStanding(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%s %s got status %d, want %d", tt.method, tt.path, rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
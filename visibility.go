@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Khazz0r/chirpy/internal/database"
+	"github.com/Khazz0r/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// streamedChirp is what's published on the real-time stream, it carries who can see the chirp so subscribers
+// can be filtered without going back to the database. Only the Chirp is ever sent on to clients
+type streamedChirp struct {
+	Chirp
+	AuthorProtected bool        `json:"author_protected"`
+	MentionedIDs    []uuid.UUID `json:"mentioned_ids"`
+}
+
+func (c streamedChirp) audience() visibility.Audience {
+	return visibility.Audience{
+		AuthorID:   c.UserID,
+		Visibility: c.Visibility,
+		Protected:  c.AuthorProtected,
+		Mentioned:  c.MentionedIDs,
+	}
+}
+
+// helper that loads who can see a chirp, should be given the transaction's queries when there is one
+func chirpAudience(ctx context.Context, db *database.Queries, chirp database.Chirp) (visibility.Audience, error) {
+	author, err := db.GetUserByID(ctx, chirp.UserID)
+	if err != nil {
+		return visibility.Audience{}, err
+	}
+
+	audience := visibility.Audience{
+		AuthorID:   chirp.UserID,
+		Visibility: chirp.Visibility,
+		Protected:  author.IsProtected,
+	}
+	if chirp.Visibility == visibility.Mentioned {
+		audience.Mentioned, err = db.GetChirpMentionIDs(ctx, chirp.ID)
+		if err != nil {
+			return visibility.Audience{}, err
+		}
+	}
+
+	return audience, nil
+}
+
+// helper that checks whether a user is in a chirp's audience, follows are only looked up when they matter
+func inAudience(ctx context.Context, db *database.Queries, audience visibility.Audience, viewerID uuid.UUID) (bool, error) {
+	following := false
+	if viewerID != uuid.Nil && viewerID != audience.AuthorID && (audience.Protected || audience.Visibility == visibility.Followers) {
+		var err error
+		following, err = db.IsFollowing(ctx, database.IsFollowingParams{
+			FollowerID: viewerID,
+			FolloweeID: audience.AuthorID,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return visibility.CanView(audience, viewerID, following), nil
+}
+
+// helper that reports whether the viewer can open a chirp, uuid.Nil is someone who isn't logged in
+func (cfg *apiConfig) canViewChirp(ctx context.Context, viewerID uuid.UUID, chirp database.Chirp) (bool, error) {
+	if viewerID != uuid.Nil && viewerID == chirp.UserID {
+		return true, nil
+	}
+
+	audience, err := chirpAudience(ctx, cfg.db, chirp)
+	if err != nil {
+		return false, err
+	}
+
+	return inAudience(ctx, cfg.db, audience, viewerID)
+}
+
+// helper that loads everyone the user follows, used to check followers-only chirps on the real-time stream
+func (cfg *apiConfig) followingSet(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	following := map[uuid.UUID]bool{}
+	if userID == uuid.Nil {
+		return following, nil
+	}
+
+	followeeIDs, err := cfg.db.GetFolloweeIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, followeeID := range followeeIDs {
+		following[followeeID] = true
+	}
+
+	return following, nil
+}
+
+// helper that records an event about a chirp for integrators, chirps that aren't public are left out since
+// webhooks can be registered by anyone
+func recordChirpEvent(ctx context.Context, db *database.Queries, eventType string, chirp database.Chirp) error {
+	author, err := db.GetUserByID(ctx, chirp.UserID)
+	if err != nil {
+		return err
+	}
+	if author.IsProtected || (chirp.Visibility != visibility.Public && chirp.Visibility != visibility.Unlisted) {
+		return nil
+	}
+
+	return recordEvent(ctx, db, aggregateChirp, chirp.ID, eventType, structureChirp(chirp))
+}